@@ -0,0 +1,125 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// AdaptationRequestConsulKVLabel points an AdaptationRequest back to the ConsulKV which raised it
+	AdaptationRequestConsulKVLabel = "sas.com.sas.com/consulkv"
+	// AdaptationRequestKeysHashLabel carries a short hash of the keys an AdaptationRequest asks to delete
+	AdaptationRequestKeysHashLabel = "sas.com.sas.com/keys-hash"
+
+	// AdaptationDecisionAnnotation lets an approver decide on an AdaptationRequest without touching its status
+	AdaptationDecisionAnnotation = "sas.com.sas.com/decision"
+	// AdaptationDecidedByAnnotation is stamped by the admission webhook with the identity of whoever set the decision annotation
+	AdaptationDecidedByAnnotation = "sas.com.sas.com/decided-by"
+)
+
+// AdaptationRequestSpec defines the destructive adaptation which awaits a human decision
+type AdaptationRequestSpec struct {
+	ConsulKV string `json:"consulkv"`
+
+	// Keys are the Consul keys self-heal wants to delete
+	Keys []string `json:"keys"`
+
+	// Evidence explains why each key was flagged without ever carrying the flagged value itself
+	Evidence []AdaptationEvidence `json:"evidence,omitempty"`
+
+	ExpiresAt metav1.Time `json:"expires_at"`
+}
+
+type AdaptationEvidence struct {
+	Path          string `json:"path"`
+	Rule          string `json:"rule,omitempty"`
+	RedactedValue string `json:"redacted_value,omitempty"`
+	Fingerprint   string `json:"fingerprint,omitempty"`
+}
+
+type AdaptationDecision string
+
+var (
+	AdaptationApproved AdaptationDecision = "approved"
+	AdaptationRejected AdaptationDecision = "rejected"
+)
+
+type AdaptationRequestPhase string
+
+var (
+	AdaptationRequestPending  AdaptationRequestPhase = "pending"
+	AdaptationRequestApproved AdaptationRequestPhase = "approved"
+	AdaptationRequestRejected AdaptationRequestPhase = "rejected"
+	AdaptationRequestExpired  AdaptationRequestPhase = "expired"
+	AdaptationRequestExecuted AdaptationRequestPhase = "executed"
+)
+
+// AdaptationRequestStatus defines the observed state of AdaptationRequest
+type AdaptationRequestStatus struct {
+	Phase AdaptationRequestPhase `json:"phase,omitempty"`
+
+	// Decision is set by an approver, DecidedBy and DecidedAt are always stamped by the admission webhook
+	// +kubebuilder:validation:Enum=approved;rejected
+	Decision  AdaptationDecision `json:"decision,omitempty"`
+	DecidedBy string             `json:"decided_by,omitempty"`
+	DecidedAt *metav1.Time       `json:"decided_at,omitempty"`
+
+	Message string `json:"message,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="ConsulKV",type=string,JSONPath=`.spec.consulkv`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Decided By",type=string,JSONPath=`.status.decided_by`
+//+kubebuilder:printcolumn:name="Expires At",type=date,JSONPath=`.spec.expires_at`
+
+// AdaptationRequest is the Schema for the adaptationrequests API
+type AdaptationRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AdaptationRequestSpec   `json:"spec,omitempty"`
+	Status AdaptationRequestStatus `json:"status,omitempty"`
+}
+
+// Decision returns the decision taken on the request, either through its status or through its annotation
+func (r *AdaptationRequest) Decision() (AdaptationDecision, string) {
+	if r.Status.Decision != "" {
+		return r.Status.Decision, r.Status.DecidedBy
+	}
+	return AdaptationDecision(r.Annotations[AdaptationDecisionAnnotation]), r.Annotations[AdaptationDecidedByAnnotation]
+}
+
+// IsExpired tells whether the request outlived its validity, once expired it can no longer be decided upon or executed
+func (r *AdaptationRequest) IsExpired(now metav1.Time) bool {
+	return !r.Spec.ExpiresAt.IsZero() && r.Spec.ExpiresAt.Before(&now)
+}
+
+//+kubebuilder:object:root=true
+
+// AdaptationRequestList contains a list of AdaptationRequest
+type AdaptationRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AdaptationRequest `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AdaptationRequest{}, &AdaptationRequestList{})
+}
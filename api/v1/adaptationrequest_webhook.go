@@ -0,0 +1,135 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var adaptationrequestlog = logf.Log.WithName("adaptationrequest-resource")
+
+// adaptationRequestApprover records who decided on an AdaptationRequest and refuses decisions from anyone
+// who is not listed as an approver on the ConsulKV which raised the request
+type adaptationRequestApprover struct {
+	reader client.Reader
+}
+
+func (r *AdaptationRequest) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&adaptationRequestApprover{reader: mgr.GetAPIReader()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-sas-com-sas-com-v1-adaptationrequest,mutating=true,failurePolicy=fail,sideEffects=None,groups=sas.com.sas.com,resources=adaptationrequests;adaptationrequests/status,verbs=create;update,versions=v1,name=madaptationrequest.kb.io,admissionReviewVersions=v1
+
+var _ webhook.CustomDefaulter = &adaptationRequestApprover{}
+
+// Default implements webhook.CustomDefaulter
+func (a *adaptationRequestApprover) Default(ctx context.Context, obj runtime.Object) error {
+	r, ok := obj.(*AdaptationRequest)
+	if !ok {
+		return fmt.Errorf("expected an AdaptationRequest but got %T", obj)
+	}
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if req.Operation == admissionv1.Create {
+		// nobody gets to create a request which is already decided upon
+		delete(r.Annotations, AdaptationDecisionAnnotation)
+		delete(r.Annotations, AdaptationDecidedByAnnotation)
+		r.Status.Decision, r.Status.DecidedBy, r.Status.DecidedAt = "", "", nil
+		return nil
+	}
+
+	var old AdaptationRequest
+	if err := json.Unmarshal(req.OldObject.Raw, &old); err != nil {
+		return fmt.Errorf("failed to decode the previous state of the AdaptationRequest: %w", err)
+	}
+
+	// a decision covers the keys it was taken on, so what a request asks for, and until when, can't change once it is raised
+	if !reflect.DeepEqual(old.Spec, r.Spec) {
+		return fmt.Errorf("the spec of AdaptationRequest %s/%s can't be changed once raised", r.Namespace, r.Name)
+	}
+	for _, label := range []string{AdaptationRequestConsulKVLabel, AdaptationRequestKeysHashLabel} {
+		if old.Labels[label] != r.Labels[label] {
+			return fmt.Errorf("the label '%s' of AdaptationRequest %s/%s can't be changed once raised", label, r.Namespace, r.Name)
+		}
+	}
+
+	newDecision, oldDecision := r.Status.Decision, old.Status.Decision
+	if req.SubResource != "status" {
+		newDecision = AdaptationDecision(r.Annotations[AdaptationDecisionAnnotation])
+		oldDecision = AdaptationDecision(old.Annotations[AdaptationDecisionAnnotation])
+	}
+
+	if newDecision == oldDecision {
+		// the decision fields are never writable by hand, so carry over whatever was stamped before
+		r.Status.DecidedBy, r.Status.DecidedAt = old.Status.DecidedBy, old.Status.DecidedAt
+		if decidedBy, found := old.Annotations[AdaptationDecidedByAnnotation]; found {
+			if r.Annotations == nil {
+				r.Annotations = map[string]string{}
+			}
+			r.Annotations[AdaptationDecidedByAnnotation] = decidedBy
+		} else {
+			delete(r.Annotations, AdaptationDecidedByAnnotation)
+		}
+		return nil
+	}
+
+	if decision, _ := old.Decision(); decision != "" {
+		return fmt.Errorf("AdaptationRequest %s/%s was already %s", r.Namespace, r.Name, decision)
+	}
+	if newDecision != AdaptationApproved && newDecision != AdaptationRejected {
+		return fmt.Errorf("unknown decision '%s', expected either '%s' or '%s'", newDecision, AdaptationApproved, AdaptationRejected)
+	}
+	now := metav1.Now()
+	if old.IsExpired(now) {
+		return fmt.Errorf("AdaptationRequest %s/%s expired at %s", r.Namespace, r.Name, old.Spec.ExpiresAt.String())
+	}
+
+	var consulKv ConsulKV
+	if err := a.reader.Get(ctx, client.ObjectKey{Namespace: r.Namespace, Name: old.Spec.ConsulKV}, &consulKv); err != nil {
+		return fmt.Errorf("failed to fetch the ConsulKV '%s' which raised the AdaptationRequest: %w", old.Spec.ConsulKV, err)
+	}
+	if !consulKv.Spec.Approval.IsAuthorized(req.UserInfo.Username, req.UserInfo.Groups) {
+		return fmt.Errorf("user '%s' is not an approver of the ConsulKV '%s'", req.UserInfo.Username, old.Spec.ConsulKV)
+	}
+
+	adaptationrequestlog.Info("recording decision", "name", r.Name, "namespace", r.Namespace, "decision", newDecision, "user", req.UserInfo.Username)
+	if req.SubResource == "status" {
+		r.Status.DecidedBy, r.Status.DecidedAt = req.UserInfo.Username, &now
+		return nil
+	}
+	r.Annotations[AdaptationDecidedByAnnotation] = req.UserInfo.Username
+	return nil
+}
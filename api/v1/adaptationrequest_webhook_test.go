@@ -0,0 +1,83 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// TestAdaptationRequestIsImmutableOnceRaised guards that nobody can swap the keys, push the expiry or relabel a request,
+// which would have an approval cover deletions nobody approved.
+func TestAdaptationRequestIsImmutableOnceRaised(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register the sas.com types: %v", err)
+	}
+	consulKv := &ConsulKV{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec:       ConsulKVSpec{Approval: &ApprovalSpec{Enabled: true, Approvers: []string{"jane"}}},
+	}
+	approver := &adaptationRequestApprover{reader: fake.NewClientBuilder().WithScheme(scheme).WithObjects(consulKv).Build()}
+
+	raised := func() *AdaptationRequest {
+		return &AdaptationRequest{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "app-x7k2p",
+				Namespace: "default",
+				Labels:    map[string]string{AdaptationRequestConsulKVLabel: "app", AdaptationRequestKeysHashLabel: "0123456789abcdef"},
+			},
+			Spec: AdaptationRequestSpec{ConsulKV: "app", Keys: []string{"app.token"}, ExpiresAt: metav1.NewTime(time.Now().Add(time.Hour).Truncate(time.Second))},
+		}
+	}
+	for _, tc := range []struct {
+		name    string
+		user    string
+		edit    func(r *AdaptationRequest)
+		refused string
+	}{
+		{name: "decision", user: "jane", edit: func(r *AdaptationRequest) {
+			r.Annotations = map[string]string{AdaptationDecisionAnnotation: string(AdaptationApproved)}
+		}},
+		{name: "unrelated label", user: "john", edit: func(r *AdaptationRequest) { r.Labels["team"] = "payments" }},
+		{name: "keys", user: "john", edit: func(r *AdaptationRequest) { r.Spec.Keys = []string{"app.database"} }, refused: "spec"},
+		{name: "expiry", user: "john", edit: func(r *AdaptationRequest) { r.Spec.ExpiresAt = metav1.NewTime(r.Spec.ExpiresAt.Add(24 * time.Hour)) }, refused: "spec"},
+		{name: "keys along with the decision", user: "jane", edit: func(r *AdaptationRequest) {
+			r.Annotations = map[string]string{AdaptationDecisionAnnotation: string(AdaptationApproved)}
+			r.Spec.Keys = append(r.Spec.Keys, "app.database")
+		}, refused: "spec"},
+		{name: "keys hash", user: "john", edit: func(r *AdaptationRequest) { r.Labels[AdaptationRequestKeysHashLabel] = "fedcba9876543210" }, refused: AdaptationRequestKeysHashLabel},
+	} {
+		old := raised()
+		oldJson, err := json.Marshal(old)
+		if err != nil {
+			t.Fatalf("failed to encode the request: %v", err)
+		}
+		updated := raised()
+		tc.edit(updated)
+		ctx := admission.NewContextWithRequest(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Update,
+			OldObject: runtime.RawExtension{Raw: oldJson},
+			UserInfo:  authenticationv1.UserInfo{Username: tc.user},
+		}})
+
+		err = approver.Default(ctx, updated)
+		if tc.refused == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tc.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.refused) {
+			t.Errorf("%s: expected the change of the %s to be refused, got %v", tc.name, tc.refused, err)
+		}
+	}
+}
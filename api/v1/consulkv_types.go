@@ -18,6 +18,7 @@ package v1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -33,10 +34,343 @@ type ConsulKVSpec struct {
 
 	Paths []PathSpec `json:"paths,omitempty"`
 
-	GuardAgainst     []string `json:"guard_against,omitempty"`
+	// GuardAgainst references detectors by their name, entries prefixed with 'cel:' are CEL expressions over key, path, value, json and metadata,
+	// any other entry which doesn't name a detector is used as a raw regex
+	GuardAgainst []string `json:"guard_against,omitempty"`
+
+	// Exceptions silence the detection on the keys they match until they expire
+	Exceptions []ExceptionSpec `json:"exceptions,omitempty"`
+
+	// WhitelistedPaths is deprecated, use Exceptions instead.
+	// Every entry keeps being honoured as an exception which never expires, an entry ending with '/' covering every key under it.
 	WhitelistedPaths []string `json:"whitelisted_paths,omitempty"`
+
+	// Baseline names a ConfigMap, in the namespace of the ConsulKV, listing the findings accepted as known under its 'baseline.json' entry.
+	// A baselined finding triggers no adaptation as long as the value at its path keeps the same fingerprint.
+	Baseline string `json:"baseline,omitempty"`
+
+	// RegoPolicies names ConfigMaps, in the namespace of the ConsulKV, whose '.rego' entries are evaluated over the whole payload.
+	// The policies add findings, objects with a key and a rule_id, to the set 'findings' of the package 'consulkv'.
+	// Their findings go through the adaptation engine like any other, unless the policy sets 'advisory' to true on them.
+	RegoPolicies []string `json:"rego_policies,omitempty"`
+
+	// Detectors tunes the detectors referenced from GuardAgainst for this KV group
+	Detectors *DetectorsSpec `json:"detectors,omitempty"`
+
+	// Approval makes self-heal wait for a human decision before deleting any key from Consul
+	Approval *ApprovalSpec `json:"approval,omitempty"`
+
+	// BlastRadius caps how much of this KV group self-heal may delete, on top of the operator-wide limits
+	BlastRadius *BlastRadiusSpec `json:"blast_radius,omitempty"`
+
+	// DetectorErrorPolicy overrides the operator-wide policy configured for the QoS of this KV group
+	// +kubebuilder:validation:Enum=fail-closed;fail-open;halt
+	DetectorErrorPolicy DetectorErrorPolicy `json:"detector_error_policy,omitempty"`
+
+	// StructuredValues tunes how JSON, YAML and properties values are scanned and remediated
+	StructuredValues *StructuredValuesSpec `json:"structured_values,omitempty"`
+
+	// KeyRules flag keys by their name alone, whatever the value they hold
+	KeyRules []KeyRuleSpec `json:"key_rules,omitempty"`
+
+	// MatchAdaptations pins the adaptation of the flagged keys depending on whether their name, their value or both got flagged, the utility function decides otherwise
+	MatchAdaptations *MatchAdaptationsSpec `json:"match_adaptations,omitempty"`
+
+	// Masking keeps the flagged keys in the configmap with only their matched spans masked, instead of getting rid of them
+	Masking *MaskingSpec `json:"masking,omitempty"`
+}
+
+type MaskingStrategy string
+
+var (
+	// PlaceholderMasking replaces every matched span with the placeholder
+	PlaceholderMasking MaskingStrategy = "placeholder"
+	// FormatPreservingMasking replaces the letters and digits of every matched span with '*', like ****1234
+	FormatPreservingMasking MaskingStrategy = "format-preserving"
+)
+
+type MaskingSpec struct {
+	// +kubebuilder:validation:Enum=placeholder;format-preserving
+	// +kubebuilder:default=placeholder
+	Strategy MaskingStrategy `json:"strategy,omitempty"`
+
+	// Placeholder used by the placeholder strategy, '********' by default
+	Placeholder string `json:"placeholder,omitempty"`
+
+	// KeepLast is the amount of trailing characters the format-preserving strategy leaves readable, 4 by default
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=8
+	KeepLast *int `json:"keep_last,omitempty"`
+
+	// WriteBackToConsul makes self-heal put the masked value back in Consul instead of deleting the key
+	WriteBackToConsul bool `json:"write_back_to_consul,omitempty"`
+}
+
+// ExceptionSpec silences the findings on the slash separated Consul keys matched by either a glob or a regex.
+// Without a rule id nor a fingerprint, the matched keys aren't scanned at all.
+type ExceptionSpec struct {
+	// Glob on the key, '*' and '?' never cross a '/' whereas '**' does
+	Glob string `json:"glob,omitempty"`
+
+	// Regex which must match the whole key
+	Regex string `json:"regex,omitempty"`
+
+	// Fingerprint limits the exception to the value with this fingerprint, as found in the notifications, so that any new value gets scanned again.
+	// Fingerprints only remain stable across restarts of the operator when it is provided with a redaction key.
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	// RuleID limits the exception to the findings of a single rule
+	RuleID string `json:"rule_id,omitempty"`
+
+	// ExpiresAt is when the detection gets re-enabled on the matched keys, a notification is sent out then
+	ExpiresAt metav1.Time `json:"expires_at"`
+
+	// +kubebuilder:validation:MinLength=1
+	Justification string `json:"justification"`
+
+	// +kubebuilder:validation:MinLength=1
+	Owner string `json:"owner"`
+}
+
+// ID identifies the exception in the notifications and the status
+func (e ExceptionSpec) ID() string {
+	id := "regex:" + e.Regex
+	if e.Glob != "" {
+		id = "glob:" + e.Glob
+	}
+	if e.RuleID != "" {
+		id += " rule:" + e.RuleID
+	}
+	if e.Fingerprint != "" {
+		id += " fingerprint:" + e.Fingerprint
+	}
+	return id + " until:" + e.ExpiresAt.UTC().Format(time.RFC3339)
+}
+
+// AliasLibraryLabel marks the ConfigMaps, living in the namespace of the operator, whose data extends the regex aliases guards may reference, each key being an alias and its value a regex
+const AliasLibraryLabel = "sas.com.sas.com/regex-aliases"
+
+// KeyRuleSpec matches the slash separated Consul key through either a glob or a regex
+type KeyRuleSpec struct {
+	Name string `json:"name"`
+
+	// Glob on the key, '*' and '?' never cross a '/' whereas '**' does
+	Glob string `json:"glob,omitempty"`
+
+	Regex string `json:"regex,omitempty"`
+
+	// +kubebuilder:validation:Enum=low;medium;high;critical
+	// +kubebuilder:default=medium
+	Severity string `json:"severity,omitempty"`
+}
+
+type MatchAdaptationsSpec struct {
+	// KeyOnly is the adaptation of the keys flagged only by a key rule
+	// +kubebuilder:validation:Enum=non-adaptive;self-healing;self-protecting
+	KeyOnly AdaptationMode `json:"key_only,omitempty"`
+
+	// ValueOnly is the adaptation of the keys flagged only by a value detector
+	// +kubebuilder:validation:Enum=non-adaptive;self-healing;self-protecting
+	ValueOnly AdaptationMode `json:"value_only,omitempty"`
+
+	// Both is the adaptation of the keys flagged by a key rule and a value detector alike
+	// +kubebuilder:validation:Enum=non-adaptive;self-healing;self-protecting
+	Both AdaptationMode `json:"both,omitempty"`
+}
+
+type StructuredValuesSpec struct {
+	// DisableLeafScanning scans structured values as a single blob instead of field by field
+	DisableLeafScanning bool `json:"disable_leaf_scanning,omitempty"`
+
+	// BlankOffendingFields makes remediation blank out only the offending fields of a structured value and write back the rest of the document
+	BlankOffendingFields bool `json:"blank_offending_fields,omitempty"`
+}
+
+// ScansLeaves tells whether structured values get scanned field by field, which is the default
+func (s *StructuredValuesSpec) ScansLeaves() bool {
+	return s == nil || !s.DisableLeafScanning
+}
+
+// BlanksOffendingFields tells whether remediation may keep a structured value and only blank out its offending fields
+func (s *StructuredValuesSpec) BlanksOffendingFields() bool {
+	return s != nil && s.BlankOffendingFields && !s.DisableLeafScanning
+}
+
+type DetectorsSpec struct {
+	// Entropy tunes the 'high-entropy' detector
+	Entropy *EntropyDetectorSpec `json:"entropy,omitempty"`
+
+	// Decoding tunes how encoded values are unwrapped before getting scanned
+	Decoding *DecodingSpec `json:"decoding,omitempty"`
+
+	// Remote declares detectors served by external plugins, a guard enables one by naming it
+	Remote []RemoteDetectorSpec `json:"remote,omitempty"`
+
+	// Certificates tunes the detector flagging expired, expiring, self-signed or weak-key X.509 certificates
+	Certificates *CertificatesDetectorSpec `json:"certificates,omitempty"`
+}
+
+type CertificatesDetectorSpec struct {
+	// ExpiryWindow is how long before expiring a certificate starts getting flagged, 720h by default
+	ExpiryWindow *metav1.Duration `json:"expiry_window,omitempty"`
+
+	// AllowSelfSigned stops self-signed leaf certificates from getting flagged
+	AllowSelfSigned bool `json:"allow_self_signed,omitempty"`
+
+	// MinRSAKeyBits is the smallest RSA key size not flagged as weak, 2048 by default
+	// +kubebuilder:validation:Minimum=1024
+	MinRSAKeyBits int `json:"min_rsa_key_bits,omitempty"`
+
+	// MinECDSAKeyBits is the smallest ECDSA curve size not flagged as weak, 256 by default
+	// +kubebuilder:validation:Minimum=224
+	MinECDSAKeyBits int `json:"min_ecdsa_key_bits,omitempty"`
+}
+
+type RemoteDetectorFailurePolicy string
+
+var (
+	// RemoteDetectorFail reports a failing plugin as a detector error, handled as per the detector error policy of the ConsulKV
+	RemoteDetectorFail RemoteDetectorFailurePolicy = "fail"
+	// RemoteDetectorIgnore scans on as if the failing plugin found nothing
+	RemoteDetectorIgnore RemoteDetectorFailurePolicy = "ignore"
+)
+
+// RemoteDetectorSpec points to a plugin speaking the v1 detector plugin schema over HTTP
+type RemoteDetectorSpec struct {
+	Name string `json:"name"`
+
+	// URL the batches of values are POSTed to
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// SendValues sends the values themselves, only their keyed fingerprints are sent otherwise
+	SendValues bool `json:"send_values,omitempty"`
+
+	// Timeout of a single batch, 5s by default
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// BatchSize is the amount of values sent at most per request, 100 by default
+	// +kubebuilder:validation:Minimum=1
+	BatchSize int `json:"batch_size,omitempty"`
+
+	// MaxConcurrency is the amount of batches in flight at most during a scan, 4 by default
+	// +kubebuilder:validation:Minimum=1
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
+
+	// +kubebuilder:validation:Enum=fail;ignore
+	// +kubebuilder:default=fail
+	FailurePolicy RemoteDetectorFailurePolicy `json:"failure_policy,omitempty"`
+}
+
+// DecodingSpec bounds the base64, hex, URL and gzip layers peeled off a value, every decoded layer gets scanned too
+type DecodingSpec struct {
+	// Disabled scans values only as they are stored
+	Disabled bool `json:"disabled,omitempty"`
+
+	// MaxDepth is the amount of nested layers decoded at most, 3 by default
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=8
+	MaxDepth int `json:"max_depth,omitempty"`
+
+	// MaxDecodedBytes is the size any decoded layer is truncated to, 1MiB by default
+	// +kubebuilder:validation:Minimum=1
+	MaxDecodedBytes int `json:"max_decoded_bytes,omitempty"`
+}
+
+type EntropyDetectorSpec struct {
+	// Charsets are the character classes random-looking tokens are looked for in, all of them by default
+	Charsets []EntropyCharsetSpec `json:"charsets,omitempty"`
+
+	// MinLength of a token for its entropy to be considered at all
+	// +kubebuilder:validation:Minimum=1
+	MinLength int `json:"min_length,omitempty"`
+
+	// Keywords boost the detection when the key name contains any of them, case-insensitively
+	Keywords []string `json:"keywords,omitempty"`
+
+	// KeywordBoost is the amount of bits per character the threshold is lowered by when a keyword is found in the key name
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	KeywordBoost string `json:"keyword_boost,omitempty"`
+}
+
+type EntropyCharset string
+
+var (
+	Base64Charset       EntropyCharset = "base64"
+	HexCharset          EntropyCharset = "hex"
+	AlphanumericCharset EntropyCharset = "alphanumeric"
+)
+
+type EntropyCharsetSpec struct {
+	// +kubebuilder:validation:Enum=base64;hex;alphanumeric
+	Class EntropyCharset `json:"class"`
+
+	// Threshold, in bits of Shannon entropy per character, above which a token is considered random
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	Threshold string `json:"threshold,omitempty"`
+}
+
+// DetectorErrorPolicy decides what happens to the values a detector failed to evaluate
+type DetectorErrorPolicy string
+
+var (
+	// FailClosed keeps the affected keys at their last synced value but never deletes them from Consul, a rule failing as a whole halts the sync only until a first configmap got synced
+	FailClosed DetectorErrorPolicy = "fail-closed"
+	// FailOpen syncs the affected keys as if the failing detector did not exist
+	FailOpen DetectorErrorPolicy = "fail-open"
+	// HaltSync leaves the configmap untouched until the detectors are healthy again
+	HaltSync DetectorErrorPolicy = "halt"
+)
+
+// BlastRadiusSpec bounds self-heal deletions, a zero value leaves the corresponding dimension unbounded
+type BlastRadiusSpec struct {
+	// +kubebuilder:validation:Minimum=0
+	MaxKeysPerReconcile int `json:"max_keys_per_reconcile,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	MaxPercentPerReconcile int `json:"max_percent_per_reconcile,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	MaxKeysPerHour int `json:"max_keys_per_hour,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	MaxPercentPerHour int `json:"max_percent_per_hour,omitempty"`
+}
+
+type ApprovalSpec struct {
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Approvers and ApproverGroups are matched against the user info of the admission request deciding on an AdaptationRequest
+	Approvers      []string `json:"approvers,omitempty"`
+	ApproverGroups []string `json:"approver_groups,omitempty"`
+
+	// TTL after which an undecided AdaptationRequest expires
+	// +kubebuilder:default="1h"
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+}
+
+// IsAuthorized tells whether the given user may decide on the AdaptationRequests guarded by this approval spec
+func (a *ApprovalSpec) IsAuthorized(username string, groups []string) bool {
+	if a == nil {
+		return false
+	}
+	for _, approver := range a.Approvers {
+		if approver == username {
+			return true
+		}
+	}
+	for _, approverGroup := range a.ApproverGroups {
+		for _, group := range groups {
+			if approverGroup == group {
+				return true
+			}
+		}
+	}
+	return false
 }
 
+// BlastRadiusOverrideAnnotation resumes a halted self-heal. Every new value of it lifts the limits for exactly one reconciliation.
+const BlastRadiusOverrideAnnotation = "sas.com.sas.com/blast-radius-override"
+
 type PathSpec struct {
 	// +kubebuilder:validation:MinLength=1
 	Path string `json:"path,omitempty"`
@@ -44,6 +378,59 @@ type PathSpec struct {
 	// +kubebuilder:default=1
 	// +kubebuilder:validation:Minimum=1
 	CriticalityWeight int `json:"criticality_weight"`
+
+	// Validation constrains the values under the path, beyond them leaking anything or not
+	Validation *ValueValidationSpec `json:"validation,omitempty"`
+}
+
+type ValueValidationPolicy string
+
+var (
+	// RejectInvalidValue keeps the value previously synced to the configmap, if any, in place of the invalid one
+	RejectInvalidValue ValueValidationPolicy = "reject"
+	// DropInvalidValue leaves the key out of the configmap
+	DropInvalidValue ValueValidationPolicy = "drop"
+	// ReportInvalidValue syncs the value anyway and only notifies about it
+	ReportInvalidValue ValueValidationPolicy = "report"
+)
+
+type ValueType string
+
+var (
+	StringValueType   ValueType = "string"
+	IntValueType      ValueType = "int"
+	BoolValueType     ValueType = "bool"
+	DurationValueType ValueType = "duration"
+	URLValueType      ValueType = "url"
+	EnumValueType     ValueType = "enum"
+	JSONValueType     ValueType = "json"
+)
+
+type ValueValidationSpec struct {
+	// Rules are checked against every value under the path whose key they match
+	Rules []ValueRuleSpec `json:"rules"`
+
+	// +kubebuilder:validation:Enum=reject;drop;report
+	// +kubebuilder:default=reject
+	Policy ValueValidationPolicy `json:"policy,omitempty"`
+}
+
+type ValueRuleSpec struct {
+	// Key is a glob over the keys, relative to the path and slash-separated, every key under the path is matched when empty
+	Key string `json:"key,omitempty"`
+
+	// Type the value must parse as, enum requires the value to be one of Enum
+	// +kubebuilder:validation:Enum=string;int;bool;duration;url;enum;json
+	Type ValueType `json:"type,omitempty"`
+
+	Enum []string `json:"enum,omitempty"`
+
+	// MaxSize is the size in bytes the value must not exceed
+	// +kubebuilder:validation:Minimum=0
+	MaxSize int `json:"max_size,omitempty"`
+
+	// JSONSchema is a schema, in JSON, the value parsed as JSON or YAML must conform to
+	JSONSchema string `json:"json_schema,omitempty"`
 }
 
 type QoSType string
@@ -68,8 +455,51 @@ type ConsulKVStatus struct {
 	// Important: Run "make" to regenerate code after modifying this file
 	UtilityFunctionValue string         `json:"utility_function_value"`
 	AdaptationMode       AdaptationMode `json:"adaptation_mode"`
+
+	PendingAdaptationRequest string `json:"pending_adaptation_request,omitempty"`
+
+	// HealingHaltedReason is set once a blast-radius limit trips and stays until an override is acknowledged
+	HealingHaltedReason             string `json:"healing_halted_reason,omitempty"`
+	AcknowledgedBlastRadiusOverride string `json:"acknowledged_blast_radius_override,omitempty"`
+
+	// DetectorVersions lists the versions of the detector packs used during the last scan
+	DetectorVersions []string `json:"detector_versions,omitempty"`
+
+	// AliasLibraryVersion is the version of the runtime alias library the last scan resolved some of its guards from
+	AliasLibraryVersion string `json:"alias_library_version,omitempty"`
+
+	// Certificates lists the certificates found by the last scan, the soonest to expire first
+	Certificates []CertificateStatus `json:"certificates,omitempty"`
+
+	// ExpiredExceptions lists the expired exceptions whose expiry was already notified
+	ExpiredExceptions []string `json:"expired_exceptions,omitempty"`
+
+	// BaselinedFindings counts the findings of the last scan which the baseline accepted
+	BaselinedFindings int `json:"baselined_findings,omitempty"`
+
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// CertificateStatus is a certificate stored in a value
+type CertificateStatus struct {
+	Path     string      `json:"path"`
+	Subject  string      `json:"subject"`
+	NotAfter metav1.Time `json:"not_after"`
+}
+
+const (
+	// DetectorsHealthyCondition reports whether every guard of the ConsulKV could be evaluated during the last scan
+	DetectorsHealthyCondition = "DetectorsHealthy"
+	// ValuesValidCondition reports whether every value passed the validation of its path during the last scan
+	ValuesValidCondition = "ValuesValid"
+	// PolicyKeysPresentCondition reports whether every key the Rego policies reported a finding about is part of the payload
+	PolicyKeysPresentCondition = "PolicyKeysPresent"
+	// PolicyReportedCondition reports whether the results of the last scan made it to the PolicyReport of the ConsulKV, absent on clusters without the PolicyReport CRD
+	PolicyReportedCondition = "PolicyReported"
+)
+
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 
@@ -0,0 +1,154 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/celguard"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/valuerules"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var consulkvlog = logf.Log.WithName("consulkv-resource")
+
+// consulKvValidator compiles the guards, key rules and value rules of a ConsulKV and refuses ambiguous exceptions and remote detectors, so that a broken spec is refused upfront rather than failing every scan
+type consulKvValidator struct{}
+
+func (r *ConsulKV) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithValidator(&consulKvValidator{}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-sas-com-sas-com-v1-consulkv,mutating=false,failurePolicy=fail,sideEffects=None,groups=sas.com.sas.com,resources=consulkvs,verbs=create;update,versions=v1,name=vconsulkv.kb.io,admissionReviewVersions=v1
+
+var _ webhook.CustomValidator = &consulKvValidator{}
+
+// ValidateCreate implements webhook.CustomValidator
+func (v *consulKvValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return deprecationWarnings(obj), v.validate(obj)
+}
+
+// ValidateUpdate implements webhook.CustomValidator
+func (v *consulKvValidator) ValidateUpdate(_ context.Context, _ runtime.Object, newObj runtime.Object) (admission.Warnings, error) {
+	return deprecationWarnings(newObj), v.validate(newObj)
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (v *consulKvValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// deprecationWarnings points the users of deprecated fields to their replacement
+func deprecationWarnings(obj runtime.Object) admission.Warnings {
+	r, ok := obj.(*ConsulKV)
+	if !ok || len(r.Spec.WhitelistedPaths) == 0 {
+		return nil
+	}
+	return admission.Warnings{"spec.whitelisted_paths is deprecated, use spec.exceptions instead"}
+}
+
+func (v *consulKvValidator) validate(obj runtime.Object) error {
+	r, ok := obj.(*ConsulKV)
+	if !ok {
+		return fmt.Errorf("expected a ConsulKV but got %T", obj)
+	}
+	consulkvlog.Info("validate", "name", r.Name)
+
+	allErrs := field.ErrorList{}
+	guardsPath := field.NewPath("spec", "guard_against")
+	for idx, guard := range r.Spec.GuardAgainst {
+		if expression, isCel := celguard.Expression(guard); isCel {
+			if _, err := celguard.Compile(expression); err != nil {
+				allErrs = append(allErrs, field.Invalid(guardsPath.Index(idx), guard, err.Error()))
+			}
+			continue
+		}
+		// detector and alias names are plain words, so whatever else fails to compile would fail as a raw regex at every scan
+		if _, err := regexp.Compile(guard); err != nil {
+			allErrs = append(allErrs, field.Invalid(guardsPath.Index(idx), guard, err.Error()))
+		}
+	}
+	keyRulesPath := field.NewPath("spec", "key_rules")
+	for idx, keyRule := range r.Spec.KeyRules {
+		if (keyRule.Glob == "") == (keyRule.Regex == "") {
+			allErrs = append(allErrs, field.Invalid(keyRulesPath.Index(idx), keyRule.Name, "exactly one of glob or regex must be set"))
+			continue
+		}
+		pattern := keyRule.Regex
+		if keyRule.Glob != "" {
+			pattern = utils.GlobToRegex(keyRule.Glob)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			allErrs = append(allErrs, field.Invalid(keyRulesPath.Index(idx), pattern, err.Error()))
+		}
+	}
+	pathsPath := field.NewPath("spec", "paths")
+	for pathIdx, pathSpec := range r.Spec.Paths {
+		if pathSpec.Validation == nil {
+			continue
+		}
+		for ruleIdx, ruleSpec := range pathSpec.Validation.Rules {
+			if _, err := valuerules.Compile(valuerules.Spec{
+				Key:        ruleSpec.Key,
+				Type:       string(ruleSpec.Type),
+				Enum:       ruleSpec.Enum,
+				MaxSize:    ruleSpec.MaxSize,
+				JSONSchema: ruleSpec.JSONSchema,
+			}); err != nil {
+				allErrs = append(allErrs, field.Invalid(pathsPath.Index(pathIdx).Child("validation", "rules").Index(ruleIdx), ruleSpec, err.Error()))
+			}
+		}
+	}
+	exceptionsPath := field.NewPath("spec", "exceptions")
+	for idx, exception := range r.Spec.Exceptions {
+		if (exception.Glob == "") == (exception.Regex == "") {
+			allErrs = append(allErrs, field.Invalid(exceptionsPath.Index(idx), exception.ID(), "exactly one of glob or regex must be set"))
+			continue
+		}
+		if _, err := regexp.Compile(exception.Regex); err != nil {
+			allErrs = append(allErrs, field.Invalid(exceptionsPath.Index(idx).Child("regex"), exception.Regex, err.Error()))
+		}
+	}
+	if r.Spec.Detectors != nil {
+		remotePath := field.NewPath("spec", "detectors", "remote")
+		remoteNames := map[string]bool{}
+		for idx, remote := range r.Spec.Detectors.Remote {
+			if remoteNames[remote.Name] {
+				allErrs = append(allErrs, field.Duplicate(remotePath.Index(idx).Child("name"), remote.Name))
+			}
+			remoteNames[remote.Name] = true
+		}
+	}
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: "ConsulKV"}, r.Name, allErrs)
+}
@@ -0,0 +1,52 @@
+package v1
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestValidateCompilesEveryRule guards that every guard and key rule failing to compile is refused at admission,
+// rather than turning into a detector error at every scan.
+func TestValidateCompilesEveryRule(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		spec    ConsulKVSpec
+		invalid string
+	}{
+		{name: "valid", spec: ConsulKVSpec{
+			GuardAgainst: []string{"email", "my-alias", `cel:value.size() > 10`, `^token-[a-z]+$`},
+			KeyRules:     []KeyRuleSpec{{Name: "passwords", Glob: "**/password"}, {Name: "tokens", Regex: `token$`}},
+		}},
+		{name: "raw regex", spec: ConsulKVSpec{GuardAgainst: []string{"email", "(["}}, invalid: "spec.guard_against[1]"},
+		{name: "cel", spec: ConsulKVSpec{GuardAgainst: []string{"cel:value +"}}, invalid: "spec.guard_against[0]"},
+		{name: "key rule regex", spec: ConsulKVSpec{KeyRules: []KeyRuleSpec{{Name: "broken", Regex: "(?<"}}}, invalid: "spec.key_rules[0]"},
+		{name: "key rule without pattern", spec: ConsulKVSpec{KeyRules: []KeyRuleSpec{{Name: "empty"}}}, invalid: "spec.key_rules[0]"},
+		{name: "key rule with both patterns", spec: ConsulKVSpec{KeyRules: []KeyRuleSpec{{Name: "both", Glob: "*", Regex: ".*"}}}, invalid: "spec.key_rules[0]"},
+	} {
+		consulKv := &ConsulKV{ObjectMeta: metav1.ObjectMeta{Name: "app"}, Spec: tc.spec}
+		_, err := (&consulKvValidator{}).ValidateCreate(context.Background(), consulKv)
+		if tc.invalid == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tc.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.invalid) {
+			t.Errorf("%s: expected %s to be refused, got %v", tc.name, tc.invalid, err)
+		}
+	}
+}
+
+func TestWhitelistedPathsAreDeprecated(t *testing.T) {
+	consulKv := &ConsulKV{ObjectMeta: metav1.ObjectMeta{Name: "app"}, Spec: ConsulKVSpec{WhitelistedPaths: []string{"app/legacy/"}}}
+	warnings, err := (&consulKvValidator{}).ValidateCreate(context.Background(), consulKv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "spec.exceptions") {
+		t.Errorf("expected a deprecation warning, got %v", warnings)
+	}
+}
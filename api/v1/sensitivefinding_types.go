@@ -0,0 +1,109 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SensitiveFindingConsulKVLabel points a SensitiveFinding back to the ConsulKV it was found in
+	SensitiveFindingConsulKVLabel = "sas.com.sas.com/consulkv"
+
+	// SensitiveFindingTriageAnnotation lets a human triage a SensitiveFinding without touching its status, either as acknowledged or as false-positive
+	SensitiveFindingTriageAnnotation = "sas.com.sas.com/triage"
+)
+
+// SensitiveFindingSpec identifies a finding of a rule on a value at a path, the value only being known through its fingerprint
+type SensitiveFindingSpec struct {
+	ConsulKV    string `json:"consulkv"`
+	Path        string `json:"path"`
+	RuleID      string `json:"rule_id"`
+	Fingerprint string `json:"fingerprint"`
+
+	Preview  string `json:"preview,omitempty"`
+	Severity string `json:"severity,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+type SensitiveFindingPhase string
+
+var (
+	// SensitiveFindingOpen is the phase of a finding nobody triaged yet
+	SensitiveFindingOpen SensitiveFindingPhase = "open"
+	// SensitiveFindingAcknowledged is the phase of a finding someone is taking care of, it keeps triggering adaptations
+	SensitiveFindingAcknowledged SensitiveFindingPhase = "acknowledged"
+	// SensitiveFindingResolved is the phase of a finding which the last scan didn't find anymore, it reopens if the finding shows up again
+	SensitiveFindingResolved SensitiveFindingPhase = "resolved"
+	// SensitiveFindingFalsePositive is the phase of a finding which no longer triggers any adaptation, for as long as the value keeps its fingerprint
+	SensitiveFindingFalsePositive SensitiveFindingPhase = "false-positive"
+)
+
+// SensitiveFindingStatus defines the observed state of SensitiveFinding
+type SensitiveFindingStatus struct {
+	// Phase is moved to acknowledged or false-positive by a human, either here or through the triage annotation, the operator moves it to open and resolved
+	// +kubebuilder:validation:Enum=open;acknowledged;resolved;"false-positive"
+	Phase SensitiveFindingPhase `json:"phase,omitempty"`
+
+	LastTransitionTime *metav1.Time `json:"last_transition_time,omitempty"`
+
+	Message string `json:"message,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="ConsulKV",type=string,JSONPath=`.spec.consulkv`
+//+kubebuilder:printcolumn:name="Path",type=string,JSONPath=`.spec.path`
+//+kubebuilder:printcolumn:name="Rule",type=string,JSONPath=`.spec.rule_id`
+//+kubebuilder:printcolumn:name="Severity",type=string,JSONPath=`.spec.severity`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SensitiveFinding is the Schema for the sensitivefindings API, there is one per unique ConsulKV, path, rule and fingerprint
+type SensitiveFinding struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SensitiveFindingSpec   `json:"spec,omitempty"`
+	Status SensitiveFindingStatus `json:"status,omitempty"`
+}
+
+// Triage returns the phase a human moved the finding to, the status taking precedence over the annotation
+func (f *SensitiveFinding) Triage() SensitiveFindingPhase {
+	switch f.Status.Phase {
+	case SensitiveFindingAcknowledged, SensitiveFindingFalsePositive:
+		return f.Status.Phase
+	}
+	switch triage := SensitiveFindingPhase(f.Annotations[SensitiveFindingTriageAnnotation]); triage {
+	case SensitiveFindingAcknowledged, SensitiveFindingFalsePositive:
+		return triage
+	}
+	return ""
+}
+
+//+kubebuilder:object:root=true
+
+// SensitiveFindingList contains a list of SensitiveFinding
+type SensitiveFindingList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SensitiveFinding `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SensitiveFinding{}, &SensitiveFindingList{})
+}
@@ -21,16 +21,217 @@ limitations under the License.
 package v1
 
 import (
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdaptationEvidence) DeepCopyInto(out *AdaptationEvidence) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdaptationEvidence.
+func (in *AdaptationEvidence) DeepCopy() *AdaptationEvidence {
+	if in == nil {
+		return nil
+	}
+	out := new(AdaptationEvidence)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdaptationRequest) DeepCopyInto(out *AdaptationRequest) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdaptationRequest.
+func (in *AdaptationRequest) DeepCopy() *AdaptationRequest {
+	if in == nil {
+		return nil
+	}
+	out := new(AdaptationRequest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AdaptationRequest) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdaptationRequestList) DeepCopyInto(out *AdaptationRequestList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AdaptationRequest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdaptationRequestList.
+func (in *AdaptationRequestList) DeepCopy() *AdaptationRequestList {
+	if in == nil {
+		return nil
+	}
+	out := new(AdaptationRequestList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AdaptationRequestList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdaptationRequestSpec) DeepCopyInto(out *AdaptationRequestSpec) {
+	*out = *in
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Evidence != nil {
+		in, out := &in.Evidence, &out.Evidence
+		*out = make([]AdaptationEvidence, len(*in))
+		copy(*out, *in)
+	}
+	in.ExpiresAt.DeepCopyInto(&out.ExpiresAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdaptationRequestSpec.
+func (in *AdaptationRequestSpec) DeepCopy() *AdaptationRequestSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AdaptationRequestSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdaptationRequestStatus) DeepCopyInto(out *AdaptationRequestStatus) {
+	*out = *in
+	if in.DecidedAt != nil {
+		in, out := &in.DecidedAt, &out.DecidedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdaptationRequestStatus.
+func (in *AdaptationRequestStatus) DeepCopy() *AdaptationRequestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AdaptationRequestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApprovalSpec) DeepCopyInto(out *ApprovalSpec) {
+	*out = *in
+	if in.Approvers != nil {
+		in, out := &in.Approvers, &out.Approvers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ApproverGroups != nil {
+		in, out := &in.ApproverGroups, &out.ApproverGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApprovalSpec.
+func (in *ApprovalSpec) DeepCopy() *ApprovalSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApprovalSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlastRadiusSpec) DeepCopyInto(out *BlastRadiusSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlastRadiusSpec.
+func (in *BlastRadiusSpec) DeepCopy() *BlastRadiusSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BlastRadiusSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateStatus) DeepCopyInto(out *CertificateStatus) {
+	*out = *in
+	in.NotAfter.DeepCopyInto(&out.NotAfter)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateStatus.
+func (in *CertificateStatus) DeepCopy() *CertificateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificatesDetectorSpec) DeepCopyInto(out *CertificatesDetectorSpec) {
+	*out = *in
+	if in.ExpiryWindow != nil {
+		in, out := &in.ExpiryWindow, &out.ExpiryWindow
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificatesDetectorSpec.
+func (in *CertificatesDetectorSpec) DeepCopy() *CertificatesDetectorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificatesDetectorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConsulKV) DeepCopyInto(out *ConsulKV) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConsulKV.
@@ -89,18 +290,67 @@ func (in *ConsulKVSpec) DeepCopyInto(out *ConsulKVSpec) {
 	if in.Paths != nil {
 		in, out := &in.Paths, &out.Paths
 		*out = make([]PathSpec, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.GuardAgainst != nil {
 		in, out := &in.GuardAgainst, &out.GuardAgainst
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Exceptions != nil {
+		in, out := &in.Exceptions, &out.Exceptions
+		*out = make([]ExceptionSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.WhitelistedPaths != nil {
 		in, out := &in.WhitelistedPaths, &out.WhitelistedPaths
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.RegoPolicies != nil {
+		in, out := &in.RegoPolicies, &out.RegoPolicies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Detectors != nil {
+		in, out := &in.Detectors, &out.Detectors
+		*out = new(DetectorsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Approval != nil {
+		in, out := &in.Approval, &out.Approval
+		*out = new(ApprovalSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BlastRadius != nil {
+		in, out := &in.BlastRadius, &out.BlastRadius
+		*out = new(BlastRadiusSpec)
+		**out = **in
+	}
+	if in.StructuredValues != nil {
+		in, out := &in.StructuredValues, &out.StructuredValues
+		*out = new(StructuredValuesSpec)
+		**out = **in
+	}
+	if in.KeyRules != nil {
+		in, out := &in.KeyRules, &out.KeyRules
+		*out = make([]KeyRuleSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.MatchAdaptations != nil {
+		in, out := &in.MatchAdaptations, &out.MatchAdaptations
+		*out = new(MatchAdaptationsSpec)
+		**out = **in
+	}
+	if in.Masking != nil {
+		in, out := &in.Masking, &out.Masking
+		*out = new(MaskingSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConsulKVSpec.
@@ -116,6 +366,30 @@ func (in *ConsulKVSpec) DeepCopy() *ConsulKVSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConsulKVStatus) DeepCopyInto(out *ConsulKVStatus) {
 	*out = *in
+	if in.DetectorVersions != nil {
+		in, out := &in.DetectorVersions, &out.DetectorVersions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Certificates != nil {
+		in, out := &in.Certificates, &out.Certificates
+		*out = make([]CertificateStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExpiredExceptions != nil {
+		in, out := &in.ExpiredExceptions, &out.ExpiredExceptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConsulKVStatus.
@@ -128,9 +402,172 @@ func (in *ConsulKVStatus) DeepCopy() *ConsulKVStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DecodingSpec) DeepCopyInto(out *DecodingSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecodingSpec.
+func (in *DecodingSpec) DeepCopy() *DecodingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DecodingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DetectorsSpec) DeepCopyInto(out *DetectorsSpec) {
+	*out = *in
+	if in.Entropy != nil {
+		in, out := &in.Entropy, &out.Entropy
+		*out = new(EntropyDetectorSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Decoding != nil {
+		in, out := &in.Decoding, &out.Decoding
+		*out = new(DecodingSpec)
+		**out = **in
+	}
+	if in.Remote != nil {
+		in, out := &in.Remote, &out.Remote
+		*out = make([]RemoteDetectorSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Certificates != nil {
+		in, out := &in.Certificates, &out.Certificates
+		*out = new(CertificatesDetectorSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DetectorsSpec.
+func (in *DetectorsSpec) DeepCopy() *DetectorsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DetectorsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EntropyCharsetSpec) DeepCopyInto(out *EntropyCharsetSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EntropyCharsetSpec.
+func (in *EntropyCharsetSpec) DeepCopy() *EntropyCharsetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EntropyCharsetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EntropyDetectorSpec) DeepCopyInto(out *EntropyDetectorSpec) {
+	*out = *in
+	if in.Charsets != nil {
+		in, out := &in.Charsets, &out.Charsets
+		*out = make([]EntropyCharsetSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.Keywords != nil {
+		in, out := &in.Keywords, &out.Keywords
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EntropyDetectorSpec.
+func (in *EntropyDetectorSpec) DeepCopy() *EntropyDetectorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EntropyDetectorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExceptionSpec) DeepCopyInto(out *ExceptionSpec) {
+	*out = *in
+	in.ExpiresAt.DeepCopyInto(&out.ExpiresAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExceptionSpec.
+func (in *ExceptionSpec) DeepCopy() *ExceptionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ExceptionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyRuleSpec) DeepCopyInto(out *KeyRuleSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyRuleSpec.
+func (in *KeyRuleSpec) DeepCopy() *KeyRuleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyRuleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaskingSpec) DeepCopyInto(out *MaskingSpec) {
+	*out = *in
+	if in.KeepLast != nil {
+		in, out := &in.KeepLast, &out.KeepLast
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaskingSpec.
+func (in *MaskingSpec) DeepCopy() *MaskingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MaskingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MatchAdaptationsSpec) DeepCopyInto(out *MatchAdaptationsSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MatchAdaptationsSpec.
+func (in *MatchAdaptationsSpec) DeepCopy() *MatchAdaptationsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MatchAdaptationsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PathSpec) DeepCopyInto(out *PathSpec) {
 	*out = *in
+	if in.Validation != nil {
+		in, out := &in.Validation, &out.Validation
+		*out = new(ValueValidationSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PathSpec.
@@ -142,3 +579,173 @@ func (in *PathSpec) DeepCopy() *PathSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemoteDetectorSpec) DeepCopyInto(out *RemoteDetectorSpec) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteDetectorSpec.
+func (in *RemoteDetectorSpec) DeepCopy() *RemoteDetectorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RemoteDetectorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SensitiveFinding) DeepCopyInto(out *SensitiveFinding) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SensitiveFinding.
+func (in *SensitiveFinding) DeepCopy() *SensitiveFinding {
+	if in == nil {
+		return nil
+	}
+	out := new(SensitiveFinding)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SensitiveFinding) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SensitiveFindingList) DeepCopyInto(out *SensitiveFindingList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SensitiveFinding, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SensitiveFindingList.
+func (in *SensitiveFindingList) DeepCopy() *SensitiveFindingList {
+	if in == nil {
+		return nil
+	}
+	out := new(SensitiveFindingList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SensitiveFindingList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SensitiveFindingSpec) DeepCopyInto(out *SensitiveFindingSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SensitiveFindingSpec.
+func (in *SensitiveFindingSpec) DeepCopy() *SensitiveFindingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SensitiveFindingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SensitiveFindingStatus) DeepCopyInto(out *SensitiveFindingStatus) {
+	*out = *in
+	if in.LastTransitionTime != nil {
+		in, out := &in.LastTransitionTime, &out.LastTransitionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SensitiveFindingStatus.
+func (in *SensitiveFindingStatus) DeepCopy() *SensitiveFindingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SensitiveFindingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StructuredValuesSpec) DeepCopyInto(out *StructuredValuesSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StructuredValuesSpec.
+func (in *StructuredValuesSpec) DeepCopy() *StructuredValuesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StructuredValuesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValueRuleSpec) DeepCopyInto(out *ValueRuleSpec) {
+	*out = *in
+	if in.Enum != nil {
+		in, out := &in.Enum, &out.Enum
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValueRuleSpec.
+func (in *ValueRuleSpec) DeepCopy() *ValueRuleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ValueRuleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValueValidationSpec) DeepCopyInto(out *ValueValidationSpec) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]ValueRuleSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValueValidationSpec.
+func (in *ValueValidationSpec) DeepCopy() *ValueValidationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ValueValidationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// baseline scans the current content of a KV group and writes out a ConfigMap accepting every finding as known,
+// for the ConsulKV to reference through its 'baseline' field. It must run with the FINDINGS_HMAC_KEY of the operator for the fingerprints to match,
+// and with the alias library and breached credentials corpus of the operator whenever the guards rely on them.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/controller"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/secretengine"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func main() {
+	var consulKvManifest string
+	var consulUrl string
+	var name string
+	var output string
+	var breachedCorpus string
+	regoPolicies := []string{}
+	aliasLibraries := []string{}
+	flag.StringVar(&consulKvManifest, "consulkv", "", "The manifest of the ConsulKV whose findings make up the baseline.")
+	flag.StringVar(&consulUrl, "consul-url", "", "Overrides the Consul URL of the ConsulKV, e.g. to go through a port-forward.")
+	flag.StringVar(&name, "name", "", "The name of the baseline ConfigMap, '<consulkv>-baseline' by default.")
+	flag.StringVar(&output, "output", "-", "Where to write the baseline ConfigMap to, '-' being the standard output.")
+	flag.Func("rego-policy", "A Rego module evaluated along with the guards, may be repeated.", func(value string) error {
+		regoPolicies = append(regoPolicies, value)
+		return nil
+	})
+	flag.Func("alias-library", fmt.Sprintf("The manifest of a ConfigMap labelled with %s extending the regex aliases, may be repeated.", sascomv1.AliasLibraryLabel), func(value string) error {
+		aliasLibraries = append(aliasLibraries, value)
+		return nil
+	})
+	flag.StringVar(&breachedCorpus, "breached-corpus", "", "The breached credentials corpus of the operator, required by the 'breached-credentials' guard.")
+	flag.Parse()
+
+	findingsHmacKey := os.Getenv("FINDINGS_HMAC_KEY")
+	if findingsHmacKey == "" {
+		fmt.Fprintln(os.Stderr, "FINDINGS_HMAC_KEY must be set to the key of the operator, the baseline would never match otherwise")
+		os.Exit(1)
+	}
+	if err := loadAliasLibrary(aliasLibraries); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load the alias library: %v\n", err)
+		os.Exit(1)
+	}
+	if breachedCorpus != "" {
+		if err := secretengine.LoadBreachedCorpus(breachedCorpus); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to load the breached credentials corpus: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := generate(consulKvManifest, consulUrl, name, output, regoPolicies, utils.NewRedactor([]byte(findingsHmacKey))); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to generate the baseline: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func generate(consulKvManifest string, consulUrl string, name string, output string, regoPolicies []string, redactor utils.Redactor) error {
+	manifest, err := os.ReadFile(consulKvManifest)
+	if err != nil {
+		return fmt.Errorf("failed to read the ConsulKV manifest: %w", err)
+	}
+	consulKv := &sascomv1.ConsulKV{}
+	if err := yaml.Unmarshal(manifest, consulKv); err != nil {
+		return fmt.Errorf("failed to parse the ConsulKV manifest: %w", err)
+	}
+	if consulUrl != "" {
+		consulKv.Spec.ConsulUrl = consulUrl
+	}
+	if unresolved := secretengine.UnresolvedAliases(consulKv); len(unresolved) != 0 {
+		return fmt.Errorf("the guards %s resolve to no detector nor alias, pass the alias library of the operator with --alias-library", strings.Join(unresolved, ", "))
+	}
+	regoPolicyModules := map[string]string{}
+	for _, path := range regoPolicies {
+		module, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read the Rego policy %s: %w", path, err)
+		}
+		regoPolicyModules[filepath.Base(path)] = string(module)
+	}
+
+	payload, _, pathToMetadata, err := controller.FetchConsulPayload(consulKv)
+	if err != nil {
+		return err
+	}
+	baseline, detectorErrors := secretengine.GenerateBaseline(consulKv, payload, pathToMetadata, regoPolicyModules, redactor)
+	if len(detectorErrors) != 0 {
+		// the baseline would miss whatever the failing detectors would have found, so that a baseline generated without, say, the breached corpus would look complete
+		messages := []string{}
+		for _, detectorError := range detectorErrors {
+			messages = append(messages, detectorError.Error())
+		}
+		return fmt.Errorf("%d detector error(s) while scanning: %s", len(detectorErrors), strings.Join(messages, "; "))
+	}
+	baselineJson, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode the baseline: %w", err)
+	}
+
+	if name == "" {
+		name = consulKv.Name + "-baseline"
+	}
+	configMap := corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: consulKv.Namespace},
+		Data:       map[string]string{secretengine.BaselineConfigMapKey: string(baselineJson)},
+	}
+	configMapYaml, err := yaml.Marshal(configMap)
+	if err != nil {
+		return fmt.Errorf("failed to encode the baseline ConfigMap: %w", err)
+	}
+	if output == "-" {
+		_, err = os.Stdout.Write(configMapYaml)
+		return err
+	}
+	if err := os.WriteFile(output, configMapYaml, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+	fmt.Fprintf(os.Stderr, "wrote a baseline of %d finding(s) to %s, reference it from the ConsulKV with 'baseline: %s'\n", len(baseline.Findings), output, name)
+	return nil
+}
+
+// loadAliasLibrary loads the aliases of the provided ConfigMap manifests, merged in the order of their names like the operator does
+func loadAliasLibrary(configMapManifests []string) error {
+	configMaps := []corev1.ConfigMap{}
+	for _, path := range configMapManifests {
+		manifest, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read the alias library %s: %w", path, err)
+		}
+		configMap := corev1.ConfigMap{}
+		if err := yaml.Unmarshal(manifest, &configMap); err != nil {
+			return fmt.Errorf("failed to parse the alias library %s: %w", path, err)
+		}
+		configMaps = append(configMaps, configMap)
+	}
+	aliases := controller.MergeAliasLibrary(configMaps, func(alias string, configMapName string) {
+		fmt.Fprintf(os.Stderr, "ignoring the alias %s of %s, defined more than once\n", alias, configMapName)
+	})
+	if errs := secretengine.LoadAliasLibrary(aliases); len(errs) != 0 {
+		messages := []string{}
+		for _, err := range errs {
+			messages = append(messages, err.Error())
+		}
+		return fmt.Errorf("%s", strings.Join(messages, "; "))
+	}
+	return nil
+}
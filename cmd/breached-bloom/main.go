@@ -0,0 +1,62 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// breached-bloom turns a list of SHA-1 hashes of breached credentials into a bloom filter the operator loads through --breached-corpus-path,
+// e.g. to ship it as the binary data of a ConfigMap.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/secretengine"
+)
+
+func main() {
+	var input string
+	var output string
+	var falsePositiveRate float64
+	flag.StringVar(&input, "input", "", "The hash list, one SHA-1 hash per line optionally followed by ':<count>'.")
+	flag.StringVar(&output, "output", "breached.bloom", "Where to write the bloom filter to.")
+	flag.Float64Var(&falsePositiveRate, "false-positive-rate", 0.001, "The share of values wrongly reported as breached the filter is sized for.")
+	flag.Parse()
+
+	if err := build(input, output, falsePositiveRate); err != nil {
+		fmt.Printf("failed to build the bloom filter: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func build(input string, output string, falsePositiveRate float64) error {
+	hashList, err := os.Open(input)
+	if err != nil {
+		return fmt.Errorf("failed to open the hash list: %w", err)
+	}
+	defer hashList.Close()
+	filter, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", output, err)
+	}
+	defer filter.Close()
+
+	count, err := secretengine.BuildBreachedBloomFilter(hashList, filter, falsePositiveRate)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("wrote a bloom filter of %d hashes to %s\n", count, output)
+	return nil
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// detector-plugin is a reference external detector: it flags the values containing any of a list of denied words
+// and, for plugins only granted fingerprints, the values whose fingerprint is among a list of known ones.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/detectorplugin"
+)
+
+func main() {
+	var bindAddress string
+	var deniedWords string
+	var fingerprintsFile string
+	flag.StringVar(&bindAddress, "bind-address", ":8090", "The address the plugin endpoint binds to.")
+	flag.StringVar(&deniedWords, "denied-words", "", "Comma-separated words flagged wherever they show up in a value, case-insensitively.")
+	flag.StringVar(&fingerprintsFile, "fingerprints-file", "", "File listing one known fingerprint per line, the values sharing one of them get flagged.")
+	flag.Parse()
+
+	words := []string{}
+	for _, word := range strings.Split(deniedWords, ",") {
+		if word = strings.TrimSpace(word); word != "" {
+			words = append(words, strings.ToLower(word))
+		}
+	}
+	fingerprints, err := loadFingerprints(fingerprintsFile)
+	if err != nil {
+		fmt.Printf("failed to load the known fingerprints: %v\n", err)
+		os.Exit(1)
+	}
+
+	http.Handle("/detect", detectorplugin.Handler(func(_ string, item detectorplugin.Item) []detectorplugin.Finding {
+		findings := []detectorplugin.Finding{}
+		if fingerprints[item.Fingerprint] {
+			findings = append(findings, detectorplugin.Finding{RuleID: "known-fingerprint", Confidence: 1, Severity: "high"})
+		}
+		if item.Value == nil {
+			return findings
+		}
+		lowered := strings.ToLower(*item.Value)
+		for _, word := range words {
+			if start := strings.Index(lowered, word); start != -1 {
+				findings = append(findings, detectorplugin.Finding{
+					RuleID:     "denied-word",
+					Confidence: 0.9,
+					Severity:   "medium",
+					Span:       &detectorplugin.Span{Start: start, End: start + len(word)},
+				})
+			}
+		}
+		return findings
+	}))
+	fmt.Printf("serving the detector plugin schema %s on %s/detect\n", detectorplugin.SchemaVersion, bindAddress)
+	if err := http.ListenAndServe(bindAddress, nil); err != nil {
+		fmt.Printf("plugin server stopped: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func loadFingerprints(path string) (map[string]bool, error) {
+	fingerprints := map[string]bool{}
+	if path == "" {
+		return fingerprints, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			fingerprints[line] = true
+		}
+	}
+	return fingerprints, scanner.Err()
+}
@@ -19,7 +19,10 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"os"
+	goruntime "runtime"
+	"time"
 
 	"github.com/PagerDuty/go-pagerduty"
 	"github.com/aws/aws-sdk-go/aws"
@@ -28,6 +31,7 @@ import (
 	"github.com/yashvardhan-kukreja/consulkv-commander/internal/adaptationengine"
 	"github.com/yashvardhan-kukreja/consulkv-commander/internal/knowledgebase"
 	"github.com/yashvardhan-kukreja/consulkv-commander/internal/secretengine"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -39,6 +43,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
@@ -62,14 +67,46 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var globalBlastRadius sascomv1.BlastRadiusSpec
+	detectorErrorPolicies := utils.DeepCopyMap(secretengine.DefaultDetectorErrorPolicies)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.IntVar(&globalBlastRadius.MaxKeysPerReconcile, "max-heal-keys-per-reconcile", 0,
+		"Maximum number of keys self-heal may delete from a single KV group in one reconciliation. Zero means unbounded.")
+	flag.IntVar(&globalBlastRadius.MaxPercentPerReconcile, "max-heal-percent-per-reconcile", 0,
+		"Maximum percentage of a KV group self-heal may delete in one reconciliation. Zero means unbounded.")
+	flag.IntVar(&globalBlastRadius.MaxKeysPerHour, "max-heal-keys-per-hour", 0,
+		"Maximum number of keys self-heal may delete across all KV groups per hour. Zero means unbounded.")
+	flag.IntVar(&globalBlastRadius.MaxPercentPerHour, "max-heal-percent-per-hour", 0,
+		"Maximum percentage of a KV group self-heal may delete per hour. Zero means unbounded.")
+	for _, qos := range []sascomv1.QoSType{sascomv1.Critical, sascomv1.Medium, sascomv1.Relaxed} {
+		qos := qos
+		flag.Func(fmt.Sprintf("detector-error-policy-%s", qos),
+			fmt.Sprintf("What to do with the values a detector failed to evaluate for %s KV groups: fail-closed, fail-open or halt (default %s).", qos, detectorErrorPolicies[qos]),
+			func(value string) error {
+				policy := sascomv1.DetectorErrorPolicy(value)
+				if policy != sascomv1.FailClosed && policy != sascomv1.FailOpen && policy != sascomv1.HaltSync {
+					return fmt.Errorf("unknown detector error policy '%s'", value)
+				}
+				detectorErrorPolicies[qos] = policy
+				return nil
+			})
+	}
 	opts := zap.Options{
 		Development: true,
 	}
+	var scanWorkers int
+	flag.IntVar(&scanWorkers, "scan-workers", goruntime.GOMAXPROCS(0),
+		"The amount of values of a single ConsulKV scanned in parallel.")
+	var aliasLibraryNamespace string
+	flag.StringVar(&aliasLibraryNamespace, "alias-library-namespace", os.Getenv("POD_NAMESPACE"),
+		fmt.Sprintf("The namespace whose ConfigMaps labelled with %s extend the regex aliases. Defaults to the namespace of the operator, the library is disabled when empty.", sascomv1.AliasLibraryLabel))
+	var breachedCorpusPath string
+	flag.StringVar(&breachedCorpusPath, "breached-corpus-path", "",
+		"The SHA-1 hash list, bloom filter or directory of k-anonymity range files the breached-credentials detector checks values against. It gets reloaded whenever modified.")
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
@@ -109,6 +146,10 @@ func main() {
 	sheetLink := os.Getenv("SHEET_LINK")
 	pdClientToken := os.Getenv("PAGERDUTY_TOKEN")
 	pdSender := os.Getenv("PAGERDUTY_SENDER")
+	findingsHmacKey := os.Getenv("FINDINGS_HMAC_KEY")
+	if findingsHmacKey == "" {
+		setupLog.Info("FINDINGS_HMAC_KEY is not set, fingerprints of the findings won't be stable across restarts")
+	}
 
 	// adaptation engine
 	adaptationEngineClient, err := adaptationengine.NewClient(
@@ -121,6 +162,7 @@ func main() {
 			Region:      aws.String("us-east-1"),
 			Credentials: credentials.NewStaticCredentials(awsAccessKey, awsSecretAccessKey, ""),
 		},
+		globalBlastRadius,
 	)
 	if err != nil {
 		setupLog.Error(err, "unable to setup the adaptation engine client")
@@ -131,6 +173,9 @@ func main() {
 	secretEngineClient := secretengine.NewClient(
 		invalidationsTrackingCtx,
 		adaptationEngineClient,
+		detectorErrorPolicies,
+		utils.NewRedactor([]byte(findingsHmacKey)),
+		scanWorkers,
 	)
 
 	rec := &controller.ConsulKVReconciler{
@@ -142,6 +187,38 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "ConsulKV")
 		os.Exit(1)
 	}
+	if aliasLibraryNamespace != "" {
+		if err = (&controller.AliasLibraryReconciler{
+			Client:    mgr.GetClient(),
+			Namespace: aliasLibraryNamespace,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "AliasLibrary")
+			os.Exit(1)
+		}
+	}
+	if breachedCorpusPath != "" {
+		if err = secretengine.LoadBreachedCorpus(breachedCorpusPath); err != nil {
+			setupLog.Error(err, "unable to load the breached credentials corpus")
+			os.Exit(1)
+		}
+		if err = mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			secretengine.WatchBreachedCorpus(ctx, breachedCorpusPath, time.Minute)
+			return nil
+		})); err != nil {
+			setupLog.Error(err, "unable to watch the breached credentials corpus")
+			os.Exit(1)
+		}
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&sascomv1.AdaptationRequest{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "AdaptationRequest")
+			os.Exit(1)
+		}
+		if err = (&sascomv1.ConsulKV{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ConsulKV")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,67 @@
+package adaptationengine
+
+import (
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// advisoryTrackingMode keys the advisory findings in the knowledge base, apart from the invalidations of any adaptation mode
+const advisoryTrackingMode = "advisory"
+
+// advisoryCategories are the categories of the findings which leak nothing, they are reported but never remediated
+var advisoryCategories = map[utils.FindingCategory]bool{
+	utils.CertificateCategory:    true,
+	utils.PolicyAdvisoryCategory: true,
+}
+
+// splitAdvisoryFindings separates the findings to remediate from the advisory ones, an invalidation holding both is split in two
+func splitAdvisoryFindings(invalidationsOutput utils.InvalidationsOutput) (utils.InvalidationsOutput, utils.InvalidationsOutput) {
+	toRemediate, advisory := utils.InvalidationsOutput{}, utils.InvalidationsOutput{}
+	for _, inv := range invalidationsOutput {
+		remediatedFindings, advisoryFindings := []utils.Finding{}, []utils.Finding{}
+		for _, finding := range inv.Findings {
+			if advisoryCategories[finding.Category] {
+				advisoryFindings = append(advisoryFindings, finding)
+			} else {
+				remediatedFindings = append(remediatedFindings, finding)
+			}
+		}
+		switch {
+		case len(advisoryFindings) == 0:
+			toRemediate = append(toRemediate, inv)
+		case len(remediatedFindings) == 0:
+			advisory = append(advisory, inv)
+		default:
+			toRemediate = append(toRemediate, utils.NewInvalidation(inv.Path, inv.RedactedValue, remediatedFindings))
+			advisory = append(advisory, utils.NewInvalidation(inv.Path, inv.RedactedValue, advisoryFindings))
+		}
+	}
+	return toRemediate, advisory
+}
+
+// reportAdvisoryFindings notifies about the advisory findings, the keys they are about are neither deleted from Consul nor kept out of the configmap
+func (c Client) reportAdvisoryFindings(item *sascomv1.ConsulKV, advisory utils.InvalidationsOutput) {
+	consulKvKey := client.ObjectKeyFromObject(item).String()
+	raisePager := !canIgnorePagingInvalidationsOutput(c.invalidationsTrackingContext, consulKvKey, advisory, advisoryTrackingMode)
+	c.invalidationsTrackingContext.SetInvalidationsOutput(consulKvKey, advisory, advisoryTrackingMode)
+
+	var urgencyLevel UrgencyLevel
+	switch item.Spec.QoS {
+	case sascomv1.Critical:
+		urgencyLevel = HighUrgencyLevel
+	case sascomv1.Medium:
+		urgencyLevel = LowUrgencyLevel
+	default: // including Relaxed mode
+		raisePager = false
+	}
+	if raisePager {
+		pagerBody := fmt.Sprintf("A KV group (%s) holds values needing attention, they were left in place"+
+			"\nDetails:"+
+			"\n%s", consulKvKey, advisory)
+		if err := c.RaisePager(urgencyLevel, pagerBody); err != nil {
+			fmt.Printf("%s\n", err.Error())
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package adaptationengine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultApprovalTTL = time.Hour
+
+func requiresApproval(item *sascomv1.ConsulKV) bool {
+	return item.Spec.Approval != nil && item.Spec.Approval.Enabled
+}
+
+// approvedAdaptationRequest finds the live AdaptationRequest covering exactly the provided invalidations and tells whether it got approved.
+// If no live request exists, a new pending one gets raised.
+func (c Client) approvedAdaptationRequest(item *sascomv1.ConsulKV, invalidationsOutput utils.InvalidationsOutput) (*sascomv1.AdaptationRequest, bool, error) {
+	ctx := context.Background()
+	now := metav1.Now()
+
+	keys := invalidationsOutput.Paths()
+	sort.Strings(keys)
+	keysHash := hashKeys(keys)
+
+	list := &sascomv1.AdaptationRequestList{}
+	if err := c.k8sClient.List(ctx, list, client.InNamespace(item.Namespace), client.MatchingLabels{
+		sascomv1.AdaptationRequestConsulKVLabel: item.Name,
+		sascomv1.AdaptationRequestKeysHashLabel: keysHash,
+	}); err != nil {
+		return nil, false, fmt.Errorf("error occurred while listing the adaptation requests of %s: %w", client.ObjectKeyFromObject(item).String(), err)
+	}
+
+	for _, adaptationRequest := range list.Items {
+		adaptationRequest := adaptationRequest
+		// labels are user-writable, so never trust them over the actual keys being asked for
+		if strings.Join(adaptationRequest.Spec.Keys, ",") != strings.Join(keys, ",") {
+			continue
+		}
+		switch adaptationRequest.Status.Phase {
+		case sascomv1.AdaptationRequestExpired, sascomv1.AdaptationRequestExecuted:
+			continue
+		}
+		if adaptationRequest.IsExpired(now) {
+			if err := c.setAdaptationRequestPhase(&adaptationRequest, sascomv1.AdaptationRequestExpired, "expired before being executed"); err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+
+		decision, decidedBy := adaptationRequest.Decision()
+		switch decision {
+		case sascomv1.AdaptationApproved:
+			if adaptationRequest.Status.Phase != sascomv1.AdaptationRequestApproved {
+				if err := c.setAdaptationRequestPhase(&adaptationRequest, sascomv1.AdaptationRequestApproved, fmt.Sprintf("approved by %s", decidedBy)); err != nil {
+					return nil, false, err
+				}
+			}
+			return &adaptationRequest, true, nil
+		case sascomv1.AdaptationRejected:
+			if adaptationRequest.Status.Phase != sascomv1.AdaptationRequestRejected {
+				if err := c.setAdaptationRequestPhase(&adaptationRequest, sascomv1.AdaptationRequestRejected, fmt.Sprintf("rejected by %s", decidedBy)); err != nil {
+					return nil, false, err
+				}
+			}
+			return &adaptationRequest, false, nil
+		default:
+			return &adaptationRequest, false, nil
+		}
+	}
+
+	adaptationRequest, err := c.raiseAdaptationRequest(item, invalidationsOutput, keys, keysHash)
+	return adaptationRequest, false, err
+}
+
+func (c Client) raiseAdaptationRequest(item *sascomv1.ConsulKV, invalidationsOutput utils.InvalidationsOutput, keys []string, keysHash string) (*sascomv1.AdaptationRequest, error) {
+	ctx := context.Background()
+
+	ttl := defaultApprovalTTL
+	if item.Spec.Approval.TTL != nil {
+		ttl = item.Spec.Approval.TTL.Duration
+	}
+
+	evidence := []sascomv1.AdaptationEvidence{}
+	for _, inv := range invalidationsOutput {
+		evidence = append(evidence, sascomv1.AdaptationEvidence{
+			Path:          inv.Path,
+			Rule:          inv.RuleID,
+			RedactedValue: inv.Preview,
+			Fingerprint:   inv.Fingerprint,
+		})
+	}
+	sort.Slice(evidence, func(i, j int) bool { return evidence[i].Path < evidence[j].Path })
+
+	adaptationRequest := &sascomv1.AdaptationRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: item.Name + "-",
+			Namespace:    item.Namespace,
+			Labels: map[string]string{
+				sascomv1.AdaptationRequestConsulKVLabel: item.Name,
+				sascomv1.AdaptationRequestKeysHashLabel: keysHash,
+			},
+		},
+		Spec: sascomv1.AdaptationRequestSpec{
+			ConsulKV:  item.Name,
+			Keys:      keys,
+			Evidence:  evidence,
+			ExpiresAt: metav1.NewTime(time.Now().Add(ttl)),
+		},
+	}
+	if err := controllerutil.SetOwnerReference(item, adaptationRequest, c.k8sClient.Scheme()); err != nil {
+		return nil, fmt.Errorf("failed to setup the owner reference on the adaptation request: %w", err)
+	}
+	if err := c.k8sClient.Create(ctx, adaptationRequest); err != nil {
+		return nil, fmt.Errorf("error occurred while creating the adaptation request for %s: %w", client.ObjectKeyFromObject(item).String(), err)
+	}
+	if err := c.setAdaptationRequestPhase(adaptationRequest, sascomv1.AdaptationRequestPending, "awaiting a decision from an approver"); err != nil {
+		return nil, err
+	}
+	return adaptationRequest, nil
+}
+
+func (c Client) setAdaptationRequestPhase(adaptationRequest *sascomv1.AdaptationRequest, phase sascomv1.AdaptationRequestPhase, message string) error {
+	adaptationRequest.Status.Phase = phase
+	adaptationRequest.Status.Message = message
+	if err := c.k8sClient.Status().Update(context.Background(), adaptationRequest); err != nil {
+		return fmt.Errorf("error occurred while moving the adaptation request %s to the phase '%s': %w", client.ObjectKeyFromObject(adaptationRequest).String(), phase, err)
+	}
+	return nil
+}
+
+func hashKeys(sortedKeys []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(sortedKeys, "\n")))
+	return hex.EncodeToString(sum[:])[:16]
+}
@@ -0,0 +1,67 @@
+package adaptationengine
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+func listAdaptationRequests(t *testing.T, k8sClient client.Client) []sascomv1.AdaptationRequest {
+	list := &sascomv1.AdaptationRequestList{}
+	if err := k8sClient.List(context.Background(), list); err != nil {
+		t.Fatalf("failed to list the adaptation requests: %v", err)
+	}
+	return list.Items
+}
+
+// TestSelfHealAwaitsApproval guards that no key gets deleted from Consul before an approver signs off on the request covering exactly these keys,
+// a rejected request keeping the keys out of the configmap only.
+func TestSelfHealAwaitsApproval(t *testing.T) {
+	for _, decision := range []sascomv1.AdaptationDecision{sascomv1.AdaptationApproved, sascomv1.AdaptationRejected} {
+		c, k8sClient, rec, server := newTestClient(t)
+		item := testConsulKV(server.URL)
+		item.Spec.Approval = &sascomv1.ApprovalSpec{Enabled: true}
+		payload := map[string]string{"app.token": "leak", "app.region": "eu"}
+		weights := map[string]int{"app.token": 1, "app.region": 0}
+		invalidationsOutput := utils.InvalidationsOutput{invalidation("app.token", finding("github-token", ""))}
+
+		sanitized, err := c.Adapt(item, invalidationsOutput, payload, weights)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", decision, err)
+		}
+		requests := listAdaptationRequests(t, k8sClient)
+		if len(requests) != 1 || requests[0].Status.Phase != sascomv1.AdaptationRequestPending || !reflect.DeepEqual(requests[0].Spec.Keys, []string{"app.token"}) {
+			t.Fatalf("%s: expected a pending request for app.token, got %+v", decision, requests)
+		}
+		if item.Status.AdaptationMode != sascomv1.SelfProtecting || item.Status.PendingAdaptationRequest != requests[0].Name {
+			t.Errorf("%s: expected to protect the configmap while the request is pending, got %+v", decision, item.Status)
+		}
+		if !reflect.DeepEqual(sanitized, map[string]string{"app.region": "eu"}) {
+			t.Errorf("%s: the flagged key wasn't kept out of the configmap: %v", decision, sanitized)
+		}
+
+		requests[0].Annotations = map[string]string{sascomv1.AdaptationDecisionAnnotation: string(decision), sascomv1.AdaptationDecidedByAnnotation: "jane"}
+		if err := k8sClient.Update(context.Background(), &requests[0]); err != nil {
+			t.Fatalf("%s: failed to decide on the request: %v", decision, err)
+		}
+		if _, err := c.Adapt(item, invalidationsOutput, payload, weights); err != nil {
+			t.Fatalf("%s: unexpected error: %v", decision, err)
+		}
+
+		wantMutations, wantPhase := []string{}, sascomv1.AdaptationRequestRejected
+		if decision == sascomv1.AdaptationApproved {
+			wantMutations, wantPhase = []string{"DELETE app/token"}, sascomv1.AdaptationRequestExecuted
+		}
+		if mutations := rec.recordedMutations(); !reflect.DeepEqual(mutations, wantMutations) {
+			t.Errorf("%s: expected the mutations %v, got %v", decision, wantMutations, mutations)
+		}
+		if phase := listAdaptationRequests(t, k8sClient)[0].Status.Phase; phase != wantPhase {
+			t.Errorf("%s: expected the request to be %s, got %s", decision, wantPhase, phase)
+		}
+	}
+}
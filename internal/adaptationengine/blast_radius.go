@@ -0,0 +1,86 @@
+package adaptationengine
+
+import (
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+)
+
+// haltHealingIfBlastRadiusExceeded latches the ConsulKV into a halted state the moment deleting the flagged keys would exceed
+// any blast-radius limit, and keeps it there until a new override annotation gets acknowledged.
+func (c Client) haltHealingIfBlastRadiusExceeded(item *sascomv1.ConsulKV, keysToDelete int, totalKeys int) bool {
+	override := item.Annotations[sascomv1.BlastRadiusOverrideAnnotation]
+	if override != "" && override != item.Status.AcknowledgedBlastRadiusOverride {
+		item.Status.AcknowledgedBlastRadiusOverride = override
+		item.Status.HealingHaltedReason = ""
+		return false
+	}
+	if item.Status.HealingHaltedReason != "" {
+		return true
+	}
+
+	reason := c.blastRadiusViolation(item, keysToDelete, totalKeys)
+	if reason == "" {
+		return false
+	}
+	item.Status.HealingHaltedReason = reason
+
+	pagerBody := fmt.Sprintf("Self-healing of the KV group (%s) was halted: %s"+
+		"\nThe flagged keys are only being kept out of the configmap from now on."+
+		"\nSet the annotation '%s' to a new value on the ConsulKV to let self-healing resume.",
+		client.ObjectKeyFromObject(item).String(), reason, sascomv1.BlastRadiusOverrideAnnotation)
+	_ = c.RaisePager(HighUrgencyLevel, pagerBody)
+	return true
+}
+
+// blastRadiusViolation describes the first blast-radius limit which deleting keysToDelete keys out of totalKeys would exceed.
+// The operator-wide keys per hour limit is accounted across every ConsulKV, every other operator-wide limit applies to each ConsulKV separately.
+func (c Client) blastRadiusViolation(item *sascomv1.ConsulKV, keysToDelete int, totalKeys int) string {
+	consulKvKey := client.ObjectKeyFromObject(item).String()
+	hourAgo := time.Now().Add(-time.Hour)
+
+	limits := c.globalBlastRadius
+	if item.Spec.BlastRadius != nil {
+		limits = sascomv1.BlastRadiusSpec{
+			MaxKeysPerReconcile:    tighterLimit(item.Spec.BlastRadius.MaxKeysPerReconcile, limits.MaxKeysPerReconcile),
+			MaxPercentPerReconcile: tighterLimit(item.Spec.BlastRadius.MaxPercentPerReconcile, limits.MaxPercentPerReconcile),
+			MaxKeysPerHour:         item.Spec.BlastRadius.MaxKeysPerHour,
+			MaxPercentPerHour:      tighterLimit(item.Spec.BlastRadius.MaxPercentPerHour, limits.MaxPercentPerHour),
+		}
+	} else {
+		limits.MaxKeysPerHour = 0
+	}
+
+	if limits.MaxKeysPerReconcile > 0 && keysToDelete > limits.MaxKeysPerReconcile {
+		return fmt.Sprintf("%d keys were flagged for deletion while at most %d may be deleted per reconciliation", keysToDelete, limits.MaxKeysPerReconcile)
+	}
+	if limits.MaxPercentPerReconcile > 0 && keysToDelete*100 > limits.MaxPercentPerReconcile*totalKeys {
+		return fmt.Sprintf("%d out of %d keys were flagged for deletion while at most %d%% may be deleted per reconciliation", keysToDelete, totalKeys, limits.MaxPercentPerReconcile)
+	}
+
+	deletedInLastHour := c.invalidationsTrackingContext.GetDeletionsSince(consulKvKey, hourAgo)
+	if limits.MaxKeysPerHour > 0 && deletedInLastHour+keysToDelete > limits.MaxKeysPerHour {
+		return fmt.Sprintf("%d keys were already deleted in the last hour and %d more were flagged while at most %d may be deleted per hour", deletedInLastHour, keysToDelete, limits.MaxKeysPerHour)
+	}
+	// the keys deleted during the last hour are not part of the payload anymore, so count them back in to get the size of the KV group an hour ago
+	if limits.MaxPercentPerHour > 0 && (deletedInLastHour+keysToDelete)*100 > limits.MaxPercentPerHour*(totalKeys+deletedInLastHour) {
+		return fmt.Sprintf("%d keys were already deleted in the last hour and %d more were flagged while at most %d%% of the KV group may be deleted per hour", deletedInLastHour, keysToDelete, limits.MaxPercentPerHour)
+	}
+
+	if c.globalBlastRadius.MaxKeysPerHour > 0 {
+		deletedGloballyInLastHour := c.invalidationsTrackingContext.GetDeletionsSince("", hourAgo)
+		if deletedGloballyInLastHour+keysToDelete > c.globalBlastRadius.MaxKeysPerHour {
+			return fmt.Sprintf("%d keys were already deleted across all KV groups in the last hour and %d more were flagged while at most %d may be deleted per hour operator-wide", deletedGloballyInLastHour, keysToDelete, c.globalBlastRadius.MaxKeysPerHour)
+		}
+	}
+	return ""
+}
+
+// tighterLimit picks the stricter of two limits where zero means unbounded
+func tighterLimit(a, b int) int {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
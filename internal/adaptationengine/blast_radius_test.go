@@ -0,0 +1,105 @@
+package adaptationengine
+
+import (
+	"reflect"
+	"testing"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+// TestBlastRadiusHaltsSelfHeal guards that self-heal deletes nothing once a limit would be exceeded, stays halted across reconciliations
+// and resumes for a single reconciliation whenever a new override gets set.
+func TestBlastRadiusHaltsSelfHeal(t *testing.T) {
+	c, _, rec, server := newTestClient(t)
+	item := testConsulKV(server.URL)
+	item.Spec.BlastRadius = &sascomv1.BlastRadiusSpec{MaxKeysPerReconcile: 1}
+	payload := map[string]string{"app.token": "leak", "app.password": "leak", "app.region": "eu"}
+	weights := map[string]int{"app.token": 1, "app.password": 1, "app.region": 0}
+	invalidationsOutput := utils.InvalidationsOutput{invalidation("app.password", finding("password", "")), invalidation("app.token", finding("github-token", ""))}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		sanitized, err := c.Adapt(item, invalidationsOutput, payload, weights)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if item.Status.AdaptationMode != sascomv1.SelfProtecting || item.Status.HealingHaltedReason == "" {
+			t.Errorf("attempt %d: expected self-heal to be halted, got the mode %s", attempt, item.Status.AdaptationMode)
+		}
+		if !reflect.DeepEqual(sanitized, map[string]string{"app.region": "eu"}) {
+			t.Errorf("attempt %d: the flagged keys weren't kept out of the configmap: %v", attempt, sanitized)
+		}
+	}
+	if mutations := rec.recordedMutations(); len(mutations) != 0 {
+		t.Errorf("a halted self-heal mutated Consul: %v", mutations)
+	}
+	if len(rec.recordedIncidents()) == 0 {
+		t.Errorf("no incident was raised about the halted self-heal")
+	}
+
+	item.Annotations = map[string]string{sascomv1.BlastRadiusOverrideAnnotation: "ticket-42"}
+	if _, err := c.Adapt(item, invalidationsOutput, payload, weights); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"DELETE app/password", "DELETE app/token"}; !reflect.DeepEqual(rec.recordedMutations(), want) {
+		t.Errorf("expected the override to let %v through, got %v", want, rec.recordedMutations())
+	}
+	if item.Status.HealingHaltedReason != "" || item.Status.AcknowledgedBlastRadiusOverride != "ticket-42" {
+		t.Errorf("the override wasn't acknowledged: %+v", item.Status)
+	}
+}
+
+// TestBlastRadiusOnlyCountsDeletedKeys guards that the keys kept in place once masked don't count against the limits,
+// only the ones self-heal would delete do.
+func TestBlastRadiusOnlyCountsDeletedKeys(t *testing.T) {
+	c, _, rec, server := newTestClient(t)
+	item := testConsulKV(server.URL)
+	item.Spec.BlastRadius = &sascomv1.BlastRadiusSpec{MaxKeysPerReconcile: 1}
+	item.Spec.Masking = &sascomv1.MaskingSpec{Placeholder: "***"}
+	payload := map[string]string{"app.token": "leak", "app.password": "leak", "app.region": "eu"}
+	weights := map[string]int{"app.token": 1, "app.password": 1, "app.region": 0}
+	invalidationsOutput := utils.InvalidationsOutput{invalidation("app.password", finding("password", "")), invalidation("app.token", finding("github-token", ""))}
+
+	sanitized, err := c.Adapt(item, invalidationsOutput, payload, weights)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if item.Status.HealingHaltedReason != "" {
+		t.Errorf("self-heal got halted although no key was to be deleted: %s", item.Status.HealingHaltedReason)
+	}
+	if want := map[string]string{"app.token": "***", "app.password": "***", "app.region": "eu"}; !reflect.DeepEqual(sanitized, want) {
+		t.Errorf("expected the configmap %v, got %v", want, sanitized)
+	}
+	if mutations := rec.recordedMutations(); len(mutations) != 0 {
+		t.Errorf("masking without writing back mutated Consul: %v", mutations)
+	}
+}
+
+func TestBlastRadiusViolation(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		spec         *sascomv1.BlastRadiusSpec
+		global       sascomv1.BlastRadiusSpec
+		keysToDelete int
+		totalKeys    int
+		violated     bool
+	}{
+		{name: "unbounded", keysToDelete: 10, totalKeys: 10},
+		{name: "within the keys", spec: &sascomv1.BlastRadiusSpec{MaxKeysPerReconcile: 2}, keysToDelete: 2, totalKeys: 10},
+		{name: "beyond the keys", spec: &sascomv1.BlastRadiusSpec{MaxKeysPerReconcile: 2}, keysToDelete: 3, totalKeys: 10, violated: true},
+		{name: "beyond the percent", spec: &sascomv1.BlastRadiusSpec{MaxPercentPerReconcile: 20}, keysToDelete: 3, totalKeys: 10, violated: true},
+		{name: "global limit is tighter", spec: &sascomv1.BlastRadiusSpec{MaxKeysPerReconcile: 5}, global: sascomv1.BlastRadiusSpec{MaxKeysPerReconcile: 2}, keysToDelete: 3, totalKeys: 10, violated: true},
+		{name: "global limit applies without a spec", global: sascomv1.BlastRadiusSpec{MaxPercentPerReconcile: 20}, keysToDelete: 3, totalKeys: 10, violated: true},
+		{name: "within the hour", spec: &sascomv1.BlastRadiusSpec{MaxKeysPerHour: 3}, keysToDelete: 3, totalKeys: 10},
+		{name: "beyond the hour", spec: &sascomv1.BlastRadiusSpec{MaxKeysPerHour: 3}, keysToDelete: 4, totalKeys: 10, violated: true},
+		{name: "global hourly limit", global: sascomv1.BlastRadiusSpec{MaxKeysPerHour: 3}, keysToDelete: 4, totalKeys: 10, violated: true},
+	} {
+		c, _, _, server := newTestClient(t)
+		c.globalBlastRadius = tc.global
+		item := testConsulKV(server.URL)
+		item.Spec.BlastRadius = tc.spec
+		if violated := c.blastRadiusViolation(item, tc.keysToDelete, tc.totalKeys) != ""; violated != tc.violated {
+			t.Errorf("%s: expected a violation = %v, got %v", tc.name, tc.violated, violated)
+		}
+	}
+}
@@ -20,9 +20,10 @@ type Client struct {
 	s3Session                    *session.Session
 	sheetLink                    string
 	invalidationsTrackingContext *knowledgebase.KnowledgeBaseContext
+	globalBlastRadius            sascomv1.BlastRadiusSpec
 }
 
-func NewClient(k8sClient client.Client, pdClient *pagerduty.Client, pdSender string, invalidationsTrackingContext *knowledgebase.KnowledgeBaseContext, sheetLink string, awsConfig *aws.Config) (Client, error) {
+func NewClient(k8sClient client.Client, pdClient *pagerduty.Client, pdSender string, invalidationsTrackingContext *knowledgebase.KnowledgeBaseContext, sheetLink string, awsConfig *aws.Config, globalBlastRadius sascomv1.BlastRadiusSpec) (Client, error) {
 	s3Session, err := session.NewSession(awsConfig)
 	if err != nil {
 		return Client{}, fmt.Errorf("error occurred while setting up the S3 session for the secret engine client: %w", err)
@@ -34,15 +35,25 @@ func NewClient(k8sClient client.Client, pdClient *pagerduty.Client, pdSender str
 		s3Session,
 		sheetLink,
 		invalidationsTrackingContext,
+		globalBlastRadius,
 	}, nil
 }
 
+// Adapt remediates the invalidations according to the adaptation mode decided for them, advisory findings are only reported
 func (c Client) Adapt(item *sascomv1.ConsulKV, invalidationsOutput utils.InvalidationsOutput, configMapPayloadUntilNow map[string]string, pathToWeights map[string]int) (map[string]string, error) {
+	invalidationsOutput, advisory := splitAdvisoryFindings(invalidationsOutput)
+	c.reportAdvisoryFindings(item, advisory)
+
 	utilityValue, adaptationMode, raisePager := c.utilityFunction(invalidationsOutput, pathToWeights)
 
 	item.Status.UtilityFunctionValue = fmt.Sprintf("%v", utilityValue)
 	item.Status.AdaptationMode = adaptationMode
 
+	if item.Spec.MatchAdaptations != nil {
+		// every adaptation keeps track of its own share of the invalidations, so they get deduplicated one partition at a time
+		return c.adaptByMatch(item, invalidationsOutput, configMapPayloadUntilNow, adaptationMode, raisePager)
+	}
+
 	if canIgnorePagingInvalidationsOutput(c.invalidationsTrackingContext, client.ObjectKeyFromObject(item).String(), invalidationsOutput, string(adaptationMode)) {
 		raisePager = false
 	}
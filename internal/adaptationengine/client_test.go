@@ -0,0 +1,165 @@
+package adaptationengine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/aws/aws-sdk-go/aws"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/knowledgebase"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+// recorder is a fake of both Consul and PagerDuty, it records the mutations of the keys and the incidents raised
+type recorder struct {
+	lock      sync.Mutex
+	mutations []string
+	incidents []string
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if req.URL.Path == "/incidents" {
+		var body struct {
+			Incident pagerduty.CreateIncidentOptions `json:"incident"`
+		}
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		r.incidents = append(r.incidents, body.Incident.Body.Details)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"incident": {}}`))
+		return
+	}
+	r.mutations = append(r.mutations, req.Method+" "+strings.TrimPrefix(req.URL.Path, "/v1/kv/"))
+	_, _ = w.Write([]byte("true"))
+}
+
+func (r *recorder) recordedMutations() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	output := append([]string{}, r.mutations...)
+	sort.Strings(output)
+	return output
+}
+
+func (r *recorder) recordedIncidents() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string{}, r.incidents...)
+}
+
+func newTestClient(t *testing.T, objects ...client.Object) (Client, client.Client, *recorder, *httptest.Server) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register the core types: %v", err)
+	}
+	if err := sascomv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register the sas.com types: %v", err)
+	}
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objects...).
+		WithStatusSubresource(&sascomv1.ConsulKV{}, &sascomv1.SensitiveFinding{}, &sascomv1.AdaptationRequest{}).
+		Build()
+
+	rec := &recorder{}
+	server := httptest.NewServer(rec)
+	t.Cleanup(server.Close)
+	c, err := NewClient(k8sClient, pagerduty.NewClient("token", pagerduty.WithAPIEndpoint(server.URL)), "sender", knowledgebase.New(context.Background()), "", &aws.Config{Region: aws.String("us-east-1")}, sascomv1.BlastRadiusSpec{})
+	if err != nil {
+		t.Fatalf("failed to setup the adaptation engine client: %v", err)
+	}
+	return c, k8sClient, rec, server
+}
+
+func testConsulKV(consulUrl string) *sascomv1.ConsulKV {
+	return &sascomv1.ConsulKV{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", UID: "app-uid", Generation: 1},
+		Spec: sascomv1.ConsulKVSpec{
+			ConsulUrl: consulUrl,
+			Paths:     []sascomv1.PathSpec{{Path: "app/"}},
+			QoS:       sascomv1.Critical,
+		},
+	}
+}
+
+func finding(ruleID string, category utils.FindingCategory) utils.Finding {
+	return utils.Finding{RuleID: ruleID, Confidence: 1, Severity: utils.HighSeverity, Category: category, Span: utils.Span{Start: 0, End: 4}}
+}
+
+func invalidation(path string, findings ...utils.Finding) utils.Invalidation {
+	return utils.NewInvalidation(path, utils.RedactedValue{Fingerprint: "hmac-sha256:" + path, Preview: "****"}, findings)
+}
+
+// TestAdaptLeavesAdvisoryFindingsInPlace guards that findings which leak nothing, like an expiring certificate,
+// are reported but never deleted from Consul nor kept out of the configmap, even when self-heal kicks in.
+func TestAdaptLeavesAdvisoryFindingsInPlace(t *testing.T) {
+	c, _, rec, server := newTestClient(t)
+	item := testConsulKV(server.URL)
+	payload := map[string]string{"app.cert": "cert", "app.token": "token"}
+	invalidationsOutput := utils.InvalidationsOutput{
+		invalidation("app.cert", finding("certificate-expiring", utils.CertificateCategory)),
+		invalidation("app.token", finding("github-token", "")),
+	}
+
+	sanitized, err := c.Adapt(item, invalidationsOutput, payload, map[string]int{"app.cert": 0, "app.token": 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if item.Status.AdaptationMode != sascomv1.SelfHealing {
+		t.Fatalf("expected self-heal, got %s", item.Status.AdaptationMode)
+	}
+	if mutations := rec.recordedMutations(); len(mutations) != 1 || mutations[0] != "DELETE app/token" {
+		t.Errorf("expected only app/token to be deleted, got %v", mutations)
+	}
+	if sanitized["app.cert"] != "cert" {
+		t.Errorf("the certificate got dropped from the configmap: %v", sanitized)
+	}
+	if _, found := sanitized["app.token"]; found {
+		t.Errorf("the token was kept in the configmap: %v", sanitized)
+	}
+	reported := false
+	for _, incident := range rec.recordedIncidents() {
+		if strings.Contains(incident, "needing attention") && strings.Contains(incident, "app.cert") {
+			reported = true
+		}
+	}
+	if !reported {
+		t.Errorf("the certificate finding was not reported: %v", rec.recordedIncidents())
+	}
+}
+
+func TestSplitAdvisoryFindings(t *testing.T) {
+	mixed := invalidation("app.bundle", finding("pem-private-key", ""), finding("certificate-expired", utils.CertificateCategory))
+	toRemediate, advisory := splitAdvisoryFindings(utils.InvalidationsOutput{
+		mixed,
+		invalidation("app.cert", finding("certificate-weak-key", utils.CertificateCategory)),
+		invalidation("app.replicas", finding("rego/replicas-pinned", utils.PolicyCategory)),
+		invalidation("app.region", finding("rego/region-deprecated", utils.PolicyAdvisoryCategory)),
+	})
+	// policy findings are remediated like any other unless their policy marked them as advisory
+	if len(toRemediate) != 2 || toRemediate[0].Path != "app.bundle" || toRemediate[0].RuleID != "pem-private-key" || toRemediate[0].Category != "" || toRemediate[1].Path != "app.replicas" {
+		t.Errorf("unexpected invalidations to remediate: %+v", toRemediate)
+	}
+	if len(advisory) != 3 || advisory[0].RuleID != "certificate-expired" || advisory[1].Path != "app.cert" || advisory[2].Path != "app.region" {
+		t.Errorf("unexpected advisory invalidations: %+v", advisory)
+	}
+	for _, inv := range advisory {
+		if !advisoryCategories[inv.Category] {
+			t.Errorf("advisory invalidation at %s lost its category: %q", inv.Path, inv.Category)
+		}
+	}
+}
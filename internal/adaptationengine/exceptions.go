@@ -0,0 +1,20 @@
+package adaptationengine
+
+import (
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NotifyExpiredExceptions lets the owners of the exceptions which just expired know that the detection is back on the keys they covered
+func (c Client) NotifyExpiredExceptions(item *sascomv1.ConsulKV, expired []sascomv1.ExceptionSpec) {
+	pagerBody := fmt.Sprintf("Exceptions of a KV group (%s) expired, the keys they covered are scanned again"+
+		"\nDetails:", client.ObjectKeyFromObject(item).String())
+	for _, exception := range expired {
+		pagerBody += fmt.Sprintf("\n%s owned by %s: %s", exception.ID(), exception.Owner, exception.Justification)
+	}
+	// an expiry is expected, the findings it may bring back raise their own pagers
+	if err := c.RaisePager(LowUrgencyLevel, pagerBody); err != nil {
+		fmt.Printf("%s\n", err.Error())
+	}
+}
@@ -0,0 +1,54 @@
+package adaptationengine
+
+import (
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+// blankedValues returns the flagged values which can be kept with only their offending fields blanked out, keyed by their path
+func blankedValues(item *sascomv1.ConsulKV, invalidationsOutput utils.InvalidationsOutput, configMapPayloadUntilNow map[string]string) map[string]string {
+	output := map[string]string{}
+	if !item.Spec.StructuredValues.BlanksOffendingFields() {
+		return output
+	}
+	for _, inv := range invalidationsOutput {
+		pointers, ok := inv.FieldPointers()
+		if !ok {
+			continue
+		}
+		blanked, err := utils.BlankStructuredFields(configMapPayloadUntilNow[inv.Path], pointers)
+		if err != nil {
+			// the whole key gets remediated instead
+			fmt.Printf("failed to blank out the offending fields of the key at the path %s: %s\n", inv.Path, err.Error())
+			continue
+		}
+		output[inv.Path] = blanked
+	}
+	return output
+}
+
+// rewrittenValues returns the flagged values which are kept in place rather than dropped, either masked or with their offending fields blanked out, keyed by their path
+func rewrittenValues(item *sascomv1.ConsulKV, invalidationsOutput utils.InvalidationsOutput, configMapPayloadUntilNow map[string]string) map[string]string {
+	if item.Spec.Masking != nil {
+		return maskedValues(item, invalidationsOutput, configMapPayloadUntilNow)
+	}
+	return blankedValues(item, invalidationsOutput, configMapPayloadUntilNow)
+}
+
+// writesBackToConsul tells whether self-heal puts the rewritten values back in Consul rather than deleting their keys
+func writesBackToConsul(item *sascomv1.ConsulKV) bool {
+	if item.Spec.Masking != nil {
+		return item.Spec.Masking.WriteBackToConsul
+	}
+	return true
+}
+
+// sanitizePayload gets rid of the flagged keys from the payload, except for the values which are kept in place once rewritten
+func sanitizePayload(item *sascomv1.ConsulKV, invalidationsOutput utils.InvalidationsOutput, configMapPayloadUntilNow map[string]string) map[string]string {
+	sanitizedConfigMapPayload := utils.RemoveKeysFromMap(configMapPayloadUntilNow, invalidationsOutput.Paths())
+	for path, rewritten := range rewrittenValues(item, invalidationsOutput, configMapPayloadUntilNow) {
+		sanitizedConfigMapPayload[path] = rewritten
+	}
+	return sanitizedConfigMapPayload
+}
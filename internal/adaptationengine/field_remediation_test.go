@@ -0,0 +1,27 @@
+package adaptationengine
+
+import (
+	"reflect"
+	"testing"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+// TestSanitizePayloadBlanksOffendingFields guards that only the offending fields get blanked out,
+// a finding on a field the document doesn't hold getting the whole key remediated instead.
+func TestSanitizePayloadBlanksOffendingFields(t *testing.T) {
+	item := &sascomv1.ConsulKV{Spec: sascomv1.ConsulKVSpec{StructuredValues: &sascomv1.StructuredValuesSpec{BlankOffendingFields: true}}}
+	onField := func(pointer string) utils.Finding {
+		found := finding("password", "")
+		found.Pointer = pointer
+		return found
+	}
+	payload := map[string]string{"app.db": `{"password":"hunter2","user":"jane"}`, "app.cache": `{"user":"jane"}`, "app.region": "eu"}
+	invalidationsOutput := utils.InvalidationsOutput{invalidation("app.cache", onField("/password")), invalidation("app.db", onField("/password"))}
+
+	want := map[string]string{"app.db": `{"password":"","user":"jane"}`, "app.region": "eu"}
+	if sanitized := sanitizePayload(item, invalidationsOutput, payload); !reflect.DeepEqual(sanitized, want) {
+		t.Errorf("expected the configmap %v, got %v", want, sanitized)
+	}
+}
@@ -0,0 +1,43 @@
+package adaptationengine
+
+import (
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+const (
+	defaultMaskingPlaceholder = "********"
+	defaultMaskingKeepLast    = 4
+)
+
+func masker(spec *sascomv1.MaskingSpec) utils.Masker {
+	if spec.Strategy == sascomv1.FormatPreservingMasking {
+		keepLast := defaultMaskingKeepLast
+		if spec.KeepLast != nil {
+			keepLast = *spec.KeepLast
+		}
+		return utils.FormatPreservingMasker(keepLast)
+	}
+	placeholder := defaultMaskingPlaceholder
+	if spec.Placeholder != "" {
+		placeholder = spec.Placeholder
+	}
+	return utils.PlaceholderMasker(placeholder)
+}
+
+// maskedValues returns every flagged value with its matched spans masked, keyed by their path
+func maskedValues(item *sascomv1.ConsulKV, invalidationsOutput utils.InvalidationsOutput, configMapPayloadUntilNow map[string]string) map[string]string {
+	output := map[string]string{}
+	mask := masker(item.Spec.Masking)
+	for _, inv := range invalidationsOutput {
+		masked, err := utils.MaskFindings(configMapPayloadUntilNow[inv.Path], inv.Findings, mask)
+		if err != nil {
+			// the whole key gets remediated instead
+			fmt.Printf("failed to mask the key at the path %s: %s\n", inv.Path, err.Error())
+			continue
+		}
+		output[inv.Path] = masked
+	}
+	return output
+}
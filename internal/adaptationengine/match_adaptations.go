@@ -0,0 +1,71 @@
+package adaptationengine
+
+import (
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// adaptationForMatch returns the adaptation pinned for the kind of match of an invalidation, if any
+func adaptationForMatch(spec *sascomv1.MatchAdaptationsSpec, match utils.MatchKind) sascomv1.AdaptationMode {
+	if spec == nil {
+		return ""
+	}
+	switch match {
+	case utils.KeyOnlyMatch:
+		return spec.KeyOnly
+	case utils.ValueOnlyMatch:
+		return spec.ValueOnly
+	case utils.KeyAndValueMatch:
+		return spec.Both
+	}
+	return ""
+}
+
+// partitionByAdaptation groups the invalidations by the adaptation they get, the one pinned for their kind of match or else the one decided by the utility function
+func partitionByAdaptation(spec *sascomv1.MatchAdaptationsSpec, invalidationsOutput utils.InvalidationsOutput, decidedMode sascomv1.AdaptationMode) map[sascomv1.AdaptationMode]utils.InvalidationsOutput {
+	output := map[sascomv1.AdaptationMode]utils.InvalidationsOutput{}
+	for _, inv := range invalidationsOutput {
+		mode := adaptationForMatch(spec, inv.Match)
+		if mode == "" {
+			mode = decidedMode
+		}
+		output[mode] = append(output[mode], inv)
+	}
+	return output
+}
+
+// adaptByMatch runs self-heal and self-protect over their own share of the invalidations, the status reports the most drastic adaptation which went through.
+// A share only pages when it differs from the one its adaptation went through last time.
+func (c Client) adaptByMatch(item *sascomv1.ConsulKV, invalidationsOutput utils.InvalidationsOutput, configMapPayloadUntilNow map[string]string, decidedMode sascomv1.AdaptationMode, raisePager bool) (map[string]string, error) {
+	partitions := partitionByAdaptation(item.Spec.MatchAdaptations, invalidationsOutput, decidedMode)
+	// decided upfront, as self-heal keeps track of its share as self-protect's whenever it falls back to it
+	raisePagerFor := map[sascomv1.AdaptationMode]bool{}
+	for _, mode := range []sascomv1.AdaptationMode{sascomv1.SelfHealing, sascomv1.SelfProtecting} {
+		raisePagerFor[mode] = raisePager && !canIgnorePagingInvalidationsOutput(c.invalidationsTrackingContext, client.ObjectKeyFromObject(item).String(), partitions[mode], string(mode))
+	}
+
+	var err error
+	sanitizedConfigMapPayload := configMapPayloadUntilNow
+	appliedMode := sascomv1.NonAdaptive
+	if toHeal := partitions[sascomv1.SelfHealing]; len(toHeal) != 0 {
+		item.Status.AdaptationMode = sascomv1.SelfHealing
+		sanitizedConfigMapPayload, err = c.selfHeal(item, toHeal, sanitizedConfigMapPayload, raisePagerFor[sascomv1.SelfHealing])
+		if err != nil {
+			return nil, err
+		}
+		// self-heal falls back to self-protect on its own when it isn't allowed to delete anything
+		appliedMode = item.Status.AdaptationMode
+	}
+	if toProtect := partitions[sascomv1.SelfProtecting]; len(toProtect) != 0 {
+		sanitizedConfigMapPayload, err = c.selfProtect(item, toProtect, sanitizedConfigMapPayload, raisePagerFor[sascomv1.SelfProtecting])
+		if err != nil {
+			return nil, err
+		}
+		if appliedMode == sascomv1.NonAdaptive {
+			appliedMode = sascomv1.SelfProtecting
+		}
+	}
+	item.Status.AdaptationMode = appliedMode
+	return sanitizedConfigMapPayload, nil
+}
@@ -0,0 +1,52 @@
+package adaptationengine
+
+import (
+	"strings"
+	"testing"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+// TestAdaptByMatchDeduplicatesEveryPartition guards that a reconcile finding the same invalidations again doesn't page about them again,
+// each adaptation comparing its own share of them against the share it got last time.
+func TestAdaptByMatchDeduplicatesEveryPartition(t *testing.T) {
+	c, _, rec, server := newTestClient(t)
+	item := testConsulKV(server.URL)
+	item.Spec.MatchAdaptations = &sascomv1.MatchAdaptationsSpec{KeyOnly: sascomv1.SelfProtecting}
+	keyFinding := finding("password-key", "")
+	keyFinding.OnKey = true
+	invalidationsOutput := utils.InvalidationsOutput{
+		invalidation("app.password", keyFinding),
+		invalidation("app.token", finding("github-token", "")),
+	}
+	payload := map[string]string{"app.password": "hunter2", "app.token": "token"}
+
+	pagedAbout := func(path string) int {
+		count := 0
+		for _, incident := range rec.recordedIncidents() {
+			if strings.Contains(incident, path) {
+				count++
+			}
+		}
+		return count
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		sanitized, err := c.Adapt(item, invalidationsOutput, payload, map[string]int{"app.password": 1, "app.token": 1})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if item.Status.AdaptationMode != sascomv1.SelfHealing {
+			t.Fatalf("expected self-heal to be the most drastic adaptation, got %s", item.Status.AdaptationMode)
+		}
+		if len(sanitized) != 0 {
+			t.Errorf("expected both keys out of the configmap, got %v", sanitized)
+		}
+	}
+	if paged := pagedAbout("app.password"); paged != 1 {
+		t.Errorf("expected a single page about the protected key, got %d", paged)
+	}
+	if mutations := rec.recordedMutations(); len(mutations) != 2 || mutations[0] != "DELETE app/token" {
+		t.Errorf("expected only app/token to be deleted, got %v", mutations)
+	}
+}
@@ -0,0 +1,46 @@
+package adaptationengine
+
+import (
+	"context"
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/policyreport"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReportPolicyResults publishes the results of the last scan of the ConsulKV in its own PolicyReport, the PolicyReported condition telling whether that worked.
+// A failure to do so never gets in the way of the adaptation, and clusters without the PolicyReport CRD are silently skipped.
+func (c Client) ReportPolicyResults(item *sascomv1.ConsulKV, results []policyreport.Result) {
+	scope := corev1.ObjectReference{
+		APIVersion: sascomv1.GroupVersion.String(),
+		Kind:       "ConsulKV",
+		Namespace:  item.Namespace,
+		Name:       item.Name,
+		UID:        item.UID,
+	}
+	err := policyreport.Write(context.Background(), c.k8sClient, scope, results)
+	switch {
+	case meta.IsNoMatchError(err):
+		meta.RemoveStatusCondition(&item.Status.Conditions, sascomv1.PolicyReportedCondition)
+	case err != nil:
+		fmt.Printf("error occurred while reporting the policy results of %s: %v\n", client.ObjectKeyFromObject(item).String(), err)
+		meta.SetStatusCondition(&item.Status.Conditions, metav1.Condition{
+			Type:               sascomv1.PolicyReportedCondition,
+			Status:             metav1.ConditionFalse,
+			Reason:             "WriteFailed",
+			Message:            err.Error(),
+			ObservedGeneration: item.Generation,
+		})
+	default:
+		meta.SetStatusCondition(&item.Status.Conditions, metav1.Condition{
+			Type:               sascomv1.PolicyReportedCondition,
+			Status:             metav1.ConditionTrue,
+			Reason:             "Reported",
+			Message:            fmt.Sprintf("%d result(s) reported", len(results)),
+			ObservedGeneration: item.Generation,
+		})
+	}
+}
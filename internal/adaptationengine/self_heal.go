@@ -9,15 +9,76 @@ import (
 )
 
 func (c Client) selfHeal(item *sascomv1.ConsulKV, invalidationsOutput utils.InvalidationsOutput, configMapPayloadUntilNow map[string]string, raisePager bool) (map[string]string, error) {
+	var adaptationRequest *sascomv1.AdaptationRequest
+	if requiresApproval(item) {
+		liveRequest, approved, err := c.approvedAdaptationRequest(item, invalidationsOutput)
+		if err != nil {
+			fmt.Printf("failed to reconcile the adaptation request for %s: %s\n", client.ObjectKeyFromObject(item).String(), err.Error())
+		}
+		if liveRequest != nil && !approved {
+			item.Status.PendingAdaptationRequest = liveRequest.Name
+		}
+		if !approved {
+			// until a human signs off on the deletions, only shield the configmap from the flagged keys
+			item.Status.AdaptationMode = sascomv1.SelfProtecting
+			return c.selfProtect(item, invalidationsOutput, configMapPayloadUntilNow, raisePager)
+		}
+		adaptationRequest = liveRequest
+		item.Status.PendingAdaptationRequest = ""
+	}
+
+	rewritten := rewrittenValues(item, invalidationsOutput, configMapPayloadUntilNow)
+	// the keys kept in place once rewritten, masked or with their offending fields blanked out, aren't deleted
+	keysToDelete := 0
+	for _, inv := range invalidationsOutput {
+		if _, found := rewritten[inv.Path]; !found {
+			keysToDelete++
+		}
+	}
+	if c.haltHealingIfBlastRadiusExceeded(item, keysToDelete, len(configMapPayloadUntilNow)) {
+		item.Status.AdaptationMode = sascomv1.SelfProtecting
+		return c.selfProtect(item, invalidationsOutput, configMapPayloadUntilNow, raisePager)
+	}
+
 	consulKvClient := utils.NewConsulKV(item.Spec.ConsulUrl)
 
 	failedDeletions := utils.InvalidationsOutput{}
+	// mutations feed the blast radius history, the keys left untouched in Consul don't count
+	mutations := 0
 	for _, inv := range invalidationsOutput {
 		inv := inv
 		slashedPath := strings.ReplaceAll(inv.Path, ".", "/")
+		if rewrittenValue, found := rewritten[inv.Path]; found {
+			if !writesBackToConsul(item) {
+				// only the configmap gets the masked value, the key is left untouched in Consul
+				continue
+			}
+			// write back what is left of the value instead of getting rid of the whole key
+			if err := consulKvClient.UpdatePath(slashedPath, rewrittenValue); err != nil {
+				fmt.Printf("failed to PUT the rewritten value at the path %s: %s\n", inv.Path, err.Error())
+				failedDeletions = append(failedDeletions, inv)
+				continue
+			}
+			mutations++
+			continue
+		}
 		if err := consulKvClient.DeletePath(slashedPath); err != nil {
 			fmt.Printf("failed to DELETE the key at the path %s: %s\n", inv.Path, err.Error())
 			failedDeletions = append(failedDeletions, inv)
+			continue
+		}
+		mutations++
+	}
+
+	c.invalidationsTrackingContext.RecordDeletions(client.ObjectKeyFromObject(item).String(), mutations)
+
+	if adaptationRequest != nil {
+		message := "all the keys were deleted"
+		if len(failedDeletions) != 0 {
+			message = fmt.Sprintf("%d out of %d keys failed to get deleted", len(failedDeletions), len(invalidationsOutput))
+		}
+		if err := c.setAdaptationRequestPhase(adaptationRequest, sascomv1.AdaptationRequestExecuted, message); err != nil {
+			fmt.Printf("%s\n", err.Error())
 		}
 	}
 
@@ -69,6 +130,6 @@ func (c Client) selfHeal(item *sascomv1.ConsulKV, invalidationsOutput utils.Inva
 		_ = c.RaisePager(urgencyLevel, pagerBody)
 	}
 
-	sanitizedConfigMapPayload := utils.RemoveKeysFromMap(configMapPayloadUntilNow, invalidationsOutput.Paths())
+	sanitizedConfigMapPayload := sanitizePayload(item, invalidationsOutput, configMapPayloadUntilNow)
 	return sanitizedConfigMapPayload, nil
 }
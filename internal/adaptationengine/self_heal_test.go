@@ -0,0 +1,43 @@
+package adaptationengine
+
+import (
+	"reflect"
+	"testing"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+// TestSelfHealMasking guards that masking without writing back to Consul only ever touches the configmap,
+// the masked keys being neither deleted nor rewritten in Consul.
+func TestSelfHealMasking(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		masking   *sascomv1.MaskingSpec
+		mutations []string
+		sanitized map[string]string
+	}{
+		{name: "no masking", mutations: []string{"DELETE app/token"}, sanitized: map[string]string{"app.region": "eu"}},
+		{name: "configmap only", masking: &sascomv1.MaskingSpec{}, mutations: []string{}, sanitized: map[string]string{"app.region": "eu", "app.token": "********-suffix"}},
+		{name: "write back", masking: &sascomv1.MaskingSpec{WriteBackToConsul: true}, mutations: []string{"PUT app/token"}, sanitized: map[string]string{"app.region": "eu", "app.token": "********-suffix"}},
+	} {
+		c, _, rec, server := newTestClient(t)
+		item := testConsulKV(server.URL)
+		item.Spec.Masking = tc.masking
+		payload := map[string]string{"app.token": "leak-suffix", "app.region": "eu"}
+
+		sanitized, err := c.Adapt(item, utils.InvalidationsOutput{invalidation("app.token", finding("github-token", ""))}, payload, map[string]int{"app.token": 1, "app.region": 0})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if item.Status.AdaptationMode != sascomv1.SelfHealing {
+			t.Fatalf("%s: expected self-heal, got %s", tc.name, item.Status.AdaptationMode)
+		}
+		if mutations := rec.recordedMutations(); !reflect.DeepEqual(mutations, tc.mutations) {
+			t.Errorf("%s: expected the mutations %v, got %v", tc.name, tc.mutations, mutations)
+		}
+		if !reflect.DeepEqual(sanitized, tc.sanitized) {
+			t.Errorf("%s: expected the configmap %v, got %v", tc.name, tc.sanitized, sanitized)
+		}
+	}
+}
@@ -36,6 +36,6 @@ func (c Client) selfProtect(item *sascomv1.ConsulKV, invalidationsOutput utils.I
 		_ = c.RaisePager(urgencyLevel, pagerBody)
 	}
 
-	sanitizedConfigMapPayload := utils.RemoveKeysFromMap(configMapPayloadUntilNow, invalidationsOutput.Paths())
+	sanitizedConfigMapPayload := sanitizePayload(item, invalidationsOutput, configMapPayloadUntilNow)
 	return sanitizedConfigMapPayload, nil
 }
@@ -0,0 +1,243 @@
+package adaptationengine
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// resolvedSensitiveFindingTTL is how long a resolved SensitiveFinding is kept around before being deleted
+	resolvedSensitiveFindingTTL = 7 * 24 * time.Hour
+	// sensitiveFindingWriters bounds how many SensitiveFindings get written at once, the writes happening under the reconcile lock
+	sensitiveFindingWriters = 8
+	// maxSensitiveFindingPrefixLength leaves room for the hash suffix within the 253 characters a name may span
+	maxSensitiveFindingPrefixLength = 253 - len("-") - 16
+)
+
+// sensitiveFindingKey is what makes a SensitiveFinding unique within its ConsulKV
+type sensitiveFindingKey struct {
+	path        string
+	ruleID      string
+	fingerprint string
+}
+
+// TrackSensitiveFindings keeps a SensitiveFinding for every finding of the invalidations and returns the invalidations without the findings triaged as false positives.
+// The SensitiveFindings which the scan no longer finds get resolved, unless their path couldn't be fully evaluated, and get deleted once resolved for long enough.
+// Only the SensitiveFindings controlled by the ConsulKV are trusted, their labels and spec being writable by anyone allowed to create one.
+func (c Client) TrackSensitiveFindings(item *sascomv1.ConsulKV, invalidationsOutput utils.InvalidationsOutput, configMapPayload map[string]string, unevaluatedPaths []string) (utils.InvalidationsOutput, error) {
+	ctx := context.Background()
+	list := &sascomv1.SensitiveFindingList{}
+	if err := c.k8sClient.List(ctx, list, client.InNamespace(item.Namespace), client.MatchingLabels{
+		sascomv1.SensitiveFindingConsulKVLabel: item.Name,
+	}); err != nil {
+		return nil, fmt.Errorf("error occurred while listing the sensitive findings of %s: %w", client.ObjectKeyFromObject(item).String(), err)
+	}
+	existing := map[sensitiveFindingKey]*sascomv1.SensitiveFinding{}
+	for idx := range list.Items {
+		sensitiveFinding := &list.Items[idx]
+		// labels and spec are user-writable, so only trust the findings this very ConsulKV raised
+		if !metav1.IsControlledBy(sensitiveFinding, item) && !c.adoptSensitiveFinding(item, sensitiveFinding) {
+			continue
+		}
+		existing[sensitiveFindingKey{sensitiveFinding.Spec.Path, sensitiveFinding.Spec.RuleID, sensitiveFinding.Spec.Fingerprint}] = sensitiveFinding
+	}
+
+	found := map[sensitiveFindingKey]bool{}
+	writes := []func() error{}
+	output := utils.InvalidationsOutput{}
+	for _, inv := range invalidationsOutput {
+		findings := []utils.Finding{}
+		for _, finding := range inv.Findings {
+			key := sensitiveFindingKey{inv.Path, finding.RuleID, inv.Fingerprint}
+			if !found[key] {
+				found[key] = true
+				if write := c.syncSensitiveFinding(item, inv, finding, existing[key]); write != nil {
+					writes = append(writes, write)
+				}
+			}
+			if sensitiveFinding := existing[key]; sensitiveFinding != nil && sensitiveFinding.Triage() == sascomv1.SensitiveFindingFalsePositive {
+				continue
+			}
+			findings = append(findings, finding)
+		}
+		switch {
+		case len(findings) == len(inv.Findings):
+			output = append(output, inv)
+		case len(findings) != 0:
+			output = append(output, utils.NewInvalidation(inv.Path, inv.RedactedValue, findings))
+		}
+	}
+
+	for key, sensitiveFinding := range existing {
+		if found[key] || sensitiveFinding.Triage() == sascomv1.SensitiveFindingFalsePositive {
+			continue
+		}
+		if sensitiveFinding.Status.Phase == sascomv1.SensitiveFindingResolved {
+			sensitiveFinding := sensitiveFinding
+			writes = append(writes, func() error { return c.pruneSensitiveFinding(sensitiveFinding, time.Now()) })
+			continue
+		}
+		_, stillPresent := configMapPayload[key.path]
+		if stillPresent && utils.ValueInSlice(key.path, unevaluatedPaths) {
+			continue
+		}
+		message := "the last scan didn't find it anymore"
+		if !stillPresent {
+			message = "the key is gone"
+		}
+		sensitiveFinding := sensitiveFinding
+		writes = append(writes, func() error {
+			return c.setSensitiveFindingPhase(sensitiveFinding, sascomv1.SensitiveFindingResolved, message)
+		})
+	}
+	c.writeSensitiveFindings(writes)
+	return output, nil
+}
+
+// writeSensitiveFindings runs the writes over a bounded pool of workers, a failed write never gets in the way of the others
+func (c Client) writeSensitiveFindings(writes []func() error) {
+	jobs := make(chan func() error)
+	wg := &sync.WaitGroup{}
+	for worker := 0; worker < sensitiveFindingWriters && worker < len(writes); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for write := range jobs {
+				if err := write(); err != nil {
+					fmt.Printf("%s\n", err.Error())
+				}
+			}
+		}()
+	}
+	for _, write := range writes {
+		jobs <- write
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// syncSensitiveFinding returns the write creating the SensitiveFinding of a finding, or moving the existing one to the phase its triage calls for,
+// nil when there's nothing to write
+func (c Client) syncSensitiveFinding(item *sascomv1.ConsulKV, inv utils.Invalidation, finding utils.Finding, sensitiveFinding *sascomv1.SensitiveFinding) func() error {
+	if sensitiveFinding == nil {
+		return func() error { return c.raiseSensitiveFinding(item, inv, finding) }
+	}
+	phase, message := sascomv1.SensitiveFindingOpen, "found by the last scan"
+	switch triage := sensitiveFinding.Triage(); triage {
+	case sascomv1.SensitiveFindingFalsePositive:
+		phase, message = triage, "triaged as a false positive, no longer triggering any adaptation"
+	case sascomv1.SensitiveFindingAcknowledged:
+		phase, message = triage, "acknowledged"
+	}
+	if sensitiveFinding.Status.Phase == phase {
+		return nil
+	}
+	if sensitiveFinding.Status.Phase == sascomv1.SensitiveFindingResolved {
+		message = "found again after being resolved"
+	}
+	return func() error { return c.setSensitiveFindingPhase(sensitiveFinding, phase, message) }
+}
+
+func (c Client) raiseSensitiveFinding(item *sascomv1.ConsulKV, inv utils.Invalidation, finding utils.Finding) error {
+	ctx := context.Background()
+	sensitiveFinding := &sascomv1.SensitiveFinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sensitiveFindingName(item.Name, inv.Path, finding.RuleID, inv.Fingerprint),
+			Namespace: item.Namespace,
+			Labels: map[string]string{
+				sascomv1.SensitiveFindingConsulKVLabel: item.Name,
+			},
+		},
+		Spec: sascomv1.SensitiveFindingSpec{
+			ConsulKV:    item.Name,
+			Path:        inv.Path,
+			RuleID:      finding.RuleID,
+			Fingerprint: inv.Fingerprint,
+			Preview:     inv.Preview,
+			Severity:    string(finding.Severity),
+			Category:    string(finding.Category),
+		},
+	}
+	if err := controllerutil.SetControllerReference(item, sensitiveFinding, c.k8sClient.Scheme()); err != nil {
+		return fmt.Errorf("failed to setup the owner reference on the sensitive finding: %w", err)
+	}
+	if err := c.k8sClient.Create(ctx, sensitiveFinding); err != nil {
+		if errors.IsAlreadyExists(err) {
+			// either its labels got tampered with, or someone else took its name, it won't be trusted in both cases
+			return fmt.Errorf("the sensitive finding %s already exists without being listed as one of %s", client.ObjectKeyFromObject(sensitiveFinding).String(), client.ObjectKeyFromObject(item).String())
+		}
+		return fmt.Errorf("error occurred while creating the sensitive finding for %s: %w", client.ObjectKeyFromObject(item).String(), err)
+	}
+	return c.setSensitiveFindingPhase(sensitiveFinding, sascomv1.SensitiveFindingOpen, "found by the last scan")
+}
+
+func (c Client) setSensitiveFindingPhase(sensitiveFinding *sascomv1.SensitiveFinding, phase sascomv1.SensitiveFindingPhase, message string) error {
+	now := metav1.Now()
+	sensitiveFinding.Status.Phase = phase
+	sensitiveFinding.Status.Message = message
+	sensitiveFinding.Status.LastTransitionTime = &now
+	if err := c.k8sClient.Status().Update(context.Background(), sensitiveFinding); err != nil {
+		return fmt.Errorf("error occurred while moving the sensitive finding %s to the phase '%s': %w", client.ObjectKeyFromObject(sensitiveFinding).String(), phase, err)
+	}
+	return nil
+}
+
+// adoptSensitiveFinding makes the ConsulKV the controller of a SensitiveFinding it raised before being set as such, it only owned them back then
+func (c Client) adoptSensitiveFinding(item *sascomv1.ConsulKV, sensitiveFinding *sascomv1.SensitiveFinding) bool {
+	if metav1.GetControllerOf(sensitiveFinding) != nil {
+		return false
+	}
+	owned := false
+	for _, ownerReference := range sensitiveFinding.OwnerReferences {
+		if ownerReference.UID == item.UID {
+			owned = true
+		}
+	}
+	if !owned {
+		return false
+	}
+	if err := controllerutil.SetControllerReference(item, sensitiveFinding, c.k8sClient.Scheme()); err != nil {
+		fmt.Printf("failed to setup the controller reference on the sensitive finding: %v\n", err)
+		return false
+	}
+	if err := c.k8sClient.Update(context.Background(), sensitiveFinding); err != nil {
+		fmt.Printf("error occurred while adopting the sensitive finding %s: %v\n", client.ObjectKeyFromObject(sensitiveFinding).String(), err)
+		return false
+	}
+	return true
+}
+
+// pruneSensitiveFinding deletes a resolved SensitiveFinding once it stayed resolved for longer than its time to live
+func (c Client) pruneSensitiveFinding(sensitiveFinding *sascomv1.SensitiveFinding, now time.Time) error {
+	resolvedAt := sensitiveFinding.Status.LastTransitionTime
+	if resolvedAt == nil || now.Sub(resolvedAt.Time) < resolvedSensitiveFindingTTL {
+		return nil
+	}
+	if err := c.k8sClient.Delete(context.Background(), sensitiveFinding); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("error occurred while deleting the resolved sensitive finding %s: %w", client.ObjectKeyFromObject(sensitiveFinding).String(), err)
+	}
+	return nil
+}
+
+// sensitiveFindingName is stable for a given ConsulKV, path, rule and fingerprint, so that a finding never gets raised twice.
+// The name of the ConsulKV gets truncated when too long for the name to stay valid, the ConsulKV label still telling which one it belongs to.
+func sensitiveFindingName(consulKvName string, path string, ruleID string, fingerprint string) string {
+	sum := sha256.Sum256([]byte(path + "\n" + ruleID + "\n" + fingerprint))
+	prefix := consulKvName
+	if len(prefix) > maxSensitiveFindingPrefixLength {
+		// a segment of the name can neither end with a '.' nor start with a '-'
+		prefix = strings.TrimRight(prefix[:maxSensitiveFindingPrefixLength], ".-")
+	}
+	return prefix + "-" + hex.EncodeToString(sum[:])[:16]
+}
@@ -0,0 +1,185 @@
+package adaptationengine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+func listSensitiveFindings(t *testing.T, k8sClient client.Client) []sascomv1.SensitiveFinding {
+	list := &sascomv1.SensitiveFindingList{}
+	if err := k8sClient.List(context.Background(), list); err != nil {
+		t.Fatalf("failed to list the sensitive findings: %v", err)
+	}
+	return list.Items
+}
+
+// TestSensitiveFindingLifecycle guards that a finding is raised controlled by its ConsulKV, resolved once the scan no longer finds it,
+// reopened if it shows up again and deleted once it stayed resolved for long enough.
+func TestSensitiveFindingLifecycle(t *testing.T) {
+	c, k8sClient, _, server := newTestClient(t)
+	item := testConsulKV(server.URL)
+	inv := invalidation("app.token", finding("github-token", ""))
+	payload := map[string]string{"app.token": "token"}
+
+	if _, err := c.TrackSensitiveFindings(item, utils.InvalidationsOutput{inv}, payload, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	raised := listSensitiveFindings(t, k8sClient)
+	if len(raised) != 1 || raised[0].Status.Phase != sascomv1.SensitiveFindingOpen || !metav1.IsControlledBy(&raised[0], item) {
+		t.Fatalf("expected an open finding controlled by the ConsulKV, got %+v", raised)
+	}
+
+	// a path which couldn't be evaluated keeps its findings open
+	if _, err := c.TrackSensitiveFindings(item, nil, payload, []string{"app.token"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if phase := listSensitiveFindings(t, k8sClient)[0].Status.Phase; phase != sascomv1.SensitiveFindingOpen {
+		t.Errorf("the finding of an unevaluated path moved to %s", phase)
+	}
+
+	if _, err := c.TrackSensitiveFindings(item, nil, payload, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if phase := listSensitiveFindings(t, k8sClient)[0].Status.Phase; phase != sascomv1.SensitiveFindingResolved {
+		t.Errorf("expected the finding to be resolved, got %s", phase)
+	}
+
+	if _, err := c.TrackSensitiveFindings(item, utils.InvalidationsOutput{inv}, payload, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reopened := listSensitiveFindings(t, k8sClient)
+	if reopened[0].Status.Phase != sascomv1.SensitiveFindingOpen || reopened[0].Status.Message != "found again after being resolved" {
+		t.Errorf("expected the finding to reopen, got %+v", reopened[0].Status)
+	}
+
+	if _, err := c.TrackSensitiveFindings(item, nil, map[string]string{}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resolved := listSensitiveFindings(t, k8sClient)[0]
+	if err := c.pruneSensitiveFinding(&resolved, time.Now()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(listSensitiveFindings(t, k8sClient)) != 1 {
+		t.Errorf("a freshly resolved finding got deleted")
+	}
+	resolvedLongAgo := metav1.NewTime(time.Now().Add(-resolvedSensitiveFindingTTL - time.Minute))
+	resolved.Status.LastTransitionTime = &resolvedLongAgo
+	if err := k8sClient.Status().Update(context.Background(), &resolved); err != nil {
+		t.Fatalf("failed to age the finding: %v", err)
+	}
+	if _, err := c.TrackSensitiveFindings(item, nil, map[string]string{}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if remaining := listSensitiveFindings(t, k8sClient); len(remaining) != 0 {
+		t.Errorf("the finding outlived its time to live once resolved: %+v", remaining)
+	}
+}
+
+// TestSensitiveFindingsOnlyTrustTheirController guards that a SensitiveFinding carrying the label and spec of a ConsulKV,
+// without being controlled by it, can't silence its findings as false positives.
+func TestSensitiveFindingsOnlyTrustTheirController(t *testing.T) {
+	inv := invalidation("app.token", finding("github-token", ""))
+	triagedAs := func(name string, ownerReferences ...metav1.OwnerReference) *sascomv1.SensitiveFinding {
+		return &sascomv1.SensitiveFinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       "default",
+				Labels:          map[string]string{sascomv1.SensitiveFindingConsulKVLabel: "app"},
+				Annotations:     map[string]string{sascomv1.SensitiveFindingTriageAnnotation: string(sascomv1.SensitiveFindingFalsePositive)},
+				OwnerReferences: ownerReferences,
+			},
+			Spec: sascomv1.SensitiveFindingSpec{ConsulKV: "app", Path: inv.Path, RuleID: "github-token", Fingerprint: inv.Fingerprint},
+		}
+	}
+	ownerOf := func(uid string, controller bool) metav1.OwnerReference {
+		return metav1.OwnerReference{APIVersion: sascomv1.GroupVersion.String(), Kind: "ConsulKV", Name: "app", UID: types.UID(uid), Controller: pointer.Bool(controller)}
+	}
+	for _, tc := range []struct {
+		name      string
+		forged    *sascomv1.SensitiveFinding
+		silenced  bool
+		wantOwner bool
+	}{
+		{name: "no owner", forged: triagedAs("forged")},
+		{name: "other controller", forged: triagedAs("forged", metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "other", UID: "other-uid", Controller: pointer.Bool(true)}, ownerOf("app-uid", false))},
+		{name: "predecessor", forged: triagedAs("forged", ownerOf("predecessor-uid", true))},
+		{name: "controller", forged: triagedAs(sensitiveFindingName("app", inv.Path, "github-token", inv.Fingerprint), ownerOf("app-uid", true)), silenced: true},
+		// raised before the findings got controlled by their ConsulKV, they only carried an owner reference
+		{name: "legacy owner", forged: triagedAs(sensitiveFindingName("app", inv.Path, "github-token", inv.Fingerprint), ownerOf("app-uid", false)), silenced: true, wantOwner: true},
+	} {
+		c, k8sClient, _, server := newTestClient(t, tc.forged)
+		item := testConsulKV(server.URL)
+		output, err := c.TrackSensitiveFindings(item, utils.InvalidationsOutput{inv}, map[string]string{"app.token": "token"}, nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if silenced := len(output) == 0; silenced != tc.silenced {
+			t.Errorf("%s: expected the finding to be silenced = %v, got %v", tc.name, tc.silenced, silenced)
+		}
+		if !tc.wantOwner {
+			continue
+		}
+		adopted := &sascomv1.SensitiveFinding{}
+		if err := k8sClient.Get(context.Background(), client.ObjectKeyFromObject(tc.forged), adopted); err != nil {
+			t.Fatalf("%s: failed to get the finding: %v", tc.name, err)
+		}
+		if !metav1.IsControlledBy(adopted, item) {
+			t.Errorf("%s: the finding didn't get adopted: %+v", tc.name, adopted.OwnerReferences)
+		}
+	}
+}
+
+// TestSensitiveFindingsAreWrittenConcurrently guards that every finding of a large scan gets raised and opened,
+// however many of them the pool of writers handles at once.
+func TestSensitiveFindingsAreWrittenConcurrently(t *testing.T) {
+	c, k8sClient, _, server := newTestClient(t)
+	item := testConsulKV(server.URL)
+	invalidationsOutput := utils.InvalidationsOutput{}
+	payload := map[string]string{}
+	for idx := 0; idx < 5*sensitiveFindingWriters; idx++ {
+		path := fmt.Sprintf("app.token-%02d", idx)
+		invalidationsOutput = append(invalidationsOutput, invalidation(path, finding("github-token", ""), finding("password", "")))
+		payload[path] = "token"
+	}
+
+	if _, err := c.TrackSensitiveFindings(item, invalidationsOutput, payload, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	raised := listSensitiveFindings(t, k8sClient)
+	if len(raised) != 2*len(invalidationsOutput) {
+		t.Fatalf("expected %d findings, got %d", 2*len(invalidationsOutput), len(raised))
+	}
+	for _, sensitiveFinding := range raised {
+		if sensitiveFinding.Status.Phase != sascomv1.SensitiveFindingOpen {
+			t.Errorf("the finding %s is %q instead of open", sensitiveFinding.Name, sensitiveFinding.Status.Phase)
+		}
+	}
+}
+
+func TestSensitiveFindingName(t *testing.T) {
+	name := sensitiveFindingName("app", "app.token", "github-token", "hmac-sha256:abc")
+	if !strings.HasPrefix(name, "app-") || len(name) != len("app-")+16 {
+		t.Errorf("unexpected name %s", name)
+	}
+	if name != sensitiveFindingName("app", "app.token", "github-token", "hmac-sha256:abc") {
+		t.Errorf("the name isn't stable")
+	}
+
+	for _, consulKvName := range []string{strings.Repeat("a", 253), strings.Repeat("a", 235) + ".b" + strings.Repeat("c", 16)} {
+		name := sensitiveFindingName(consulKvName, "app.token", "github-token", "hmac-sha256:abc")
+		if len(name) > 253 || strings.Contains(name, ".-") {
+			t.Errorf("invalid name %s of %d characters", name, len(name))
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package adaptationengine
+
+import (
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// validationTrackingMode keys the validation failures in the knowledge base, apart from the invalidations of any adaptation mode
+const validationTrackingMode = "validation"
+
+// AdaptValidationFailures applies the policy of their path to the values failing their validation and notifies about them.
+// Such values leak nothing, so they are never deleted from Consul whatever the adaptation mode.
+func (c Client) AdaptValidationFailures(item *sascomv1.ConsulKV, failures utils.InvalidationsOutput, pathToPolicy map[string]sascomv1.ValueValidationPolicy, configMapPayload map[string]string, previousConfigMapPayload map[string]string) map[string]string {
+	consulKvKey := client.ObjectKeyFromObject(item).String()
+	raisePager := !canIgnorePagingInvalidationsOutput(c.invalidationsTrackingContext, consulKvKey, failures, validationTrackingMode)
+	defer func() {
+		c.invalidationsTrackingContext.SetInvalidationsOutput(consulKvKey, failures, validationTrackingMode)
+	}()
+
+	sanitizedConfigMapPayload := utils.DeepCopyMap(configMapPayload)
+	for _, inv := range failures {
+		switch pathToPolicy[inv.Path] {
+		case sascomv1.ReportInvalidValue:
+		case sascomv1.DropInvalidValue:
+			delete(sanitizedConfigMapPayload, inv.Path)
+		default: // including reject
+			if previousValue, found := previousConfigMapPayload[inv.Path]; found {
+				sanitizedConfigMapPayload[inv.Path] = previousValue
+			} else {
+				delete(sanitizedConfigMapPayload, inv.Path)
+			}
+		}
+	}
+
+	var urgencyLevel UrgencyLevel
+	switch item.Spec.QoS {
+	case sascomv1.Critical:
+		urgencyLevel = HighUrgencyLevel
+	case sascomv1.Medium:
+		urgencyLevel = LowUrgencyLevel
+	default: // including Relaxed mode
+		raisePager = false
+	}
+	if raisePager {
+		pagerBody := fmt.Sprintf("A KV group (%s) holds values failing their validation"+
+			"\nDetails:"+
+			"\n%s", consulKvKey, failures)
+		if err := c.RaisePager(urgencyLevel, pagerBody); err != nil {
+			fmt.Printf("%s\n", err.Error())
+		}
+	}
+	return sanitizedConfigMapPayload
+}
@@ -0,0 +1,46 @@
+package adaptationengine
+
+import (
+	"reflect"
+	"testing"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+// TestAdaptValidationFailures guards that every policy only ever shapes the configmap, invalid values leaking nothing to be deleted from Consul
+func TestAdaptValidationFailures(t *testing.T) {
+	c, _, rec, server := newTestClient(t)
+	item := testConsulKV(server.URL)
+	payload := map[string]string{"app.replicas": "many", "app.timeout": "soon", "app.port": "http", "app.ratio": "half", "app.region": "eu"}
+	previous := map[string]string{"app.replicas": "3", "app.port": "8080"}
+	failures := utils.InvalidationsOutput{}
+	for _, path := range []string{"app.replicas", "app.timeout", "app.port", "app.ratio"} {
+		failures = append(failures, invalidation(path, finding("validation/type", "")))
+	}
+	pathToPolicy := map[string]sascomv1.ValueValidationPolicy{
+		"app.replicas": sascomv1.RejectInvalidValue,
+		"app.timeout":  sascomv1.RejectInvalidValue,
+		"app.port":     sascomv1.DropInvalidValue,
+		"app.ratio":    sascomv1.ReportInvalidValue,
+	}
+
+	sanitized := c.AdaptValidationFailures(item, failures, pathToPolicy, payload, previous)
+	// a rejected value falls back to the previous one, or is left out without any
+	want := map[string]string{"app.replicas": "3", "app.ratio": "half", "app.region": "eu"}
+	if !reflect.DeepEqual(sanitized, want) {
+		t.Errorf("expected the configmap %v, got %v", want, sanitized)
+	}
+	if mutations := rec.recordedMutations(); len(mutations) != 0 {
+		t.Errorf("invalid values got mutated in Consul: %v", mutations)
+	}
+	if incidents := rec.recordedIncidents(); len(incidents) != 1 {
+		t.Errorf("expected a single incident about the invalid values, got %v", incidents)
+	}
+
+	// the same failures don't page twice
+	c.AdaptValidationFailures(item, failures, pathToPolicy, payload, previous)
+	if incidents := rec.recordedIncidents(); len(incidents) != 1 {
+		t.Errorf("the same failures paged again: %v", incidents)
+	}
+}
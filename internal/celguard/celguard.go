@@ -0,0 +1,117 @@
+package celguard
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	"strings"
+	"sync"
+)
+
+const (
+	// Prefix marks the guards which are CEL expressions rather than detector names or regexes
+	Prefix = "cel:"
+
+	// RuntimeCostLimit bounds the work a single evaluation may do, an expression going past it fails instead of stalling the scan
+	RuntimeCostLimit = 1000000
+	// MaxExpressionLength keeps expressions short enough for their type-checking to stay cheap at admission
+	MaxExpressionLength = 2048
+)
+
+var (
+	envOnce sync.Once
+	env     *cel.Env
+	envErr  error
+)
+
+// Input is what an expression gets to look at
+type Input struct {
+	// Key is the slash separated Consul key, Path is its dotted configmap form
+	Key   string
+	Path  string
+	Value string
+	// Metadata holds the Consul metadata of the key: create_index, modify_index, lock_index, flags and session
+	Metadata map[string]interface{}
+}
+
+// Program is a type-checked expression ready to be evaluated
+type Program struct {
+	program cel.Program
+}
+
+// Expression tells whether a guard is a CEL expression and returns the expression itself
+func Expression(guard string) (string, bool) {
+	if !strings.HasPrefix(guard, Prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(guard, Prefix)), true
+}
+
+// environment declares the variables available to the expressions:
+// key, path and value as strings, json as the value parsed as JSON (null when it isn't) and metadata as a map
+func environment() (*cel.Env, error) {
+	envOnce.Do(func() {
+		env, envErr = cel.NewEnv(
+			cel.Variable("key", cel.StringType),
+			cel.Variable("path", cel.StringType),
+			cel.Variable("value", cel.StringType),
+			cel.Variable("json", cel.DynType),
+			cel.Variable("metadata", cel.MapType(cel.StringType, cel.DynType)),
+			ext.Strings(),
+			ext.Encoders(),
+		)
+	})
+	return env, envErr
+}
+
+// Compile parses and type-checks an expression, which must evaluate to a bool.
+// An expression whose type is only known at evaluation, like a field of json, is accepted, evaluating to anything but a bool then being an error.
+func Compile(expression string) (Program, error) {
+	if len(expression) > MaxExpressionLength {
+		return Program{}, fmt.Errorf("expression is %d characters long, at most %d are allowed", len(expression), MaxExpressionLength)
+	}
+	celEnv, err := environment()
+	if err != nil {
+		return Program{}, fmt.Errorf("failed to setup the CEL environment: %w", err)
+	}
+	ast, issues := celEnv.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return Program{}, fmt.Errorf("invalid expression: %w", issues.Err())
+	}
+	if outputType := ast.OutputType(); !cel.BoolType.IsAssignableType(outputType) && outputType.String() != cel.DynType.String() {
+		return Program{}, fmt.Errorf("expression must evaluate to a bool, not to a %s", ast.OutputType())
+	}
+	program, err := celEnv.Program(ast, cel.CostLimit(RuntimeCostLimit), cel.EvalOptions(cel.OptOptimize))
+	if err != nil {
+		return Program{}, fmt.Errorf("failed to build the program of the expression: %w", err)
+	}
+	return Program{program: program}, nil
+}
+
+// Matches evaluates the expression over the input
+func (p Program) Matches(input Input) (bool, error) {
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(input.Value), &parsed); err != nil {
+		parsed = nil
+	}
+	metadata := input.Metadata
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	out, _, err := p.program.Eval(map[string]interface{}{
+		"key":      input.Key,
+		"path":     input.Path,
+		"value":    input.Value,
+		"json":     parsed,
+		"metadata": metadata,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate the expression: %w", err)
+	}
+	matches, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression evaluated to a %s instead of a bool", out.Type())
+	}
+	return matches, nil
+}
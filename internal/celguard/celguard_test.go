@@ -0,0 +1,80 @@
+package celguard
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestMatches guards what the expressions get to look at, and that an evaluation going wrong is an error rather than a miss
+func TestMatches(t *testing.T) {
+	metadata := map[string]interface{}{"modify_index": int64(42), "flags": int64(0), "session": ""}
+	for _, tc := range []struct {
+		name       string
+		expression string
+		input      Input
+		matches    bool
+		failure    string
+	}{
+		{name: "key and path", expression: `key == "app/token" && path == "app.token"`, input: Input{Key: "app/token", Path: "app.token"}, matches: true},
+		{name: "json field", expression: `json.enabled == true && json.replicas > 2`, input: Input{Value: `{"enabled": true, "replicas": 3}`}, matches: true},
+		{name: "json field mismatch", expression: `json.enabled == true`, input: Input{Value: `{"enabled": false}`}},
+		{name: "not json", expression: `json == null`, input: Input{Value: "plain text"}, matches: true},
+		{name: "metadata", expression: `metadata.modify_index > 10 && metadata.session == ""`, input: Input{Metadata: metadata}, matches: true},
+		{name: "metadata without any", expression: `size(metadata) == 0`, input: Input{}, matches: true},
+		{name: "string extension", expression: `value.lowerAscii().startsWith("bearer ")`, input: Input{Value: "Bearer abc"}, matches: true},
+		// a dynamic result type-checks, but only a bool tells whether the value matches
+		{name: "non-bool result", expression: `json.enabled`, input: Input{Value: `{"enabled": "yes"}`}, failure: "instead of a bool"},
+		{name: "missing json field", expression: `json.missing == "x"`, input: Input{Value: `{"enabled": true}`}, failure: "failed to evaluate"},
+		{name: "runtime error", expression: `int(value) > 0`, input: Input{Value: "abc"}, failure: "failed to evaluate"},
+	} {
+		program, err := Compile(tc.expression)
+		if err != nil {
+			t.Fatalf("%s: unexpected compile error: %v", tc.name, err)
+		}
+		matches, err := program.Matches(tc.input)
+		if tc.failure != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.failure) {
+				t.Errorf("%s: expected an error about '%s', got %v matching %v", tc.name, tc.failure, err, matches)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if matches != tc.matches {
+			t.Errorf("%s: expected a match = %v, got %v", tc.name, tc.matches, matches)
+		}
+	}
+}
+
+// TestRuntimeCostLimit guards that an expression doing too much work on a large value fails instead of stalling the scan
+func TestRuntimeCostLimit(t *testing.T) {
+	program, err := Compile(`value.split("").all(a, value.split("").all(b, a == b))`)
+	if err != nil {
+		t.Fatalf("unexpected compile error: %v", err)
+	}
+	if _, err := program.Matches(Input{Value: strings.Repeat("a", 2000)}); err == nil || !strings.Contains(err.Error(), "cost limit") {
+		t.Errorf("expected the evaluation to be aborted by the cost limit, got %v", err)
+	}
+	if matches, err := program.Matches(Input{Value: "aaa"}); err != nil || !matches {
+		t.Errorf("expected a small value to be evaluated, got %v matching %v", err, matches)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	for _, tc := range []struct {
+		expression string
+		valid      bool
+	}{
+		{expression: `size(value) > 10`, valid: true},
+		{expression: `size(value)`},
+		{expression: `value +`},
+		{expression: `unknown == 1`},
+		{expression: strings.Repeat(" ", MaxExpressionLength) + "true"},
+	} {
+		if _, err := Compile(tc.expression); (err == nil) != tc.valid {
+			t.Errorf("%.40q: expected valid = %v, got %v", tc.expression, tc.valid, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/secretengine"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sort"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+)
+
+// AliasLibraryReconciler hot-reloads the runtime regex alias library out of the labelled ConfigMaps of a single namespace
+type AliasLibraryReconciler struct {
+	client.Client
+	Namespace string
+}
+
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+
+// Reconcile reloads the whole library whichever labelled ConfigMap changed, so that a deleted ConfigMap takes its aliases away with it
+func (r *AliasLibraryReconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+	var configMaps v1.ConfigMapList
+	if err := r.List(ctx, &configMaps, client.InNamespace(r.Namespace), client.HasLabels{sascomv1.AliasLibraryLabel}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("error occurred while listing the alias library configmaps: %w", err)
+	}
+
+	aliases := MergeAliasLibrary(configMaps.Items, func(alias string, configMapName string) {
+		log.FromContext(ctx).Info("ignoring an alias defined more than once", "alias", alias, "configmap", configMapName)
+	})
+	for _, err := range secretengine.LoadAliasLibrary(aliases) {
+		log.FromContext(ctx).Error(err, "failed to load an alias of the library")
+	}
+	log.FromContext(ctx).Info("reloaded the alias library", "aliases", len(aliases), "version", secretengine.AliasLibraryVersion())
+	return ctrl.Result{}, nil
+}
+
+// MergeAliasLibrary merges the aliases of the ConfigMaps in the order of their names, so that an alias defined twice always resolves the same way.
+// onDuplicate is called with every later definition getting ignored.
+func MergeAliasLibrary(configMaps []v1.ConfigMap, onDuplicate func(alias string, configMapName string)) map[string]string {
+	sorted := append([]v1.ConfigMap{}, configMaps...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	aliases := map[string]string{}
+	for _, configMap := range sorted {
+		for alias, regex := range configMap.Data {
+			if _, found := aliases[alias]; found {
+				onDuplicate(alias, configMap.Name)
+				continue
+			}
+			aliases[alias] = regex
+		}
+	}
+	return aliases
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *AliasLibraryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	isAliasLibrary := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		_, labelled := obj.GetLabels()[sascomv1.AliasLibraryLabel]
+		return labelled && obj.GetNamespace() == r.Namespace
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("aliaslibrary").
+		For(&v1.ConfigMap{}, builder.WithPredicates(isAliasLibrary)).
+		Complete(r)
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/secretengine"
+)
+
+// TestAliasLibraryReconcile guards that the library is made of the labelled ConfigMaps of its namespace only,
+// an alias defined twice resolving to its definition in the ConfigMap coming first by name.
+func TestAliasLibraryReconcile(t *testing.T) {
+	defer secretengine.LoadAliasLibrary(map[string]string{})
+	libraryOf := func(name string, namespace string, labelled bool, data map[string]string) *v1.ConfigMap {
+		configMap := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}, Data: data}
+		if labelled {
+			configMap.Labels = map[string]string{sascomv1.AliasLibraryLabel: ""}
+		}
+		return configMap
+	}
+	r := &AliasLibraryReconciler{
+		Client: newTestReconciler(t,
+			libraryOf("b-team", "operator", true, map[string]string{"internal-token": `itk_[a-z]+`, "team-token": `tt_[a-z]+`}),
+			libraryOf("a-platform", "operator", true, map[string]string{"internal-token": `itk_[0-9]+`}),
+			libraryOf("unlabelled", "operator", false, map[string]string{"unlabelled-token": `ut_[a-z]+`}),
+			libraryOf("elsewhere", "other", true, map[string]string{"elsewhere-token": `et_[a-z]+`}),
+		).Client,
+		Namespace: "operator",
+	}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loadedVersion := secretengine.AliasLibraryVersion()
+
+	if errs := secretengine.LoadAliasLibrary(map[string]string{"internal-token": `itk_[0-9]+`, "team-token": `tt_[a-z]+`}); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if want := secretengine.AliasLibraryVersion(); loadedVersion != want {
+		t.Errorf("expected the library %s, got %s", want, loadedVersion)
+	}
+}
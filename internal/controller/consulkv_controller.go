@@ -19,6 +19,7 @@ package controller
 import (
 	"context"
 	"encoding/base64"
+	stdErrors "errors"
 	"fmt"
 	"github.com/yashvardhan-kukreja/consulkv-commander/internal/secretengine"
 	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
@@ -30,6 +31,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 	"strings"
 	"sync"
@@ -53,6 +55,11 @@ type ConsulKVReconciler struct {
 //+kubebuilder:rbac:groups=sas.com.sas.com,resources=consulkvs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=sas.com.sas.com,resources=consulkvs/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=sas.com.sas.com,resources=consulkvs/finalizers,verbs=update
+//+kubebuilder:rbac:groups=sas.com.sas.com,resources=adaptationrequests,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=sas.com.sas.com,resources=adaptationrequests/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=sas.com.sas.com,resources=sensitivefindings,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=sas.com.sas.com,resources=sensitivefindings/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=wgpolicyk8s.io,resources=policyreports,verbs=get;list;watch;create;update;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -69,20 +76,79 @@ func (r *ConsulKVReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	var consulKv sascomv1.ConsulKV
 	if err := r.Get(ctx, req.NamespacedName, &consulKv); err != nil {
 		if errors.IsNotFound(err) {
+			// its policy report gets garbage collected along with it
+			r.SecretEngineClient.Forget(req.NamespacedName.String())
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, err
 	}
 
+	unvalidatedConfigMapPayload, pathToWeights, pathToMetadata, err := FetchConsulPayload(&consulKv)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	// values failing their validation may fall back to the ones synced last time, nil when nothing got synced yet
+	var previousConfigMapPayload map[string]string
+	var previousConfigMap v1.ConfigMap
+	if err := r.Get(ctx, client.ObjectKeyFromObject(&consulKv), &previousConfigMap); err == nil {
+		previousConfigMapPayload = previousConfigMap.Data
+	} else if !errors.IsNotFound(err) {
+		return ctrl.Result{}, fmt.Errorf("error occurred while getting the configmap previously synced: %w", err)
+	}
+
+	regoPolicyModules, err := r.regoPolicyModules(ctx, &consulKv)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	baseline, baselineErr, err := r.baseline(ctx, &consulKv)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	sanitizedConfigMapPayload, err := r.SecretEngineClient.Run(&consulKv, unvalidatedConfigMapPayload, pathToWeights, pathToMetadata, previousConfigMapPayload, regoPolicyModules, baseline, baselineErr)
+	if stdErrors.Is(err, secretengine.ErrSyncHalted) {
+		// the configmap keeps its previous content, only the status reports why
+		log.FromContext(ctx).Info("leaving the configmap untouched", "reason", err.Error())
+		return ctrl.Result{}, r.updateStatus(req.NamespacedName, consulKv.Status.DeepCopy())
+	}
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to track any invalidations after the new reconciliation: %w", err)
+	}
+
+	configMapName, configMapNamespace := consulKv.Name, consulKv.Namespace
+	desiredConfigMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configMapName,
+			Namespace: configMapNamespace,
+		},
+		Data: sanitizedConfigMapPayload,
+	}
+
+	if err := controllerutil.SetControllerReference(&consulKv, desiredConfigMap, r.Scheme); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to setup controller reference on the ")
+	}
+
+	if err := r.reconcileConfigMap(ctx, desiredConfigMap); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to reconcile the ConfigMap: %w", err)
+	}
+
+	return ctrl.Result{}, r.updateStatus(req.NamespacedName, consulKv.Status.DeepCopy())
+}
+
+// FetchConsulPayload reads every key under the paths of the ConsulKV out of Consul, keyed by their dotted configmap path
+func FetchConsulPayload(consulKv *sascomv1.ConsulKV) (map[string]string, map[string]int, map[string]utils.ConsulMetadata, error) {
 	consulKvClient := utils.NewConsulKV(consulKv.Spec.ConsulUrl)
 
 	pathToWeights := map[string]int{}
+	pathToMetadata := map[string]utils.ConsulMetadata{}
 
 	unvalidatedConfigMapPayload := map[string]string{}
 	for _, pathSpec := range consulKv.Spec.Paths {
 		consulKvResponse, err := consulKvClient.GetPath(pathSpec.Path)
 		if err != nil {
-			return ctrl.Result{}, fmt.Errorf("error occurred while GET-ing the consul key at the path %s: %w", pathSpec.Path, err)
+			return nil, nil, nil, fmt.Errorf("error occurred while GET-ing the consul key at the path %s: %w", pathSpec.Path, err)
 		}
 
 		for _, elem := range consulKvResponse {
@@ -90,40 +156,54 @@ func (r *ConsulKVReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 			base64EncodedValue := elem.Value
 			decodedValueBytes, err := base64.StdEncoding.DecodeString(base64EncodedValue)
 			if err != nil {
-				return ctrl.Result{}, fmt.Errorf("failed to decode the base64 value corresponding to the Key '%s': %w", key, err)
+				return nil, nil, nil, fmt.Errorf("failed to decode the base64 value corresponding to the Key '%s': %w", key, err)
 			}
 			value := string(decodedValueBytes)
 			key = strings.ReplaceAll(key, "/", ".")
 			unvalidatedConfigMapPayload[key] = value
 
 			pathToWeights[key] = pathSpec.CriticalityWeight
+			pathToMetadata[key] = elem.Metadata()
 		}
 	}
-	r.lock.Lock()
-	defer r.lock.Unlock()
-	sanitizedConfigMapPayload, err := r.SecretEngineClient.Run(&consulKv, unvalidatedConfigMapPayload, pathToWeights)
-	if err != nil {
-		return ctrl.Result{}, fmt.Errorf("failed to track any invalidations after the new reconciliation: %w", err)
-	}
+	return unvalidatedConfigMapPayload, pathToWeights, pathToMetadata, nil
+}
 
-	configMapName, configMapNamespace := consulKv.Name, consulKv.Namespace
-	desiredConfigMap := &v1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      configMapName,
-			Namespace: configMapNamespace,
-		},
-		Data: sanitizedConfigMapPayload,
+// baseline parses the baseline ConfigMap referenced by the ConsulKV, if any.
+// A missing or invalid baseline is returned as a baselineErr, for the scan to report it like a broken rule instead of failing the reconcile.
+func (r *ConsulKVReconciler) baseline(ctx context.Context, consulKv *sascomv1.ConsulKV) (baseline *secretengine.Baseline, baselineErr error, err error) {
+	if consulKv.Spec.Baseline == "" {
+		return nil, nil, nil
 	}
-
-	if err := controllerutil.SetControllerReference(&consulKv, desiredConfigMap, r.Scheme); err != nil {
-		return ctrl.Result{}, fmt.Errorf("failed to setup controller reference on the ")
+	var configMap v1.ConfigMap
+	if err := r.Get(ctx, client.ObjectKey{Namespace: consulKv.Namespace, Name: consulKv.Spec.Baseline}, &configMap); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, fmt.Errorf("the baseline configmap %s doesn't exist", consulKv.Spec.Baseline), nil
+		}
+		return nil, nil, fmt.Errorf("error occurred while getting the baseline configmap %s: %w", consulKv.Spec.Baseline, err)
 	}
-
-	if err := r.reconcileConfigMap(ctx, desiredConfigMap); err != nil {
-		return ctrl.Result{}, fmt.Errorf("failed to reconcile the ConfigMap: %w", err)
+	parsed, err := secretengine.ParseBaseline(configMap.Data[secretengine.BaselineConfigMapKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid baseline in the configmap %s: %w", consulKv.Spec.Baseline, err), nil
 	}
+	return &parsed, nil, nil
+}
 
-	return ctrl.Result{}, r.updateStatus(req.NamespacedName, consulKv.Status.DeepCopy())
+// regoPolicyModules collects the '.rego' entries of the ConfigMaps referenced by the ConsulKV, keyed by '<configmap>/<entry>'
+func (r *ConsulKVReconciler) regoPolicyModules(ctx context.Context, consulKv *sascomv1.ConsulKV) (map[string]string, error) {
+	modules := map[string]string{}
+	for _, configMapName := range consulKv.Spec.RegoPolicies {
+		var configMap v1.ConfigMap
+		if err := r.Get(ctx, client.ObjectKey{Namespace: consulKv.Namespace, Name: configMapName}, &configMap); err != nil {
+			return nil, fmt.Errorf("error occurred while getting the Rego policies of the configmap %s: %w", configMapName, err)
+		}
+		for entry, module := range configMap.Data {
+			if strings.HasSuffix(entry, ".rego") {
+				modules[configMapName+"/"+entry] = module
+			}
+		}
+	}
+	return modules, nil
 }
 
 func (r *ConsulKVReconciler) updateStatus(consulKvKey client.ObjectKey, newStatus *sascomv1.ConsulKVStatus) error {
@@ -166,12 +246,49 @@ func (r *ConsulKVReconciler) reconcileConfigMap(ctx context.Context, desiredConf
 	return nil
 }
 
+// referencedConfigMapsField indexes the ConsulKVs by the ConfigMaps they read their Rego policies and baseline from
+const referencedConfigMapsField = "spec.referencedConfigMaps"
+
+// referencedConfigMaps lists the names of the ConfigMaps, in its own namespace, a ConsulKV reads its Rego policies and baseline from
+func referencedConfigMaps(obj client.Object) []string {
+	consulKv, ok := obj.(*sascomv1.ConsulKV)
+	if !ok {
+		return nil
+	}
+	names := append([]string{}, consulKv.Spec.RegoPolicies...)
+	if consulKv.Spec.Baseline != "" {
+		names = append(names, consulKv.Spec.Baseline)
+	}
+	return names
+}
+
+// consulKVsReferencing enqueues the ConsulKVs reading their Rego policies or baseline from the ConfigMap, so that editing them applies right away
+func (r *ConsulKVReconciler) consulKVsReferencing(ctx context.Context, configMap client.Object) []reconcile.Request {
+	var list sascomv1.ConsulKVList
+	if err := r.List(ctx, &list, client.InNamespace(configMap.GetNamespace()), client.MatchingFields{referencedConfigMapsField: configMap.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list the ConsulKVs referencing a configmap", "configmap", client.ObjectKeyFromObject(configMap).String())
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, item := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
+	}
+	return requests
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ConsulKVReconciler) SetupWithManager(mgr ctrl.Manager, periodicConfigMapReconcilerChan chan event.GenericEvent) error {
 	r.lock = &sync.Mutex{}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &sascomv1.ConsulKV{}, referencedConfigMapsField, referencedConfigMaps); err != nil {
+		return fmt.Errorf("failed to index the ConsulKVs by the configmaps they reference: %w", err)
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&sascomv1.ConsulKV{}).
 		Owns(&v1.ConfigMap{}).
+		Owns(&sascomv1.AdaptationRequest{}).
+		// a triage, either through the annotation or the status, takes effect right away
+		Owns(&sascomv1.SensitiveFinding{}).
+		Watches(&v1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.consulKVsReferencing)).
 		WatchesRawSource(&source.Channel{Source: periodicConfigMapReconcilerChan}, &handler.EnqueueRequestForObject{}).
 		Complete(r)
 }
@@ -0,0 +1,252 @@
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/adaptationengine"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/knowledgebase"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/secretengine"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+// fakeConsul serves the KV endpoints of Consul out of a map and records every mutation it receives
+type fakeConsul struct {
+	lock      sync.Mutex
+	kvs       map[string]string
+	mutations []string
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	switch r.Method {
+	case http.MethodGet:
+		response := utils.ConsulKVResponse{}
+		for k, v := range f.kvs {
+			if k == key || (r.URL.Query().Has("recurse") && strings.HasPrefix(k, key)) {
+				response = append(response, utils.ConsulKVResponseElement{Key: k, Value: base64.StdEncoding.EncodeToString([]byte(v)), ModifyIndex: 1})
+			}
+		}
+		if len(response) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	default:
+		f.mutations = append(f.mutations, r.Method+" "+key)
+		if r.Method == http.MethodDelete {
+			delete(f.kvs, key)
+		}
+		_, _ = w.Write([]byte("true"))
+	}
+}
+
+func (f *fakeConsul) recordedMutations() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	output := append([]string{}, f.mutations...)
+	sort.Strings(output)
+	return output
+}
+
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register the core types: %v", err)
+	}
+	if err := sascomv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to register the sas.com types: %v", err)
+	}
+	return scheme
+}
+
+func newTestReconciler(t *testing.T, objects ...client.Object) *ConsulKVReconciler {
+	scheme := newTestScheme(t)
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objects...).
+		WithIndex(&sascomv1.ConsulKV{}, referencedConfigMapsField, referencedConfigMaps).
+		WithStatusSubresource(&sascomv1.ConsulKV{}, &sascomv1.SensitiveFinding{}).
+		Build()
+	kbCtx := knowledgebase.New(context.Background())
+	adaptationEngineClient, err := adaptationengine.NewClient(k8sClient, nil, "", kbCtx, "", &aws.Config{Region: aws.String("us-east-1")}, sascomv1.BlastRadiusSpec{})
+	if err != nil {
+		t.Fatalf("failed to setup the adaptation engine client: %v", err)
+	}
+	return &ConsulKVReconciler{
+		Client:             k8sClient,
+		Scheme:             scheme,
+		SecretEngineClient: secretengine.NewClient(kbCtx, adaptationEngineClient, secretengine.DefaultDetectorErrorPolicies, utils.NewRedactor([]byte("test-key")), 1),
+		lock:               &sync.Mutex{},
+	}
+}
+
+func testConsulKV(consulUrl string, guards ...string) *sascomv1.ConsulKV {
+	return &sascomv1.ConsulKV{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", UID: "app-uid", Generation: 1},
+		Spec: sascomv1.ConsulKVSpec{
+			ConsulUrl:    consulUrl,
+			Paths:        []sascomv1.PathSpec{{Path: "app/"}},
+			GuardAgainst: guards,
+			QoS:          sascomv1.Critical,
+		},
+	}
+}
+
+// TestBrokenGuardLeavesConfigMapIntact guards that a guard failing as a whole under the fail-closed policy protects every key
+// with the value synced last time, and halts the sync when nothing got synced yet rather than creating an empty configmap.
+func TestBrokenGuardLeavesConfigMapIntact(t *testing.T) {
+	previousData := map[string]string{"app.replicas": "3", "app.region": "eu-west-1"}
+	for _, tc := range []struct {
+		name         string
+		previousData map[string]string
+	}{
+		{name: "synced before", previousData: previousData},
+		{name: "never synced"},
+	} {
+		consul := &fakeConsul{kvs: map[string]string{"app/replicas": "4", "app/region": "eu-west-1", "app/timeout": "30s"}}
+		server := httptest.NewServer(consul)
+		consulKv := testConsulKV(server.URL, "([")
+		objects := []client.Object{consulKv}
+		if tc.previousData != nil {
+			objects = append(objects, &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}, Data: tc.previousData})
+		}
+		r := newTestReconciler(t, objects...)
+
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(consulKv)}); err != nil {
+			t.Fatalf("%s: unexpected reconcile error: %v", tc.name, err)
+		}
+		server.Close()
+
+		var current v1.ConfigMap
+		err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "app"}, &current)
+		if tc.previousData == nil {
+			if err == nil {
+				t.Errorf("%s: a configmap got created out of unevaluated values: %v", tc.name, current.Data)
+			}
+		} else if err != nil {
+			t.Fatalf("%s: the configmap is gone: %v", tc.name, err)
+		} else if !reflect.DeepEqual(current.Data, tc.previousData) {
+			t.Errorf("%s: expected every key to fall back to %v, got %v", tc.name, tc.previousData, current.Data)
+		}
+		if mutations := consul.recordedMutations(); len(mutations) != 0 {
+			t.Errorf("%s: unexpected mutations of Consul: %v", tc.name, mutations)
+		}
+
+		var updated sascomv1.ConsulKV
+		if err := r.Get(context.Background(), client.ObjectKeyFromObject(consulKv), &updated); err != nil {
+			t.Fatalf("%s: failed to get the ConsulKV: %v", tc.name, err)
+		}
+		if !meta.IsStatusConditionFalse(updated.Status.Conditions, sascomv1.DetectorsHealthyCondition) {
+			t.Errorf("%s: the broken guard is not reported in the status: %v", tc.name, updated.Status.Conditions)
+		}
+	}
+}
+
+// TestBaselineErrorsDontFailTheReconcile guards that a missing or invalid baseline is reported like a broken rule,
+// protecting every key under the fail-closed policy and syncing without it under the fail-open one, rather than failing the reconcile.
+func TestBaselineErrorsDontFailTheReconcile(t *testing.T) {
+	previousData := map[string]string{"app.replicas": "3"}
+	for _, tc := range []struct {
+		name     string
+		baseline *v1.ConfigMap
+		policy   sascomv1.DetectorErrorPolicy
+		wantData map[string]string
+	}{
+		{name: "missing", policy: sascomv1.FailClosed, wantData: previousData},
+		{name: "invalid", baseline: &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app-baseline", Namespace: "default"}, Data: map[string]string{secretengine.BaselineConfigMapKey: "{"}},
+			policy: sascomv1.FailClosed, wantData: previousData},
+		{name: "missing under fail-open", policy: sascomv1.FailOpen, wantData: map[string]string{"app.replicas": "4"}},
+	} {
+		consul := &fakeConsul{kvs: map[string]string{"app/replicas": "4"}}
+		server := httptest.NewServer(consul)
+		consulKv := testConsulKV(server.URL, "aws-access-key")
+		consulKv.Spec.Baseline = "app-baseline"
+		consulKv.Spec.DetectorErrorPolicy = tc.policy
+		objects := []client.Object{consulKv, &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}, Data: previousData}}
+		if tc.baseline != nil {
+			objects = append(objects, tc.baseline)
+		}
+		r := newTestReconciler(t, objects...)
+
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(consulKv)}); err != nil {
+			t.Errorf("%s: unexpected reconcile error: %v", tc.name, err)
+		}
+		server.Close()
+
+		var current v1.ConfigMap
+		if err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "app"}, &current); err != nil {
+			t.Fatalf("%s: the configmap is gone: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(current.Data, tc.wantData) {
+			t.Errorf("%s: expected the configmap to hold %v, got %v", tc.name, tc.wantData, current.Data)
+		}
+		var updated sascomv1.ConsulKV
+		if err := r.Get(context.Background(), client.ObjectKeyFromObject(consulKv), &updated); err != nil {
+			t.Fatalf("%s: failed to get the ConsulKV: %v", tc.name, err)
+		}
+		condition := meta.FindStatusCondition(updated.Status.Conditions, sascomv1.DetectorsHealthyCondition)
+		if condition == nil || condition.Status != metav1.ConditionFalse || !strings.Contains(condition.Message, secretengine.BaselineRule) {
+			t.Errorf("%s: the baseline error is not reported in the status: %+v", tc.name, condition)
+		}
+	}
+}
+
+// TestConfigMapEditsEnqueueReferencingConsulKVs guards that editing the Rego policies or the baseline of a ConsulKV reconciles it right away
+// instead of waiting for the periodic resync.
+func TestConfigMapEditsEnqueueReferencingConsulKVs(t *testing.T) {
+	withPolicies := testConsulKV("http://consul")
+	withPolicies.Spec.RegoPolicies = []string{"policies", "shared"}
+	withBaseline := testConsulKV("http://consul")
+	withBaseline.Name, withBaseline.UID = "other", "other-uid"
+	withBaseline.Spec.Baseline = "shared"
+	elsewhere := testConsulKV("http://consul")
+	elsewhere.Namespace = "elsewhere"
+	elsewhere.Spec.RegoPolicies = []string{"shared"}
+	r := newTestReconciler(t, withPolicies, withBaseline, elsewhere)
+
+	for configMapName, want := range map[string][]string{
+		"policies": {"default/app"},
+		"shared":   {"default/app", "default/other"},
+		"app":      {},
+	} {
+		configMap := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: configMapName, Namespace: "default"}}
+		got := []string{}
+		for _, request := range r.consulKVsReferencing(context.Background(), configMap) {
+			got = append(got, request.NamespacedName.String())
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("configmap %s: expected %v to be enqueued, got %v", configMapName, want, got)
+		}
+	}
+}
+
+// TestReconcileDeletedConsulKV guards that forgetting a deleted ConsulKV succeeds, even on a cluster without the PolicyReport CRD
+func TestReconcileDeletedConsulKV(t *testing.T) {
+	r := newTestReconciler(t)
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Namespace: "default", Name: "gone"}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
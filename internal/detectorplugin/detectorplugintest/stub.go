@@ -0,0 +1,61 @@
+// Package detectorplugintest provides a local detector plugin for tests, kept apart so that httptest stays out of the manager binary
+package detectorplugintest
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/detectorplugin"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Stub is a local plugin for tests, it records every request it gets and answers them with the provided function
+type Stub struct {
+	server   *httptest.Server
+	lock     *sync.Mutex
+	requests []detectorplugin.Request
+}
+
+// NewStub starts a stub plugin, it must be closed once done with
+func NewStub(detect detectorplugin.DetectFunc) *Stub {
+	stub := &Stub{lock: &sync.Mutex{}}
+	handler := detectorplugin.Handler(detect)
+	stub.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := detectorplugin.Request{}
+		if err := json.Unmarshal(body, &request); err == nil {
+			stub.lock.Lock()
+			stub.requests = append(stub.requests, request)
+			stub.lock.Unlock()
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		handler.ServeHTTP(w, r)
+	}))
+	return stub
+}
+
+// NewFailingStub starts a stub plugin answering every request with the provided status code
+func NewFailingStub(statusCode int) *Stub {
+	stub := &Stub{lock: &sync.Mutex{}}
+	stub.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, http.StatusText(statusCode), statusCode)
+	}))
+	return stub
+}
+
+func (s *Stub) URL() string {
+	return s.server.URL
+}
+
+// Requests lists the requests handled so far, in the order they came in
+func (s *Stub) Requests() []detectorplugin.Request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]detectorplugin.Request{}, s.requests...)
+}
+
+func (s *Stub) Close() {
+	s.server.Close()
+}
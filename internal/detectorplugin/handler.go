@@ -0,0 +1,40 @@
+package detectorplugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// DetectFunc scans a single item of a request
+type DetectFunc func(detector string, item Item) []Finding
+
+// Handler serves the v1 schema over HTTP, leaving the actual detection up to the provided function
+func Handler(detect DetectFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		request := Request{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, fmt.Sprintf("failed to decode the request: %v", err), http.StatusBadRequest)
+			return
+		}
+		if request.SchemaVersion != SchemaVersion {
+			http.Error(w, fmt.Sprintf("unsupported schema version '%s', expected '%s'", request.SchemaVersion, SchemaVersion), http.StatusBadRequest)
+			return
+		}
+
+		response := Response{SchemaVersion: SchemaVersion, Results: []Result{}}
+		for _, item := range request.Items {
+			if findings := detect(request.Detector, item); len(findings) != 0 {
+				response.Results = append(response.Results, Result{ID: item.ID, Findings: findings})
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			fmt.Printf("failed to encode the response: %v\n", err)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+// Package detectorplugin holds the versioned schema spoken between the operator and the external detector plugins it sends values to
+package detectorplugin
+
+// SchemaVersion is the version of the request/response schema below, a plugin must echo it back in its responses
+const SchemaVersion = "v1"
+
+// Request is a batch of Consul keys a plugin is asked to scan
+type Request struct {
+	SchemaVersion string `json:"schema_version"`
+	// Detector is the name the plugin is referenced by in the ConsulKV
+	Detector string `json:"detector"`
+	Items    []Item `json:"items"`
+}
+
+// Item is a single Consul key along with either the keyed fingerprint of its value alone, or the value too
+type Item struct {
+	// ID is unique within its request only
+	ID          string `json:"id"`
+	Key         string `json:"key"`
+	Fingerprint string `json:"fingerprint"`
+	// Value is only present when the plugin was granted access to the values themselves
+	Value *string `json:"value,omitempty"`
+}
+
+// Response lists the findings of a plugin per item, items without findings may be left out
+type Response struct {
+	SchemaVersion string   `json:"schema_version"`
+	Results       []Result `json:"results"`
+}
+
+type Result struct {
+	ID       string    `json:"id"`
+	Findings []Finding `json:"findings"`
+}
+
+type Finding struct {
+	RuleID string `json:"rule_id"`
+	// Confidence ranges from 0 to 1
+	Confidence float64 `json:"confidence"`
+	// Severity is one of low, medium, high or critical, medium is assumed otherwise
+	Severity string `json:"severity,omitempty"`
+	// Span is relative to the value and defaults to the whole of it
+	Span *Span `json:"span,omitempty"`
+}
+
+// Span holds the byte offsets [Start, End) of the part of the value the finding covers
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
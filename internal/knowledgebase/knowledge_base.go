@@ -12,8 +12,17 @@ type contextKey int
 const (
 	lastInvalidationsOutputKey contextKey = iota
 	lastInvalidationsTime
+	deletionsHistoryKey
 )
 
+// deletionsHistoryRetention bounds how far back the deletions history is kept around
+const deletionsHistoryRetention = 24 * time.Hour
+
+type deletionRecord struct {
+	timestamp int64
+	count     int
+}
+
 type KnowledgeBaseContext struct {
 	ctx  context.Context
 	lock *sync.RWMutex
@@ -66,3 +75,48 @@ func (c *KnowledgeBaseContext) SetInvalidationsOutput(consulKvKey string, newInv
 
 	c.ctx = newCtx
 }
+
+func (c *KnowledgeBaseContext) RecordDeletions(consulKvKey string, count int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	history, ok := c.ctx.Value(deletionsHistoryKey).(map[string][]deletionRecord)
+	if !ok {
+		history = map[string][]deletionRecord{}
+	}
+
+	now := time.Now()
+	retained := []deletionRecord{}
+	for _, record := range history[consulKvKey] {
+		if record.timestamp >= now.Add(-deletionsHistoryRetention).UnixMilli() {
+			retained = append(retained, record)
+		}
+	}
+	history[consulKvKey] = append(retained, deletionRecord{timestamp: now.UnixMilli(), count: count})
+
+	c.ctx = context.WithValue(c.ctx, deletionsHistoryKey, history)
+}
+
+// GetDeletionsSince counts the keys deleted on behalf of the provided ConsulKV since the given time.
+// An empty consulKvKey counts the deletions across every ConsulKV.
+func (c *KnowledgeBaseContext) GetDeletionsSince(consulKvKey string, since time.Time) int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	history, ok := c.ctx.Value(deletionsHistoryKey).(map[string][]deletionRecord)
+	if !ok {
+		return 0
+	}
+	total := 0
+	for key, records := range history {
+		if consulKvKey != "" && key != consulKvKey {
+			continue
+		}
+		for _, record := range records {
+			if record.timestamp >= since.UnixMilli() {
+				total += record.count
+			}
+		}
+	}
+	return total
+}
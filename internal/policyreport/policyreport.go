@@ -0,0 +1,189 @@
+// Package policyreport models the wgpolicyk8s.io PolicyReport, aggregated by the Policy Reporter UI alongside the reports of other tools.
+// The CRD isn't shipped by the operator, reports are only written to the clusters which have it installed.
+package policyreport
+
+import (
+	"context"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/pointer"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+)
+
+// Source names the operator in the results, Policy Reporter filters on it
+const Source = "consulkv-commander"
+
+// managedByLabel tells the reports written by the operator apart from the ones of other tools
+const managedByLabel = "app.kubernetes.io/managed-by"
+
+// GroupVersionKind is the one of the PolicyReport CRD
+var GroupVersionKind = schema.GroupVersionKind{Group: "wgpolicyk8s.io", Version: "v1alpha2", Kind: "PolicyReport"}
+
+type ResultStatus string
+
+var (
+	Pass  ResultStatus = "pass"
+	Fail  ResultStatus = "fail"
+	Warn  ResultStatus = "warn"
+	Error ResultStatus = "error"
+	Skip  ResultStatus = "skip"
+)
+
+// Result is the outcome of a rule against a resource, Policy being the guard the rule belongs to
+type Result struct {
+	Source     string                   `json:"source"`
+	Policy     string                   `json:"policy"`
+	Rule       string                   `json:"rule,omitempty"`
+	Category   string                   `json:"category,omitempty"`
+	Severity   string                   `json:"severity,omitempty"`
+	Timestamp  metav1.Timestamp         `json:"timestamp,omitempty"`
+	Result     ResultStatus             `json:"result"`
+	Scored     bool                     `json:"scored"`
+	Resources  []corev1.ObjectReference `json:"resources,omitempty"`
+	Message    string                   `json:"message,omitempty"`
+	Properties map[string]string        `json:"properties,omitempty"`
+}
+
+type Summary struct {
+	Pass  int `json:"pass"`
+	Fail  int `json:"fail"`
+	Warn  int `json:"warn"`
+	Error int `json:"error"`
+	Skip  int `json:"skip"`
+}
+
+// Report is the content of a PolicyReport besides its metadata, Scope being the ConsulKV the results were evaluated for
+type Report struct {
+	Scope   *corev1.ObjectReference `json:"scope,omitempty"`
+	Summary Summary                 `json:"summary"`
+	Results []Result                `json:"results,omitempty"`
+}
+
+// FromUnstructured reads the report out of a PolicyReport
+func FromUnstructured(object *unstructured.Unstructured) (Report, error) {
+	report := Report{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object.UnstructuredContent(), &report); err != nil {
+		return Report{}, fmt.Errorf("failed to read the policy report %s/%s: %w", object.GetNamespace(), object.GetName(), err)
+	}
+	return report, nil
+}
+
+// ToUnstructured writes the report into a PolicyReport, leaving its metadata alone
+func (r Report) ToUnstructured(object *unstructured.Unstructured) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&r)
+	if err != nil {
+		return fmt.Errorf("failed to write the policy report %s/%s: %w", object.GetNamespace(), object.GetName(), err)
+	}
+	object.SetGroupVersionKind(GroupVersionKind)
+	for _, field := range []string{"scope", "summary", "results"} {
+		if value, found := content[field]; found {
+			object.Object[field] = value
+		} else {
+			delete(object.Object, field)
+		}
+	}
+	return nil
+}
+
+// Replace swaps the results of the report for the provided ones, and tells whether anything but their timestamps changed
+func (r *Report) Replace(results []Result) bool {
+	results = append([]Result{}, results...)
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Policy != results[j].Policy {
+			return results[i].Policy < results[j].Policy
+		}
+		return results[i].Rule < results[j].Rule
+	})
+	if equalIgnoringTimestamps(r.Results, results) {
+		return false
+	}
+	r.Results = results
+	r.Summary = Summary{}
+	for _, result := range r.Results {
+		switch result.Result {
+		case Pass:
+			r.Summary.Pass++
+		case Fail:
+			r.Summary.Fail++
+		case Warn:
+			r.Summary.Warn++
+		case Error:
+			r.Summary.Error++
+		case Skip:
+			r.Summary.Skip++
+		}
+	}
+	return true
+}
+
+func equalIgnoringTimestamps(previous []Result, current []Result) bool {
+	if len(previous) != len(current) {
+		return false
+	}
+	for idx := range previous {
+		left, right := previous[idx], current[idx]
+		left.Timestamp, right.Timestamp = metav1.Timestamp{}, metav1.Timestamp{}
+		if !reflect.DeepEqual(left, right) {
+			return false
+		}
+	}
+	return true
+}
+
+// Write replaces the results in the PolicyReport of the scope, a ConsulKV, creating the report named after it and owned by it if needed,
+// so that a report never outgrows a single ConsulKV and goes away along with it.
+// Nothing gets written when only the timestamps of the results would change.
+func Write(ctx context.Context, k8sClient client.Client, scope corev1.ObjectReference, results []Result) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		object := &unstructured.Unstructured{}
+		object.SetGroupVersionKind(GroupVersionKind)
+		err := k8sClient.Get(ctx, client.ObjectKey{Namespace: scope.Namespace, Name: scope.Name}, object)
+		if errors.IsNotFound(err) {
+			if len(results) == 0 {
+				return nil
+			}
+			report := Report{Scope: &scope}
+			report.Replace(results)
+			object.SetNamespace(scope.Namespace)
+			object.SetName(scope.Name)
+			object.SetLabels(map[string]string{managedByLabel: Source})
+			object.SetOwnerReferences(ownerReferences(scope))
+			if err := report.ToUnstructured(object); err != nil {
+				return err
+			}
+			return k8sClient.Create(ctx, object)
+		}
+		if err != nil {
+			return err
+		}
+		if object.GetLabels()[managedByLabel] != Source {
+			return fmt.Errorf("the policy report %s/%s is managed by another tool", object.GetNamespace(), object.GetName())
+		}
+		report, err := FromUnstructured(object)
+		if err != nil {
+			return err
+		}
+		// a ConsulKV created anew under the same name takes the report over before it gets garbage collected
+		if !report.Replace(results) && reflect.DeepEqual(report.Scope, &scope) {
+			return nil
+		}
+		report.Scope = &scope
+		object.SetOwnerReferences(ownerReferences(scope))
+		if err := report.ToUnstructured(object); err != nil {
+			return err
+		}
+		return k8sClient.Update(ctx, object)
+	})
+}
+
+func ownerReferences(scope corev1.ObjectReference) []metav1.OwnerReference {
+	return []metav1.OwnerReference{{APIVersion: scope.APIVersion, Kind: scope.Kind, Name: scope.Name, UID: scope.UID, Controller: pointer.Bool(true)}}
+}
@@ -0,0 +1,127 @@
+package policyreport
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func resultOf(source string, policy string, rule string, status ResultStatus, seconds int64) Result {
+	return Result{Source: source, Policy: policy, Rule: rule, Result: status, Scored: true, Timestamp: metav1.Timestamp{Seconds: seconds}}
+}
+
+func rulesOf(report Report) []string {
+	rules := []string{}
+	for _, result := range report.Results {
+		rules = append(rules, result.Source+"/"+result.Policy+"/"+result.Rule)
+	}
+	return rules
+}
+
+// TestReplace guards that the results get replaced as a whole, the summary counting every one of them,
+// and that a replacement only changing the timestamps leaves the report alone.
+func TestReplace(t *testing.T) {
+	report := Report{Results: []Result{resultOf(Source, "email", "email", Fail, 1)}}
+
+	if !report.Replace([]Result{resultOf(Source, "github-token", "github-token", Fail, 2), resultOf(Source, "email", "email", Pass, 2), resultOf(Source, "credentials", "aws-access-key", Warn, 2)}) {
+		t.Fatalf("the replaced results weren't reported as changed")
+	}
+	wantRules := []string{"consulkv-commander/credentials/aws-access-key", "consulkv-commander/email/email", "consulkv-commander/github-token/github-token"}
+	if rules := rulesOf(report); !reflect.DeepEqual(rules, wantRules) {
+		t.Errorf("expected the results %v, got %v", wantRules, rules)
+	}
+	if want := (Summary{Pass: 1, Fail: 1, Warn: 1}); report.Summary != want {
+		t.Errorf("expected the summary %+v, got %+v", want, report.Summary)
+	}
+
+	if report.Replace([]Result{resultOf(Source, "email", "email", Pass, 3), resultOf(Source, "github-token", "github-token", Fail, 3), resultOf(Source, "credentials", "aws-access-key", Warn, 3)}) {
+		t.Errorf("a replacement only changing the timestamps was reported as changed")
+	}
+	if report.Results[0].Timestamp.Seconds != 2 {
+		t.Errorf("a replacement only changing the timestamps got applied")
+	}
+
+	if !report.Replace(nil) {
+		t.Fatalf("dropping the results wasn't reported as changed")
+	}
+	if report.Summary != (Summary{}) || len(report.Results) != 0 {
+		t.Errorf("expected an empty report, got %+v over %v", report.Summary, rulesOf(report))
+	}
+}
+
+// TestWrite guards that the report of a ConsulKV is only created once there are results for it, owned by the ConsulKV and scoped to it,
+// that it is only updated when they change and that the report of another tool is left alone.
+func TestWrite(t *testing.T) {
+	k8sClient := fake.NewClientBuilder().WithScheme(runtime.NewScheme()).Build()
+	ctx := context.Background()
+	scope := corev1.ObjectReference{APIVersion: "sas.com/v1", Kind: "ConsulKV", Namespace: "default", Name: "app", UID: "app-uid"}
+	key := client.ObjectKey{Namespace: "default", Name: "app"}
+	get := func() *unstructured.Unstructured {
+		object := &unstructured.Unstructured{}
+		object.SetGroupVersionKind(GroupVersionKind)
+		if err := k8sClient.Get(ctx, key, object); err != nil {
+			t.Fatalf("failed to get the report: %v", err)
+		}
+		return object
+	}
+
+	if err := Write(ctx, k8sClient, scope, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(GroupVersionKind)
+	if err := k8sClient.Get(ctx, key, object); err == nil {
+		t.Errorf("a report got created without any result")
+	}
+
+	if err := Write(ctx, k8sClient, scope, []Result{resultOf(Source, "email", "email", Fail, 1)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	created := get()
+	report, err := FromUnstructured(created)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Results) != 1 || report.Summary != (Summary{Fail: 1}) || report.Scope == nil || *report.Scope != scope {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if owners := created.GetOwnerReferences(); len(owners) != 1 || owners[0].UID != scope.UID || created.GetLabels()[managedByLabel] != Source {
+		t.Errorf("the report isn't owned by the ConsulKV: owned by %v, labelled %v", owners, created.GetLabels())
+	}
+
+	if err := Write(ctx, k8sClient, scope, []Result{resultOf(Source, "email", "email", Fail, 2)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if get().GetResourceVersion() != created.GetResourceVersion() {
+		t.Errorf("the report got updated although only the timestamps changed")
+	}
+
+	recreated := scope
+	recreated.UID = "app-uid-2"
+	if err := Write(ctx, k8sClient, recreated, []Result{resultOf(Source, "email", "email", Fail, 3)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if owners := get().GetOwnerReferences(); len(owners) != 1 || owners[0].UID != recreated.UID {
+		t.Errorf("the ConsulKV created anew didn't take the report over: %v", owners)
+	}
+
+	foreign := &unstructured.Unstructured{}
+	foreign.SetGroupVersionKind(GroupVersionKind)
+	foreign.SetNamespace("default")
+	foreign.SetName("other")
+	if err := k8sClient.Create(ctx, foreign); err != nil {
+		t.Fatalf("failed to create the report of another tool: %v", err)
+	}
+	other := scope
+	other.Name = "other"
+	if err := Write(ctx, k8sClient, other, []Result{resultOf(Source, "email", "email", Fail, 1)}); err == nil {
+		t.Errorf("the report of another tool got overwritten")
+	}
+}
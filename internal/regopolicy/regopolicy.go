@@ -0,0 +1,125 @@
+// Package regopolicy evaluates Rego policies over the whole payload of a KV group, for the rules spanning several keys
+package regopolicy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/open-policy-agent/opa/rego"
+	"sort"
+	"time"
+)
+
+const (
+	// Query is the rule every set of policies is evaluated through, policies add to it from the package 'consulkv'
+	Query = "data.consulkv.findings"
+	// EvaluationTimeout bounds a single evaluation of the policies
+	EvaluationTimeout = 5 * time.Second
+)
+
+// unsafeBuiltins are kept out of reach of the policies, an evaluation must neither reach the network nor the runtime of the operator
+var unsafeBuiltins = map[string]struct{}{
+	"http.send":          {},
+	"net.lookup_ip_addr": {},
+	"opa.runtime":        {},
+}
+
+// Input is what the policies get as 'input', keys are slash-separated as in Consul
+type Input struct {
+	Payload  map[string]string                 `json:"payload"`
+	Metadata map[string]map[string]interface{} `json:"metadata"`
+	ConsulKV map[string]interface{}            `json:"consulkv"`
+}
+
+// Finding is an element of the set the policies make up under Query, Key must name a key of the payload
+type Finding struct {
+	Key    string `json:"key"`
+	RuleID string `json:"rule_id"`
+	// Severity is one of low, medium, high or critical, medium is assumed otherwise
+	Severity string `json:"severity,omitempty"`
+	// Confidence defaults to 1, the policy being an explicit statement of its author
+	Confidence *float64 `json:"confidence,omitempty"`
+	Message    string   `json:"message,omitempty"`
+	// Advisory findings are reported but never trigger any adaptation, for policies about values which leak nothing
+	Advisory bool `json:"advisory,omitempty"`
+}
+
+// Evaluator is a compiled set of policies
+type Evaluator struct {
+	query rego.PreparedEvalQuery
+	// Version is a digest of the source of the policies
+	Version string
+}
+
+// Compile compiles the provided Rego modules, keyed by their file name
+func Compile(modules map[string]string) (Evaluator, error) {
+	names := []string{}
+	for name := range modules {
+		names = append(names, name)
+	}
+	// modules are added in a stable order for the compile errors to be stable too
+	sort.Strings(names)
+
+	options := []func(*rego.Rego){rego.Query(Query), rego.UnsafeBuiltins(unsafeBuiltins), rego.StrictBuiltinErrors(true)}
+	for _, name := range names {
+		options = append(options, rego.Module(name, modules[name]))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), EvaluationTimeout)
+	defer cancel()
+	query, err := rego.New(options...).PrepareForEval(ctx)
+	if err != nil {
+		return Evaluator{}, fmt.Errorf("failed to compile the Rego policies: %w", err)
+	}
+	return Evaluator{query: query, Version: Version(modules)}, nil
+}
+
+// Version is a digest of the source of the modules, the same modules always yield the same version
+func Version(modules map[string]string) string {
+	names := []string{}
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	digest := sha256.New()
+	for _, name := range names {
+		digest.Write([]byte(name + "\n" + modules[name] + "\n"))
+	}
+	return hex.EncodeToString(digest.Sum(nil))[:12]
+}
+
+// Evaluate returns the findings the policies make up for the input, an undefined Query means no findings
+func (e Evaluator) Evaluate(input Input) ([]Finding, error) {
+	// the input goes through JSON so that the policies see plain JSON types
+	inputBytes, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the policy input: %w", err)
+	}
+	var rawInput interface{}
+	if err := json.Unmarshal(inputBytes, &rawInput); err != nil {
+		return nil, fmt.Errorf("failed to decode the policy input: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), EvaluationTimeout)
+	defer cancel()
+	resultSet, err := e.query.Eval(ctx, rego.EvalInput(rawInput))
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate the Rego policies: %w", err)
+	}
+	findings := []Finding{}
+	for _, result := range resultSet {
+		for _, expression := range result.Expressions {
+			resultBytes, err := json.Marshal(expression.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode the findings of the Rego policies: %w", err)
+			}
+			expressionFindings := []Finding{}
+			if err := json.Unmarshal(resultBytes, &expressionFindings); err != nil {
+				return nil, fmt.Errorf("%s must be a set of objects with a key and a rule_id: %w", Query, err)
+			}
+			findings = append(findings, expressionFindings...)
+		}
+	}
+	return findings, nil
+}
@@ -0,0 +1,109 @@
+package secretengine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// libraryAliasConfidence is the confidence of the aliases loaded at runtime, they are vetted by whoever manages the operator rather than by the author of the ConsulKV
+const libraryAliasConfidence = 0.8
+
+// aliasLibrary holds the regex aliases loaded at runtime on top of the ones compiled into the binary
+type aliasLibrary struct {
+	lock      *sync.RWMutex
+	regexes   map[string]string
+	detectors map[string]Detector
+	version   string
+}
+
+var runtimeAliasLibrary = &aliasLibrary{
+	lock:      &sync.RWMutex{},
+	regexes:   map[string]string{},
+	detectors: map[string]Detector{},
+}
+
+// libraryAliasDetector is a regex alias of the runtime library, it remembers the version of the library it was resolved from
+type libraryAliasDetector struct {
+	regexDetector
+	libraryVersion string
+}
+
+// LoadAliasLibrary swaps the runtime alias library for the provided aliases, keyed by their name.
+// Every regex is compiled beforehand: an alias failing to compile keeps its previously loaded regex, if any, and is reported back.
+// Aliases can't shadow the detectors compiled into the binary.
+func LoadAliasLibrary(aliases map[string]string) []error {
+	runtimeAliasLibrary.lock.Lock()
+	defer runtimeAliasLibrary.lock.Unlock()
+
+	loadErrors := []error{}
+	regexes := map[string]string{}
+	for name, regex := range aliases {
+		if _, found := lookupDetector(name); found {
+			loadErrors = append(loadErrors, fmt.Errorf("alias '%s' is a built-in detector and can't be overridden", name))
+			continue
+		}
+		if _, err := newRegexDetector(name, regex, libraryAliasConfidence); err != nil {
+			loadErrors = append(loadErrors, fmt.Errorf("alias '%s' is invalid: %w", name, err))
+			if previousRegex, found := runtimeAliasLibrary.regexes[name]; found {
+				regexes[name] = previousRegex
+			}
+			continue
+		}
+		regexes[name] = regex
+	}
+
+	version := aliasLibraryVersion(regexes)
+	detectors := map[string]Detector{}
+	for name, regex := range regexes {
+		detectors[name] = libraryAliasDetector{
+			regexDetector:  mustRegexDetector(name, regex, libraryAliasConfidence),
+			libraryVersion: version,
+		}
+	}
+	runtimeAliasLibrary.regexes, runtimeAliasLibrary.detectors, runtimeAliasLibrary.version = regexes, detectors, version
+	return loadErrors
+}
+
+// AliasLibraryVersion is the version of the runtime alias library currently loaded, empty when the library is empty
+func AliasLibraryVersion() string {
+	runtimeAliasLibrary.lock.RLock()
+	defer runtimeAliasLibrary.lock.RUnlock()
+	return runtimeAliasLibrary.version
+}
+
+func lookupLibraryAlias(name string) (Detector, bool) {
+	runtimeAliasLibrary.lock.RLock()
+	defer runtimeAliasLibrary.lock.RUnlock()
+	detector, found := runtimeAliasLibrary.detectors[name]
+	return detector, found
+}
+
+// aliasLibraryVersion is a digest of the content of the library, so that the same aliases always yield the same version
+func aliasLibraryVersion(regexes map[string]string) string {
+	if len(regexes) == 0 {
+		return ""
+	}
+	names := []string{}
+	for name := range regexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	digest := sha256.New()
+	for _, name := range names {
+		digest.Write([]byte(name + "=" + regexes[name] + "\n"))
+	}
+	return hex.EncodeToString(digest.Sum(nil))[:12]
+}
+
+// usedAliasLibraryVersion returns the version of the library the provided detectors were resolved from, if any of them was
+func usedAliasLibraryVersion(detectors []Detector) string {
+	for _, detector := range detectors {
+		if libraryAlias, ok := detector.(libraryAliasDetector); ok {
+			return libraryAlias.libraryVersion
+		}
+	}
+	return ""
+}
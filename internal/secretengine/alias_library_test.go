@@ -0,0 +1,66 @@
+package secretengine
+
+import (
+	"testing"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+// TestLoadAliasLibrary guards that an alias failing to compile keeps its previous regex, that built-in detectors can't be shadowed
+// and that the version only depends on the content of the library.
+func TestLoadAliasLibrary(t *testing.T) {
+	defer LoadAliasLibrary(map[string]string{})
+
+	if errs := LoadAliasLibrary(map[string]string{"internal-token": `itk_[a-z0-9]{8}`}); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	version := AliasLibraryVersion()
+	if version == "" {
+		t.Fatalf("a loaded library has no version")
+	}
+
+	errs := LoadAliasLibrary(map[string]string{"internal-token": `itk_[a-z0-9`, "email": `.*`})
+	if len(errs) != 2 {
+		t.Errorf("expected both the invalid regex and the shadowed detector to be reported, got %v", errs)
+	}
+	if AliasLibraryVersion() != version {
+		t.Errorf("the version changed from %s to %s although the library kept the same aliases", version, AliasLibraryVersion())
+	}
+	if _, found := lookupLibraryAlias("email"); found {
+		t.Errorf("the built-in email detector got shadowed")
+	}
+	detector, found := lookupLibraryAlias("internal-token")
+	if !found {
+		t.Fatalf("the alias lost its previous regex")
+	}
+	if findings, _ := detector.Detect("app.token", "itk_0a1b2c3d"); len(findings) == 0 {
+		t.Errorf("the alias kept its previous regex but doesn't match with it")
+	}
+
+	if errs := LoadAliasLibrary(map[string]string{}); len(errs) != 0 || AliasLibraryVersion() != "" {
+		t.Errorf("expected an empty library without version, got the version %q and the errors %v", AliasLibraryVersion(), errs)
+	}
+	if _, found := lookupLibraryAlias("internal-token"); found {
+		t.Errorf("the alias outlived the library it was removed from")
+	}
+}
+
+// TestScanReportsTheAliasLibraryVersion guards that a scan tells which version of the library its aliases were resolved from, and only when it used any
+func TestScanReportsTheAliasLibraryVersion(t *testing.T) {
+	defer LoadAliasLibrary(map[string]string{})
+	if errs := LoadAliasLibrary(map[string]string{"internal-token": `itk_[a-z0-9]{8}`}); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	redactor := utils.NewRedactor([]byte("test-key"))
+	payload := map[string]string{"app.token": "itk_0a1b2c3d"}
+
+	withAlias := getInvalidations(&sascomv1.ConsulKV{Spec: sascomv1.ConsulKVSpec{GuardAgainst: []string{"internal-token"}}}, payload, redactor)
+	if withAlias.aliasLibraryVersion != AliasLibraryVersion() || len(withAlias.invalidationsOutput) != 1 {
+		t.Errorf("expected a finding made with the library %s, got %d finding(s) made with %q", AliasLibraryVersion(), len(withAlias.invalidationsOutput), withAlias.aliasLibraryVersion)
+	}
+	withoutAlias := getInvalidations(&sascomv1.ConsulKV{Spec: sascomv1.ConsulKVSpec{GuardAgainst: []string{"email"}}}, payload, redactor)
+	if withoutAlias.aliasLibraryVersion != "" {
+		t.Errorf("a scan using no alias reported the library %s", withoutAlias.aliasLibraryVersion)
+	}
+}
@@ -0,0 +1,106 @@
+package secretengine
+
+import (
+	"encoding/json"
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"sort"
+	"time"
+)
+
+// BaselineConfigMapKey is the entry of a baseline ConfigMap holding the baseline
+const BaselineConfigMapKey = "baseline.json"
+
+// BaselineRule is the rule a baseline which couldn't be loaded is reported under
+const BaselineRule = "baseline"
+
+// Baseline lists the findings accepted as known, like a detect-secrets baseline.
+// Values are only known through their fingerprint, so a baseline only matches the operator sharing the redaction key it was generated with.
+type Baseline struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	Findings    []BaselineEntry `json:"findings"`
+}
+
+// BaselineEntry is a finding of a rule on the value with this fingerprint at this path, any other value at the same path gets flagged again
+type BaselineEntry struct {
+	Path        string `json:"path"`
+	RuleID      string `json:"rule_id"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// GenerateBaseline scans the payload with the rules of the ConsulKV and Rego policies, if any, and accepts every finding of the scan as known.
+// The detector errors are returned along with the baseline, which misses whatever the failing detectors would have found.
+func GenerateBaseline(consulKv *sascomv1.ConsulKV, configMapPayload map[string]string, pathToMetadata map[string]utils.ConsulMetadata, regoPolicyModules map[string]string, redactor utils.Redactor) (Baseline, []DetectorError) {
+	rules := compileRuleSet(consulKv)
+	scan := scanPayload(rules, configMapPayload, pathToMetadata, redactor, defaultScanWorkers(), nil)
+	invalidationsOutput, detectorErrors := scan.invalidationsOutput, scan.detectorErrors
+	if len(regoPolicyModules) != 0 {
+		// findings about absent keys have no value to fingerprint, so they can't be baselined
+		policyFindings, _, policyErrors := evaluateRegoPolicies(newRegoPolicyCache(), consulKv, regoPolicyModules, configMapPayload, pathToMetadata)
+		invalidationsOutput = applyExceptions(rules.exceptions, mergeFindings(invalidationsOutput, policyFindings, configMapPayload, redactor), time.Now())
+		detectorErrors = append(detectorErrors, policyErrors...)
+	}
+
+	baseline := Baseline{GeneratedAt: time.Now().UTC(), Findings: []BaselineEntry{}}
+	for _, inv := range invalidationsOutput {
+		ruleIDs := []string{}
+		for _, finding := range inv.Findings {
+			if !utils.ValueInSlice(finding.RuleID, ruleIDs) {
+				ruleIDs = append(ruleIDs, finding.RuleID)
+			}
+		}
+		sort.Strings(ruleIDs)
+		for _, ruleID := range ruleIDs {
+			baseline.Findings = append(baseline.Findings, BaselineEntry{Path: inv.Path, RuleID: ruleID, Fingerprint: inv.RedactedValue.Fingerprint})
+		}
+	}
+	return baseline, detectorErrors
+}
+
+// ParseBaseline parses the content of the BaselineConfigMapKey entry of a baseline ConfigMap
+func ParseBaseline(content string) (Baseline, error) {
+	if content == "" {
+		return Baseline{}, fmt.Errorf("the entry '%s' is missing or empty", BaselineConfigMapKey)
+	}
+	baseline := Baseline{}
+	if err := json.Unmarshal([]byte(content), &baseline); err != nil {
+		return Baseline{}, fmt.Errorf("failed to parse the baseline: %w", err)
+	}
+	for idx, entry := range baseline.Findings {
+		if entry.Path == "" || entry.RuleID == "" || entry.Fingerprint == "" {
+			return Baseline{}, fmt.Errorf("the finding %d of the baseline lacks either its path, its rule_id or its fingerprint", idx)
+		}
+	}
+	return baseline, nil
+}
+
+// applyBaseline drops the findings accepted by the baseline, along with the invalidations left without any finding, and counts the dropped findings
+func applyBaseline(baseline *Baseline, invalidationsOutput utils.InvalidationsOutput) (utils.InvalidationsOutput, int) {
+	if baseline == nil || len(baseline.Findings) == 0 {
+		return invalidationsOutput, 0
+	}
+	known := map[BaselineEntry]bool{}
+	for _, entry := range baseline.Findings {
+		known[entry] = true
+	}
+	output := utils.InvalidationsOutput{}
+	baselined := 0
+	for _, inv := range invalidationsOutput {
+		findings := []utils.Finding{}
+		for _, finding := range inv.Findings {
+			if known[BaselineEntry{Path: inv.Path, RuleID: finding.RuleID, Fingerprint: inv.RedactedValue.Fingerprint}] {
+				baselined++
+				continue
+			}
+			findings = append(findings, finding)
+		}
+		switch {
+		case len(findings) == len(inv.Findings):
+			output = append(output, inv)
+		case len(findings) != 0:
+			output = append(output, utils.NewInvalidation(inv.Path, inv.RedactedValue, findings))
+		}
+	}
+	return output, baselined
+}
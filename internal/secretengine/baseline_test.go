@@ -0,0 +1,81 @@
+package secretengine
+
+import (
+	"reflect"
+	"testing"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+// TestBaselineAcceptsKnownFindingsOnly guards that a generated baseline silences the findings it was generated from,
+// while a new value at a baselined path gets flagged again.
+func TestBaselineAcceptsKnownFindingsOnly(t *testing.T) {
+	redactor := utils.NewRedactor([]byte("test-key"))
+	consulKv := &sascomv1.ConsulKV{Spec: sascomv1.ConsulKVSpec{GuardAgainst: []string{"email"}}}
+	payload := map[string]string{"app.owner": "jane.doe@example.com", "app.support": "support-desk@example.org", "app.replicas": "3"}
+
+	baseline, detectorErrors := GenerateBaseline(consulKv, payload, nil, nil, redactor)
+	if len(detectorErrors) != 0 {
+		t.Fatalf("unexpected detector errors: %v", detectorErrors)
+	}
+	if len(baseline.Findings) != 2 {
+		t.Fatalf("expected a baseline of 2 findings, got %+v", baseline.Findings)
+	}
+
+	output, baselined := applyBaseline(&baseline, getInvalidations(consulKv, payload, redactor).invalidationsOutput)
+	if len(output) != 0 || baselined != 2 {
+		t.Errorf("expected the baseline to accept every finding, %d got baselined and %d remain: %s", baselined, len(output), output)
+	}
+
+	payload["app.owner"] = "john.doe@example.com"
+	output, baselined = applyBaseline(&baseline, getInvalidations(consulKv, payload, redactor).invalidationsOutput)
+	if len(output) != 1 || output[0].Path != "app.owner" || baselined != 1 {
+		t.Errorf("expected the new value of app.owner to be flagged again, %d got baselined and %d remain: %s", baselined, len(output), output)
+	}
+
+	output, baselined = applyBaseline(nil, getInvalidations(consulKv, payload, redactor).invalidationsOutput)
+	if len(output) != 2 || baselined != 0 {
+		t.Errorf("expected no baseline to accept nothing, %d got baselined and %d remain", baselined, len(output))
+	}
+}
+
+func TestParseBaseline(t *testing.T) {
+	for _, tc := range []struct {
+		content string
+		want    []BaselineEntry
+		wantErr bool
+	}{
+		{content: `{"generated_at":"2023-01-01T00:00:00Z","findings":[{"path":"app.token","rule_id":"github-token","fingerprint":"hmac-sha256:ab"}]}`,
+			want: []BaselineEntry{{Path: "app.token", RuleID: "github-token", Fingerprint: "hmac-sha256:ab"}}},
+		{content: `{"findings":[]}`, want: []BaselineEntry{}},
+		{content: "", wantErr: true},
+		{content: "{", wantErr: true},
+		{content: `{"findings":[{"path":"app.token","rule_id":"github-token"}]}`, wantErr: true},
+	} {
+		baseline, err := ParseBaseline(tc.content)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%q: expected an error = %v, got %v", tc.content, tc.wantErr, err)
+			continue
+		}
+		if !tc.wantErr && !reflect.DeepEqual(baseline.Findings, tc.want) {
+			t.Errorf("%q: expected %+v, got %+v", tc.content, tc.want, baseline.Findings)
+		}
+	}
+}
+
+// TestUnresolvedAliases guards that a guard naming an alias of a runtime library which isn't loaded is told apart from a raw regex
+func TestUnresolvedAliases(t *testing.T) {
+	defer LoadAliasLibrary(map[string]string{})
+	consulKv := &sascomv1.ConsulKV{Spec: sascomv1.ConsulKVSpec{GuardAgainst: []string{"email", "internal-token", "token-[0-9]+", "cel:size(value) > 10"}}}
+
+	if unresolved := UnresolvedAliases(consulKv); !reflect.DeepEqual(unresolved, []string{"internal-token"}) {
+		t.Errorf("expected internal-token to be unresolved, got %v", unresolved)
+	}
+	if errs := LoadAliasLibrary(map[string]string{"internal-token": `itk_[a-z0-9]{32}`}); len(errs) != 0 {
+		t.Fatalf("failed to load the alias library: %v", errs)
+	}
+	if unresolved := UnresolvedAliases(consulKv); len(unresolved) != 0 {
+		t.Errorf("expected every guard to resolve once the library is loaded, got %v", unresolved)
+	}
+}
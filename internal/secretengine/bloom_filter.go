@@ -0,0 +1,150 @@
+package secretengine
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// bloomFilterMagic starts every serialized bloom filter, it is followed by the amount of hash functions and the amount of bits, both big endian
+var bloomFilterMagic = []byte("CKVBLOOM")
+
+// maxBloomFilterBits bounds the size of a filter read from disk, 2^33 bits are a 1GiB filter
+const maxBloomFilterBits = 1 << 33
+
+// bloomFilter holds SHA-1 digests, its hash functions are derived from the digest itself by double hashing
+type bloomFilter struct {
+	hashes uint32
+	bits   uint64
+	data   []byte
+}
+
+func newBloomFilter(expectedItems int, falsePositiveRate float64) *bloomFilter {
+	if expectedItems < 1 {
+		expectedItems = 1
+	}
+	bits := uint64(math.Ceil(-float64(expectedItems) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	if bits < 64 {
+		bits = 64
+	}
+	hashes := uint32(math.Round(float64(bits) / float64(expectedItems) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+	return &bloomFilter{hashes: hashes, bits: bits, data: make([]byte, (bits+7)/8)}
+}
+
+func (f *bloomFilter) positions(digest [sha1.Size]byte) []uint64 {
+	h1 := binary.BigEndian.Uint64(digest[0:8])
+	h2 := binary.BigEndian.Uint64(digest[8:16]) | 1
+	positions := make([]uint64, f.hashes)
+	for i := range positions {
+		positions[i] = (h1 + uint64(i)*h2) % f.bits
+	}
+	return positions
+}
+
+func (f *bloomFilter) add(digest [sha1.Size]byte) {
+	for _, position := range f.positions(digest) {
+		f.data[position/8] |= 1 << (position % 8)
+	}
+}
+
+func (f *bloomFilter) mayContain(digest [sha1.Size]byte) bool {
+	for _, position := range f.positions(digest) {
+		if f.data[position/8]&(1<<(position%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *bloomFilter) writeTo(w io.Writer) error {
+	header := make([]byte, 12)
+	binary.BigEndian.PutUint32(header[0:4], f.hashes)
+	binary.BigEndian.PutUint64(header[4:12], f.bits)
+	for _, chunk := range [][]byte{bloomFilterMagic, header, f.data} {
+		if _, err := w.Write(chunk); err != nil {
+			return fmt.Errorf("failed to write the bloom filter: %w", err)
+		}
+	}
+	return nil
+}
+
+func isBloomFilter(content []byte) bool {
+	return bytes.HasPrefix(content, bloomFilterMagic)
+}
+
+func readBloomFilter(content []byte) (*bloomFilter, error) {
+	if !isBloomFilter(content) || len(content) < len(bloomFilterMagic)+12 {
+		return nil, fmt.Errorf("not a bloom filter")
+	}
+	header := content[len(bloomFilterMagic) : len(bloomFilterMagic)+12]
+	filter := &bloomFilter{
+		hashes: binary.BigEndian.Uint32(header[0:4]),
+		bits:   binary.BigEndian.Uint64(header[4:12]),
+		data:   content[len(bloomFilterMagic)+12:],
+	}
+	if filter.hashes == 0 || filter.bits == 0 || filter.bits > maxBloomFilterBits {
+		return nil, fmt.Errorf("bloom filter header is invalid: %d hash functions over %d bits", filter.hashes, filter.bits)
+	}
+	if uint64(len(filter.data)) != (filter.bits+7)/8 {
+		return nil, fmt.Errorf("bloom filter is truncated: %d bytes of data for %d bits", len(filter.data), filter.bits)
+	}
+	return filter, nil
+}
+
+// BuildBreachedBloomFilter turns a list of SHA-1 hashes, one per line and optionally followed by ':<count>' as in the Pwned Passwords dumps, into a bloom filter small enough to be mounted out of a ConfigMap
+func BuildBreachedBloomFilter(hashList io.Reader, output io.Writer, falsePositiveRate float64) (int, error) {
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return 0, fmt.Errorf("the false positive rate must lie between 0 and 1 exclusively")
+	}
+	digests, err := parseHashList(hashList)
+	if err != nil {
+		return 0, err
+	}
+	filter := newBloomFilter(len(digests), falsePositiveRate)
+	for _, digest := range digests {
+		filter.add(digest)
+	}
+	return len(digests), filter.writeTo(output)
+}
+
+// parseHashList reads SHA-1 hashes, one per line, blank lines and '#' comments are skipped
+func parseHashList(hashList io.Reader) ([][sha1.Size]byte, error) {
+	digests := [][sha1.Size]byte{}
+	scanner := bufio.NewScanner(hashList)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		digest, err := parseSha1(strings.SplitN(line, ":", 2)[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		digests = append(digests, digest)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the hash list: %w", err)
+	}
+	return digests, nil
+}
+
+func parseSha1(hexDigest string) ([sha1.Size]byte, error) {
+	digest := [sha1.Size]byte{}
+	decoded, err := hex.DecodeString(hexDigest)
+	if err != nil || len(decoded) != sha1.Size {
+		return digest, fmt.Errorf("'%s' is not a SHA-1 hash", hexDigest)
+	}
+	copy(digest[:], decoded)
+	return digest, nil
+}
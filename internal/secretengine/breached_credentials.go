@@ -0,0 +1,245 @@
+package secretengine
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	breachedCredentialsDetectorName = "breached-credentials"
+	breachedCredentialRuleID        = "breached-credential"
+	// values longer than that, or spanning several lines, are documents rather than passwords or tokens
+	maxBreachedCandidateLength = 256
+	// kAnonymityPrefixLength is the length of the SHA-1 prefix naming the range files of a k-anonymity corpus
+	kAnonymityPrefixLength = 5
+	// bloomHitConfidence is the confidence of a hit in a bloom filter, which may be a false positive
+	bloomHitConfidence = 0.6
+)
+
+// breachedCorpus tells whether a SHA-1 digest belongs to a credential known to be compromised
+type breachedCorpus interface {
+	contains(digest [sha1.Size]byte) (bool, error)
+	// exact tells whether a hit is a certainty rather than a likelihood
+	exact() bool
+}
+
+// hashListCorpus holds every hash of a flat hash list in memory
+type hashListCorpus map[[sha1.Size]byte]struct{}
+
+func (c hashListCorpus) contains(digest [sha1.Size]byte) (bool, error) {
+	_, found := c[digest]
+	return found, nil
+}
+
+func (c hashListCorpus) exact() bool {
+	return true
+}
+
+// bloomCorpus may report a value as breached which isn't, at the false positive rate the filter was built with, but never misses one
+type bloomCorpus struct {
+	filter *bloomFilter
+}
+
+func (c bloomCorpus) contains(digest [sha1.Size]byte) (bool, error) {
+	return c.filter.mayContain(digest), nil
+}
+
+func (c bloomCorpus) exact() bool {
+	return false
+}
+
+// kAnonymityCorpus is a directory of range files named after the first 5 hex characters of the hashes and listing their remaining 35 ones,
+// as laid out by the Pwned Passwords downloader. Only the range file of the value at hand is ever read.
+type kAnonymityCorpus struct {
+	directory string
+}
+
+func (c kAnonymityCorpus) exact() bool {
+	return true
+}
+
+func (c kAnonymityCorpus) contains(digest [sha1.Size]byte) (bool, error) {
+	hexDigest := strings.ToUpper(hex.EncodeToString(digest[:]))
+	prefix, suffix := hexDigest[:kAnonymityPrefixLength], hexDigest[kAnonymityPrefixLength:]
+	for _, name := range []string{prefix, prefix + ".txt"} {
+		content, err := os.ReadFile(filepath.Join(c.directory, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to read the range file %s: %w", name, err)
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			if strings.EqualFold(strings.SplitN(strings.TrimSpace(line), ":", 2)[0], suffix) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, nil
+}
+
+// breachedCorpusHolder holds the corpus loaded at runtime, along with what's needed to tell whether its source changed since
+type breachedCorpusHolder struct {
+	lock    *sync.RWMutex
+	path    string
+	modTime time.Time
+	corpus  breachedCorpus
+	version string
+}
+
+var runtimeBreachedCorpus = &breachedCorpusHolder{lock: &sync.RWMutex{}}
+
+func init() {
+	RegisterDetector(breachedCredentialsDetector{})
+}
+
+// LoadBreachedCorpus loads the corpus of breached credentials out of the provided path, which is either
+// a flat list of SHA-1 hashes, a bloom filter built by BuildBreachedBloomFilter or a directory of k-anonymity range files.
+// The previously loaded corpus remains in use when loading fails.
+func LoadBreachedCorpus(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat the breached credentials corpus: %w", err)
+	}
+	corpus, version, err := readBreachedCorpus(path, info)
+	if err != nil {
+		return err
+	}
+	runtimeBreachedCorpus.lock.Lock()
+	defer runtimeBreachedCorpus.lock.Unlock()
+	runtimeBreachedCorpus.path, runtimeBreachedCorpus.modTime = path, info.ModTime()
+	runtimeBreachedCorpus.corpus, runtimeBreachedCorpus.version = corpus, version
+	return nil
+}
+
+// WatchBreachedCorpus reloads the corpus whenever its source gets modified, as happens when the ConfigMap it is mounted from gets updated, until the context is done
+func WatchBreachedCorpus(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(path)
+			if err != nil {
+				fmt.Printf("failed to stat the breached credentials corpus, keeping the loaded one: %v\n", err)
+				continue
+			}
+			runtimeBreachedCorpus.lock.RLock()
+			unchanged := runtimeBreachedCorpus.path == path && runtimeBreachedCorpus.modTime.Equal(info.ModTime())
+			runtimeBreachedCorpus.lock.RUnlock()
+			if unchanged {
+				continue
+			}
+			if err := LoadBreachedCorpus(path); err != nil {
+				fmt.Printf("failed to reload the breached credentials corpus, keeping the loaded one: %v\n", err)
+			}
+		}
+	}
+}
+
+// BreachedCorpusVersion is the version of the corpus currently loaded, empty when none is
+func BreachedCorpusVersion() string {
+	runtimeBreachedCorpus.lock.RLock()
+	defer runtimeBreachedCorpus.lock.RUnlock()
+	return runtimeBreachedCorpus.version
+}
+
+func readBreachedCorpus(path string, info os.FileInfo) (breachedCorpus, string, error) {
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to list the range files of the breached credentials corpus: %w", err)
+		}
+		// reading every range file to digest them would defeat the purpose, so the listing stands for the content
+		digest := sha256.New()
+		for _, entry := range entries {
+			entryInfo, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			digest.Write([]byte(fmt.Sprintf("%s:%d:%d\n", entry.Name(), entryInfo.Size(), entryInfo.ModTime().UnixNano())))
+		}
+		return kAnonymityCorpus{directory: path}, hex.EncodeToString(digest.Sum(nil))[:12], nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read the breached credentials corpus: %w", err)
+	}
+	digest := sha256.Sum256(content)
+	version := hex.EncodeToString(digest[:])[:12]
+	if isBloomFilter(content) {
+		filter, err := readBloomFilter(content)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid breached credentials bloom filter: %w", err)
+		}
+		return bloomCorpus{filter: filter}, version, nil
+	}
+	digests, err := parseHashList(strings.NewReader(string(content)))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid breached credentials hash list: %w", err)
+	}
+	corpus := hashListCorpus{}
+	for _, digest := range digests {
+		corpus[digest] = struct{}{}
+	}
+	return corpus, version, nil
+}
+
+// breachedCredentialsDetector flags the values known to be compromised, whatever their format, with a critical severity.
+// A hit in a bloom filter is only likely to be one, it is reported with a lower confidence and severity.
+type breachedCredentialsDetector struct{}
+
+func (d breachedCredentialsDetector) Name() string {
+	return breachedCredentialsDetectorName
+}
+
+// Version is the one of the corpus, so that the status tells which corpus the values were checked against
+func (d breachedCredentialsDetector) Version() string {
+	if version := BreachedCorpusVersion(); version != "" {
+		return version
+	}
+	return "none"
+}
+
+func (d breachedCredentialsDetector) Detect(_ string, value string) ([]utils.Finding, error) {
+	runtimeBreachedCorpus.lock.RLock()
+	corpus := runtimeBreachedCorpus.corpus
+	runtimeBreachedCorpus.lock.RUnlock()
+	if corpus == nil {
+		return nil, fmt.Errorf("no breached credentials corpus is loaded")
+	}
+
+	candidate := strings.TrimSpace(value)
+	if candidate == "" || len(candidate) > maxBreachedCandidateLength || strings.ContainsAny(candidate, "\r\n") {
+		return nil, nil
+	}
+	breached, err := corpus.contains(sha1.Sum([]byte(candidate)))
+	if err != nil || !breached {
+		return nil, err
+	}
+	start := strings.Index(value, candidate)
+	finding := utils.Finding{
+		RuleID:     breachedCredentialRuleID,
+		Confidence: 1,
+		// a credential out in the wild is critical, however harmless its format looks
+		Severity: utils.CriticalSeverity,
+		Span:     utils.Span{Start: start, End: start + len(candidate)},
+	}
+	if !corpus.exact() {
+		finding.Confidence, finding.Severity = bloomHitConfidence, utils.HighSeverity
+	}
+	return []utils.Finding{finding}, nil
+}
@@ -0,0 +1,139 @@
+package secretengine
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+func sha1Hex(value string) string {
+	digest := sha1.Sum([]byte(value))
+	return strings.ToUpper(hex.EncodeToString(digest[:]))
+}
+
+// TestBloomFilterRoundtrip guards that a serialized filter reads back to one holding the same values, at about the false positive rate it was built for
+func TestBloomFilterRoundtrip(t *testing.T) {
+	hashList := strings.Builder{}
+	hashList.WriteString("# breached passwords\n\n")
+	for idx := 0; idx < 1000; idx++ {
+		hashList.WriteString(fmt.Sprintf("%s:%d\n", sha1Hex(fmt.Sprintf("password-%d", idx)), idx))
+	}
+	serialized := &bytes.Buffer{}
+	count, err := BuildBreachedBloomFilter(strings.NewReader(hashList.String()), serialized, 0.01)
+	if err != nil || count != 1000 {
+		t.Fatalf("expected 1000 hashes to be added, got %d and %v", count, err)
+	}
+
+	filter, err := readBloomFilter(serialized.Bytes())
+	if err != nil {
+		t.Fatalf("failed to read the filter back: %v", err)
+	}
+	// m = -n ln(p) / ln(2)^2 and k = m/n ln(2)
+	if filter.bits != 9586 || filter.hashes != 7 {
+		t.Errorf("unexpected sizing: %d hash functions over %d bits", filter.hashes, filter.bits)
+	}
+	for idx := 0; idx < 1000; idx++ {
+		if !filter.mayContain(sha1.Sum([]byte(fmt.Sprintf("password-%d", idx)))) {
+			t.Fatalf("the filter lost password-%d", idx)
+		}
+	}
+	falsePositives := 0
+	for idx := 0; idx < 10000; idx++ {
+		if filter.mayContain(sha1.Sum([]byte(fmt.Sprintf("unknown-%d", idx)))) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / 10000; math.Abs(rate-0.01) > 0.01 {
+		t.Errorf("the false positive rate drifted to %v", rate)
+	}
+}
+
+func TestReadBloomFilterRefusesBrokenFilters(t *testing.T) {
+	serialized := &bytes.Buffer{}
+	if err := newBloomFilter(100, 0.01).writeTo(serialized); err != nil {
+		t.Fatalf("failed to write the filter: %v", err)
+	}
+	valid := serialized.Bytes()
+	zeroHashes := append([]byte{}, valid...)
+	copy(zeroHashes[len(bloomFilterMagic):], []byte{0, 0, 0, 0})
+
+	for name, content := range map[string][]byte{
+		"truncated":      valid[:len(valid)-1],
+		"trailing bytes": append(append([]byte{}, valid...), 0),
+		"header only":    valid[:len(bloomFilterMagic)+6],
+		"no magic":       valid[len(bloomFilterMagic):],
+		"zero hashes":    zeroHashes,
+	} {
+		if _, err := readBloomFilter(content); err == nil {
+			t.Errorf("%s: expected the filter to be refused", name)
+		}
+	}
+}
+
+func TestKAnonymityCorpus(t *testing.T) {
+	directory := t.TempDir()
+	breached := sha1Hex("hunter2")
+	// the range files of the downloader list the suffixes in upper case, followed by the amount of times they were seen
+	rangeFile := fmt.Sprintf("0123456789ABCDEF0123456789ABCDEF012:3\r\n%s:42\r\n", breached[kAnonymityPrefixLength:])
+	if err := os.WriteFile(filepath.Join(directory, breached[:kAnonymityPrefixLength]+".txt"), []byte(rangeFile), 0o600); err != nil {
+		t.Fatalf("failed to write the range file: %v", err)
+	}
+
+	corpus := kAnonymityCorpus{directory: directory}
+	for value, want := range map[string]bool{"hunter2": true, "hunter3": false} {
+		found, err := corpus.contains(sha1.Sum([]byte(value)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if found != want {
+			t.Errorf("%s: expected %v, got %v", value, want, found)
+		}
+	}
+}
+
+// TestBreachedCredentialsConfidence guards that a hit in a bloom filter, which may be a false positive, isn't reported as a certainty
+func TestBreachedCredentialsConfidence(t *testing.T) {
+	defer func(previous breachedCorpusHolder) { *runtimeBreachedCorpus = previous }(*runtimeBreachedCorpus)
+	directory := t.TempDir()
+	hashListPath := filepath.Join(directory, "hashes.txt")
+	if err := os.WriteFile(hashListPath, []byte(sha1Hex("hunter2")+"\n"), 0o600); err != nil {
+		t.Fatalf("failed to write the hash list: %v", err)
+	}
+	bloomPath := filepath.Join(directory, "hashes.bloom")
+	bloomFile, err := os.Create(bloomPath)
+	if err != nil {
+		t.Fatalf("failed to create the bloom filter: %v", err)
+	}
+	if _, err := BuildBreachedBloomFilter(strings.NewReader(sha1Hex("hunter2")), bloomFile, 0.01); err != nil {
+		t.Fatalf("failed to build the bloom filter: %v", err)
+	}
+	bloomFile.Close()
+
+	for _, tc := range []struct {
+		path       string
+		confidence float64
+		severity   utils.Severity
+	}{
+		{hashListPath, 1, utils.CriticalSeverity},
+		{bloomPath, bloomHitConfidence, utils.HighSeverity},
+	} {
+		if err := LoadBreachedCorpus(tc.path); err != nil {
+			t.Fatalf("failed to load %s: %v", tc.path, err)
+		}
+		findings, err := breachedCredentialsDetector{}.Detect("app.password", " hunter2\n")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(findings) != 1 || findings[0].Confidence != tc.confidence || findings[0].Severity != tc.severity || findings[0].Span != (utils.Span{Start: 1, End: 8}) {
+			t.Errorf("%s: unexpected findings %+v", filepath.Base(tc.path), findings)
+		}
+	}
+}
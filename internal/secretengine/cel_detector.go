@@ -0,0 +1,60 @@
+package secretengine
+
+import (
+	"fmt"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/celguard"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"strings"
+)
+
+// celDetector flags the values for which a CEL guard evaluates to true, the finding spans the whole value
+type celDetector struct {
+	name    string
+	program celguard.Program
+}
+
+func newCelDetector(guard string, expression string) (celDetector, error) {
+	program, err := celguard.Compile(expression)
+	if err != nil {
+		return celDetector{}, fmt.Errorf("CEL guard failed to get compiled: %w", err)
+	}
+	return celDetector{name: guard, program: program}, nil
+}
+
+func (d celDetector) Name() string {
+	return d.name
+}
+
+func (d celDetector) Detect(path string, value string) ([]utils.Finding, error) {
+	return d.DetectWithMetadata(path, value, utils.ConsulMetadata{})
+}
+
+func (d celDetector) DetectWithMetadata(path string, value string, metadata utils.ConsulMetadata) ([]utils.Finding, error) {
+	matches, err := d.program.Matches(celguard.Input{
+		Key:      strings.ReplaceAll(path, ".", "/"),
+		Path:     path,
+		Value:    value,
+		Metadata: metadataFields(metadata),
+	})
+	if err != nil || !matches {
+		return nil, err
+	}
+	return []utils.Finding{{
+		RuleID: d.name,
+		// the author of the ConsulKV vouches for the guard just like for a raw regex
+		Confidence: rawRegexConfidence,
+		Severity:   utils.MediumSeverity,
+		Span:       utils.Span{Start: 0, End: len(value)},
+	}}, nil
+}
+
+// metadataFields is the Consul metadata of a key as exposed to the expressions and policies
+func metadataFields(metadata utils.ConsulMetadata) map[string]interface{} {
+	return map[string]interface{}{
+		"create_index": int64(metadata.CreateIndex),
+		"modify_index": int64(metadata.ModifyIndex),
+		"lock_index":   int64(metadata.LockIndex),
+		"flags":        int64(metadata.Flags),
+		"session":      metadata.Session,
+	}
+}
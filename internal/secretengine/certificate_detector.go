@@ -0,0 +1,197 @@
+package secretengine
+
+import (
+	"bytes"
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	certificatesDetectorName = "certificates"
+
+	defaultCertificateExpiryWindow = 30 * 24 * time.Hour
+	defaultMinRSAKeyBits           = 2048
+	defaultMinECDSAKeyBits         = 256
+	// maxReportedCertificates bounds the certificates listed in the status, the soonest to expire are kept
+	maxReportedCertificates = 50
+)
+
+var pemCertificateHeader = []byte("-----BEGIN CERTIFICATE-----")
+
+func init() {
+	RegisterDetector(newCertificateDetector(nil))
+}
+
+// certificateDetector parses the PEM certificates of a value, chains included, and flags the expired, expiring, self-signed or weak-key ones
+type certificateDetector struct {
+	expiryWindow    time.Duration
+	allowSelfSigned bool
+	minRSAKeyBits   int
+	minECDSAKeyBits int
+	now             func() time.Time
+}
+
+// pemCertificate is a certificate along with the span of its PEM block in the value
+type pemCertificate struct {
+	certificate *x509.Certificate
+	span        utils.Span
+	// leaf is the first certificate of a chain, the following ones being its issuers
+	leaf bool
+}
+
+func newCertificateDetector(spec *sascomv1.CertificatesDetectorSpec) certificateDetector {
+	detector := certificateDetector{
+		expiryWindow:    defaultCertificateExpiryWindow,
+		minRSAKeyBits:   defaultMinRSAKeyBits,
+		minECDSAKeyBits: defaultMinECDSAKeyBits,
+		now:             time.Now,
+	}
+	if spec == nil {
+		return detector
+	}
+	if spec.ExpiryWindow != nil && spec.ExpiryWindow.Duration > 0 {
+		detector.expiryWindow = spec.ExpiryWindow.Duration
+	}
+	detector.allowSelfSigned = spec.AllowSelfSigned
+	if spec.MinRSAKeyBits > 0 {
+		detector.minRSAKeyBits = spec.MinRSAKeyBits
+	}
+	if spec.MinECDSAKeyBits > 0 {
+		detector.minECDSAKeyBits = spec.MinECDSAKeyBits
+	}
+	return detector
+}
+
+func (d certificateDetector) Name() string {
+	return certificatesDetectorName
+}
+
+func (d certificateDetector) Configure(spec *sascomv1.DetectorsSpec) (Detector, error) {
+	if spec == nil || spec.Certificates == nil {
+		return d, nil
+	}
+	return newCertificateDetector(spec.Certificates), nil
+}
+
+func (d certificateDetector) Detect(_ string, value string) ([]utils.Finding, error) {
+	findings := []utils.Finding{}
+	now := d.now()
+	for _, parsed := range parsePemCertificates(value) {
+		certificate := parsed.certificate
+		switch {
+		case now.After(certificate.NotAfter):
+			findings = append(findings, utils.Finding{RuleID: "certificate-expired", Confidence: 1, Severity: utils.CriticalSeverity, Category: utils.CertificateCategory, Span: parsed.span})
+		case now.Add(d.expiryWindow).After(certificate.NotAfter):
+			findings = append(findings, utils.Finding{RuleID: "certificate-expiring", Confidence: 1, Severity: utils.HighSeverity, Category: utils.CertificateCategory, Span: parsed.span})
+		}
+		// a chain legitimately ends with a self-signed root, only a self-signed leaf is worth flagging
+		if parsed.leaf && !d.allowSelfSigned && isSelfSigned(certificate) {
+			findings = append(findings, utils.Finding{RuleID: "certificate-self-signed", Confidence: 0.9, Severity: utils.MediumSeverity, Category: utils.CertificateCategory, Span: parsed.span})
+		}
+		if d.hasWeakKey(certificate) {
+			findings = append(findings, utils.Finding{RuleID: "certificate-weak-key", Confidence: 1, Severity: utils.HighSeverity, Category: utils.CertificateCategory, Span: parsed.span})
+		}
+	}
+	return findings, nil
+}
+
+func (d certificateDetector) hasWeakKey(certificate *x509.Certificate) bool {
+	switch key := certificate.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return key.N.BitLen() < d.minRSAKeyBits
+	case *ecdsa.PublicKey:
+		return key.Curve.Params().BitSize < d.minECDSAKeyBits
+	case *dsa.PublicKey:
+		return true
+	default:
+		return false
+	}
+}
+
+// inventory lists the certificates found in any of the layers scanned out of a value, along with the moment the findings about them will change
+func (d certificateDetector) inventory(path string, layers []string) ([]sascomv1.CertificateStatus, time.Time) {
+	certificates := []sascomv1.CertificateStatus{}
+	seen := map[string]bool{}
+	rescanAfter := time.Time{}
+	now := d.now()
+	for _, layer := range layers {
+		for _, parsed := range parsePemCertificates(layer) {
+			// a certificate stored as is also shows up in the leaves of the document holding it
+			if seen[string(parsed.certificate.Raw)] {
+				continue
+			}
+			seen[string(parsed.certificate.Raw)] = true
+			certificates = append(certificates, sascomv1.CertificateStatus{
+				Path:     path,
+				Subject:  parsed.certificate.Subject.String(),
+				NotAfter: metav1.NewTime(parsed.certificate.NotAfter),
+			})
+			for _, transition := range []time.Time{parsed.certificate.NotAfter.Add(-d.expiryWindow), parsed.certificate.NotAfter} {
+				if transition.After(now) && (rescanAfter.IsZero() || transition.Before(rescanAfter)) {
+					rescanAfter = transition
+				}
+			}
+		}
+	}
+	return certificates, rescanAfter
+}
+
+// parsePemCertificates parses every PEM certificate of the value, blocks failing to parse are skipped.
+// Certificates directly following one another make up a chain, whose first certificate is the leaf.
+func parsePemCertificates(value string) []pemCertificate {
+	certificates := []pemCertificate{}
+	remaining := []byte(value)
+	offset := 0
+	previousEnd := -1
+	for {
+		start := bytes.Index(remaining, pemCertificateHeader)
+		if start == -1 {
+			return certificates
+		}
+		block, rest := pem.Decode(remaining[start:])
+		if block == nil {
+			// a broken block, skip past its header and look for the next one
+			offset += start + len(pemCertificateHeader)
+			remaining = remaining[start+len(pemCertificateHeader):]
+			continue
+		}
+		consumed := len(remaining[start:]) - len(rest)
+		span := utils.Span{Start: offset + start, End: offset + start + consumed}
+		if block.Type == "CERTIFICATE" {
+			if certificate, err := x509.ParseCertificate(block.Bytes); err == nil {
+				leaf := previousEnd == -1 || strings.TrimSpace(value[previousEnd:span.Start]) != ""
+				certificates = append(certificates, pemCertificate{certificate: certificate, span: span, leaf: leaf})
+				previousEnd = span.End
+			}
+		}
+		offset += start + consumed
+		remaining = rest
+	}
+}
+
+func isSelfSigned(certificate *x509.Certificate) bool {
+	return bytes.Equal(certificate.RawIssuer, certificate.RawSubject) && certificate.CheckSignatureFrom(certificate) == nil
+}
+
+// sortedCertificates orders the certificates by expiry, then path, and keeps the soonest to expire
+func sortedCertificates(certificates []sascomv1.CertificateStatus) []sascomv1.CertificateStatus {
+	sort.SliceStable(certificates, func(i, j int) bool {
+		if !certificates[i].NotAfter.Equal(&certificates[j].NotAfter) {
+			return certificates[i].NotAfter.Before(&certificates[j].NotAfter)
+		}
+		return certificates[i].Path < certificates[j].Path
+	})
+	if len(certificates) > maxReportedCertificates {
+		return certificates[:maxReportedCertificates]
+	}
+	return certificates
+}
@@ -0,0 +1,103 @@
+package secretengine
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+func selfSignedPem(t *testing.T, notAfter time.Time) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate a key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "app.example.com"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+		// a certificate checks its own signature only when it may sign certificates
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create a certificate: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+// TestCertificateFindingsAreAdvisory guards that the certificate findings carry their own category, so that the adaptation engine reports them
+// without deleting the certificates.
+func TestCertificateFindingsAreAdvisory(t *testing.T) {
+	now := time.Now()
+	detector := newCertificateDetector(nil)
+	detector.now = func() time.Time { return now }
+
+	findings, err := detector.Detect("app.cert", selfSignedPem(t, now.Add(-time.Hour)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rules := map[string]bool{}
+	for _, finding := range findings {
+		rules[finding.RuleID] = true
+		if finding.Category != utils.CertificateCategory {
+			t.Errorf("finding %s carries the category %q", finding.RuleID, finding.Category)
+		}
+	}
+	if !rules["certificate-expired"] || !rules["certificate-self-signed"] {
+		t.Errorf("expected the certificate to be flagged as expired and self-signed, got %v", rules)
+	}
+}
+
+// TestInventoryCoversEveryScannedLayer guards that certificates stored encoded or inside a structured value are inventoried,
+// so that their scan result goes stale once they start expiring.
+func TestInventoryCoversEveryScannedLayer(t *testing.T) {
+	now := time.Now()
+	detector := newCertificateDetector(nil)
+	detector.now = func() time.Time { return now }
+	notAfter := now.Add(90 * 24 * time.Hour).Truncate(time.Second)
+	certificate := selfSignedPem(t, notAfter)
+	document, err := json.Marshal(map[string]map[string]string{"tls": {"cert": certificate}})
+	if err != nil {
+		t.Fatalf("failed to marshal the document: %v", err)
+	}
+	limits := decodingLimitsFor(nil)
+
+	for name, tc := range map[string]struct {
+		value      string
+		scanLeaves bool
+	}{
+		"raw":     {certificate, false},
+		"base64":  {base64.StdEncoding.EncodeToString([]byte(certificate)), false},
+		"leaf":    {string(document), true},
+		"twice":   {certificate + certificate, false},
+		"nothing": {"plain value", true},
+	} {
+		certificates, rescanAfter := detector.inventory("app.cert", scannedLayers(tc.value, tc.scanLeaves, limits))
+		if name == "nothing" {
+			if len(certificates) != 0 || !rescanAfter.IsZero() {
+				t.Errorf("%s: unexpected inventory %v, rescan after %v", name, certificates, rescanAfter)
+			}
+			continue
+		}
+		if len(certificates) != 1 {
+			t.Errorf("%s: expected 1 certificate, got %v", name, certificates)
+			continue
+		}
+		if want := notAfter.Add(-defaultCertificateExpiryWindow); !rescanAfter.Equal(want) {
+			t.Errorf("%s: expected a rescan after %v, got %v", name, want, rescanAfter)
+		}
+	}
+}
@@ -6,35 +6,43 @@ import (
 	"github.com/yashvardhan-kukreja/consulkv-commander/internal/adaptationengine"
 	"github.com/yashvardhan-kukreja/consulkv-commander/internal/knowledgebase"
 	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-)
-
-var (
-	predefinedRegexAliases = map[string]string{
-		"email":           "^[\\w-\\.]+@([\\w-]+\\.)+[\\w-]{2,4}$",
-		"contact":         "^\\s*(?:\\+?(\\d{1,3}))?([-. (]*(\\d{3})[-. )]*)?((\\d{3})[-. ]*(\\d{2,4})(?:[-.x ]*(\\d+))?)\\s*$",
-		"bitcoin-address": "([13][a-km-zA-HJ-NP-Z0-9]{26,33})",
-		"badwords":        "\\b(?:(?:ass+(?:\\s+)?|i+(?:\\s+)?|butt+(?:\\s+)?|mo(?:(?:m|t|d)h?(?:e|a)?r?)(?:\\s+)?)?f(?:(?:\\s+)?u+)?(?:(?:\\s+)?c+)?(?:(?:\\s+)?k+)?(?:(?:e|a)(?:r+)?|i(?:n(?:g)?)?)?(?:s+)?(?:\\s+)?(?:hole|head|(?:yo?)?u?)?)+\\b",
-		"html-tags":       "</?\\w+((\\s+\\w+(\\s*=\\s*(?:\".*?\"|'.*?'|[^'\">\\s]+))?)+\\s*|\\s*)/?>",
-		"sql-injection":   "\"((SELECT|DELETE|UPDATE|INSERT INTO) (\\*|[A-Z0-9_]+) (FROM) ([A-Z0-9_]+))( (WHERE) ([A-Z0-9_]+) (=|<|>|>=|<=|==|!=) (\\?|\\$[A-Z]{1}[A-Z_]+)( (AND) ([A-Z0-9_]+) (=|<|>|>=|<=|==|!=) (\\?))?)?\"",
-	}
+	"sort"
+	"sync"
+	"time"
 )
 
 type Client struct {
 	invalidationsTrackingContext *knowledgebase.KnowledgeBaseContext
 	advisoryLock                 *AdvisoryLock
 	adaptationEngineClient       adaptationengine.Client
+	detectorErrorPolicies        map[sascomv1.QoSType]sascomv1.DetectorErrorPolicy
+	redactor                     utils.Redactor
+	ruleSets                     *ruleSetCache
+	scanResults                  *scanCache
+	scanWorkers                  int
+	regoPolicies                 *regoPolicyCache
 }
 
-func NewClient(invalidationsTrackingContext *knowledgebase.KnowledgeBaseContext, adaptationEngineClient adaptationengine.Client) Client {
+func NewClient(invalidationsTrackingContext *knowledgebase.KnowledgeBaseContext, adaptationEngineClient adaptationengine.Client, detectorErrorPolicies map[sascomv1.QoSType]sascomv1.DetectorErrorPolicy, redactor utils.Redactor, scanWorkers int) Client {
+	if scanWorkers <= 0 {
+		scanWorkers = defaultScanWorkers()
+	}
 	return Client{
 		invalidationsTrackingContext,
 		NewAdvisoryLock(),
 		adaptationEngineClient,
+		detectorErrorPolicies,
+		redactor,
+		newRuleSetCache(),
+		newScanCache(),
+		scanWorkers,
+		newRegoPolicyCache(),
 	}
 }
 
-func (s Client) Run(item *sascomv1.ConsulKV, configMapPayloadUntilNow map[string]string, pathToWeights map[string]int) (map[string]string, error) {
+func (s Client) Run(item *sascomv1.ConsulKV, configMapPayloadUntilNow map[string]string, pathToWeights map[string]int, pathToMetadata map[string]utils.ConsulMetadata, previousConfigMapPayload map[string]string, regoPolicyModules map[string]string, baseline *Baseline, baselineErr error) (map[string]string, error) {
 	consulKvKey := client.ObjectKeyFromObject(item).String()
 
 	s.advisoryLock.Init(consulKvKey)
@@ -42,51 +50,226 @@ func (s Client) Run(item *sascomv1.ConsulKV, configMapPayloadUntilNow map[string
 	s.advisoryLock.Lock(consulKvKey)
 	defer s.advisoryLock.Unlock(consulKvKey)
 
-	invalidationsOutput := getInvalidations(item, configMapPayloadUntilNow)
+	rules := s.ruleSets.get(item)
+	// the advisory lock held above makes this ConsulKV the only user of its scan cache
+	scan := scanPayload(rules, configMapPayloadUntilNow, pathToMetadata, s.redactor, s.scanWorkers, s.scanResults.forConsulKV(consulKvKey, rules.version, pathToMetadata))
+	invalidationsOutput, detectorErrors := scan.invalidationsOutput, scan.detectorErrors
+	now := time.Now()
+	absentKeys := []absentKeyFinding{}
+	if len(regoPolicyModules) != 0 {
+		policyFindings, policyAbsentKeys, policyErrors := evaluateRegoPolicies(s.regoPolicies, item, regoPolicyModules, configMapPayloadUntilNow, pathToMetadata)
+		invalidationsOutput = applyExceptions(rules.exceptions, mergeFindings(invalidationsOutput, policyFindings, configMapPayloadUntilNow, s.redactor), now)
+		absentKeys = exceptAbsentKeys(rules.exceptions, policyAbsentKeys, now)
+		detectorErrors = append(detectorErrors, policyErrors...)
+	}
+	if baselineErr != nil {
+		// the findings the baseline accepts can't be told apart from the others, which fails every path like a broken rule would
+		detectorErrors = append(detectorErrors, DetectorError{Rule: BaselineRule, Err: baselineErr})
+	}
+	setPolicyKeysPresentCondition(item, len(regoPolicyModules) != 0, absentKeys)
+	s.notifyExpiredExceptions(item, rules.exceptions, now)
+	invalidationsOutput, item.Status.BaselinedFindings = applyBaseline(baseline, invalidationsOutput)
+	invalidationsOutput, err := s.adaptationEngineClient.TrackSensitiveFindings(item, invalidationsOutput, configMapPayloadUntilNow, unevaluatedPaths(configMapPayloadUntilNow, scan.scannedPaths, detectorErrors))
+	if err != nil {
+		return nil, fmt.Errorf("failed to track the sensitive findings: %w", err)
+	}
+	item.Status.DetectorVersions = scan.detectorVersions
+	item.Status.AliasLibraryVersion = scan.aliasLibraryVersion
+	item.Status.Certificates = sortedCertificates(scan.certificates)
+	recordCertificateExpiries(consulKvKey, scan.certificates)
+
+	validation := validateValues(rules.validations, configMapPayloadUntilNow, s.redactor)
+	setValuesValidCondition(item, validation)
+
+	policy := s.detectorErrorPolicy(item)
+	setDetectorsHealthyCondition(item, detectorErrors, policy)
+	s.adaptationEngineClient.ReportPolicyResults(item, policyReportResults(item, evaluatedRules(rules, len(regoPolicyModules) != 0), append(append(utils.InvalidationsOutput{}, invalidationsOutput...), validation.failures...), absentKeys, detectorErrors))
+
+	if len(detectorErrors) != 0 && policy == sascomv1.HaltSync {
+		return nil, fmt.Errorf("%w: %d detector error(s) while scanning %s", ErrSyncHalted, len(detectorErrors), consulKvKey)
+	}
+	if policy == sascomv1.FailClosed && hasRuleWideErrors(detectorErrors) && previousConfigMapPayload == nil {
+		// a rule failing as a whole leaves every value unevaluated, and without any configmap synced before none has a fully evaluated value to fall back to
+		return nil, fmt.Errorf("%w: a rule failed as a whole while scanning %s under the fail-closed policy", ErrSyncHalted, consulKvKey)
+	}
 
 	sanitizedConfigMapPayload, err := s.adaptationEngineClient.Adapt(item, invalidationsOutput, configMapPayloadUntilNow, pathToWeights)
 	if err != nil {
 		return nil, fmt.Errorf("failed to adapt the system: %w", err)
 	}
 
+	if len(detectorErrors) != 0 && policy == sascomv1.FailClosed {
+		// values which could not be fully evaluated are protected, but never handed over to self-heal for deletion
+		sanitizedConfigMapPayload = protectErroredPaths(sanitizedConfigMapPayload, erroredPaths(detectorErrors, scan.scannedPaths), previousConfigMapPayload)
+	}
+
+	validationFailures := pendingValidationFailures(validation.failures, configMapPayloadUntilNow, sanitizedConfigMapPayload)
+	sanitizedConfigMapPayload = s.adaptationEngineClient.AdaptValidationFailures(item, validationFailures, validation.pathToPolicy, sanitizedConfigMapPayload, previousConfigMapPayload)
 	return sanitizedConfigMapPayload, nil
 }
 
-func getInvalidations(consulKv *sascomv1.ConsulKV, configMapPayload map[string]string) utils.InvalidationsOutput {
+// Forget drops whatever is cached about a ConsulKV, to be called once it is deleted
+func (s Client) Forget(consulKvKey string) {
+	s.ruleSets.evict(consulKvKey)
+	s.scanResults.evict(consulKvKey)
+	s.regoPolicies.evict(consulKvKey)
+}
+
+// scanOutcome is everything a scan of a KV group found out, matches and detector errors being distinct outcomes
+type scanOutcome struct {
+	invalidationsOutput utils.InvalidationsOutput
+	detectorErrors      []DetectorError
+	scannedPaths        []string
+	detectorVersions    []string
+	aliasLibraryVersion string
+	certificates        []sascomv1.CertificateStatus
+}
+
+// unevaluatedPaths are the paths of the payload which either weren't scanned or whose scan failed, what was found on them before may still hold
+func unevaluatedPaths(configMapPayload map[string]string, scannedPaths []string, detectorErrors []DetectorError) []string {
+	paths := append([]string{}, erroredPaths(detectorErrors, scannedPaths)...)
+	scanned := map[string]bool{}
+	for _, path := range scannedPaths {
+		scanned[path] = true
+	}
+	for path := range configMapPayload {
+		if !scanned[path] {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// notifyExpiredExceptions notifies once about every exception which expired, the status keeping track of the notified ones
+func (s Client) notifyExpiredExceptions(item *sascomv1.ConsulKV, exceptions []exception, now time.Time) {
+	newlyExpired, expiredIDs := newlyExpiredExceptions(exceptions, item.Status.ExpiredExceptions, now)
+	if len(newlyExpired) != 0 {
+		s.adaptationEngineClient.NotifyExpiredExceptions(item, newlyExpired)
+	}
+	item.Status.ExpiredExceptions = expiredIDs
+}
+
+func getInvalidations(consulKv *sascomv1.ConsulKV, configMapPayload map[string]string, redactor utils.Redactor) scanOutcome {
+	return scanPayload(compileRuleSet(consulKv), configMapPayload, nil, redactor, defaultScanWorkers(), nil)
+}
+
+// pathScan is the outcome of the scan of a single path
+type pathScan struct {
+	invalidation   *utils.Invalidation
+	detectorErrors []DetectorError
+	certificates   []sascomv1.CertificateStatus
+	// rescanAfter is when the result goes stale even though the value doesn't change, like once a certificate expires
+	rescanAfter time.Time
+	// incomplete tells a detector failed on the path without reporting it, the path may only look clean
+	incomplete bool
+}
+
+// scanPayload scans every path of the payload over a bounded pool of workers, the invalidations come out sorted by path.
+// The paths and findings covered by an active exception are left out.
+// With a cache, only the paths whose value was modified since their last scan get scanned again.
+func scanPayload(rules ruleSet, configMapPayload map[string]string, pathToMetadata map[string]utils.ConsulMetadata, redactor utils.Redactor, workers int, cache *consulKvScanCache) scanOutcome {
 	invalidationsOutput := []utils.Invalidation{}
-	if len(consulKv.Spec.GuardAgainst) == 0 {
-		return invalidationsOutput
+	scannedPaths := []string{}
+	if rules.isEmpty() {
+		return scanOutcome{invalidationsOutput: invalidationsOutput, scannedPaths: scannedPaths}
 	}
 
-	validationRegexes := []string{}
-	for _, guard := range consulKv.Spec.GuardAgainst {
-		validationRegex := guard
-		regexAlias, found := predefinedRegexAliases[guard]
-		if found {
-			validationRegex = regexAlias
+	now := time.Now()
+	for pathToValidate := range configMapPayload {
+		fingerprint := func() string {
+			return redactor.Fingerprint(configMapPayload[pathToValidate])
 		}
-		validationRegexes = append(validationRegexes, validationRegex)
+		if isPathExcepted(rules.exceptions, pathToValidate, fingerprint, now) {
+			continue
+		}
+		scannedPaths = append(scannedPaths, pathToValidate)
 	}
+	sort.Strings(scannedPaths)
 
-	for pathToValidate, valueToValidate := range configMapPayload {
-		// skip validation is pathToValidate was ultimately found to be whitelisted
-		if isPathWhitelisted(pathToValidate, consulKv.Spec.WhitelistedPaths) {
-			continue
+	results := make([]pathScan, len(scannedPaths))
+	toScan := []int{}
+	for idx, path := range scannedPaths {
+		if cache != nil {
+			if result, found := cache.lookup(path); found {
+				results[idx] = result
+				continue
+			}
 		}
+		toScan = append(toScan, idx)
+	}
 
-		matchesRegex, matchingRegexp, err := validate(valueToValidate, validationRegexes)
-		if matchesRegex {
+	toScanPaths := []string{}
+	for _, idx := range toScan {
+		toScanPaths = append(toScanPaths, scannedPaths[idx])
+	}
+	remoteOutcomes := runBatchDetectors(rules.detectors, toScanPaths, configMapPayload, redactor)
 
-			invalidation := utils.Invalidation{
-				Path:         pathToValidate,
-				Value:        valueToValidate,
-				FailingRegex: matchingRegexp,
-			}
-			if err != nil {
-				invalidation.AnyError = err.Error()
+	if workers > len(toScan) {
+		workers = len(toScan)
+	}
+	jobs := make(chan int)
+	wg := &sync.WaitGroup{}
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				path := scannedPaths[idx]
+				results[idx] = scanPath(rules, path, configMapPayload[path], pathToMetadata[path], redactor, remoteOutcomes[path])
 			}
-			invalidationsOutput = append(invalidationsOutput, invalidation)
+		}()
+	}
+	for _, idx := range toScan {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	if cache != nil {
+		for _, idx := range toScan {
+			cache.store(scannedPaths[idx], results[idx])
 		}
+		cache.prune(scannedPaths)
 	}
-	return invalidationsOutput
+
+	detectorErrors := append([]DetectorError{}, rules.compileErrors...)
+	certificates := []sascomv1.CertificateStatus{}
+	for _, result := range results {
+		detectorErrors = append(detectorErrors, result.detectorErrors...)
+		certificates = append(certificates, result.certificates...)
+		if result.invalidation != nil {
+			invalidationsOutput = append(invalidationsOutput, *result.invalidation)
+		}
+	}
+	return scanOutcome{
+		invalidationsOutput: applyExceptions(rules.exceptions, invalidationsOutput, now),
+		detectorErrors:      detectorErrors,
+		scannedPaths:        scannedPaths,
+		detectorVersions:    detectorVersions(rules.detectors),
+		aliasLibraryVersion: usedAliasLibraryVersion(rules.detectors),
+		certificates:        certificates,
+	}
+}
+
+func scanPath(rules ruleSet, pathToValidate string, valueToValidate string, metadata utils.ConsulMetadata, redactor utils.Redactor, remote remoteOutcome) pathScan {
+	findings, detectorErrors := scanValue(rules.detectors, pathToValidate, valueToValidate, metadata, rules.scanLeaves, rules.limits)
+	findings = append(findings, remote.findings...)
+	detectorErrors = append(detectorErrors, remote.detectorErrors...)
+	// an empty value leaks nothing, however sensitive its key name sounds
+	if valueToValidate != "" {
+		findings = append(findings, matchKeyRules(rules.keyRules, pathToValidate)...)
+	}
+	scan := pathScan{detectorErrors: detectorErrors, incomplete: remote.incomplete}
+	if rules.certificates != nil {
+		scan.certificates, scan.rescanAfter = rules.certificates.inventory(pathToValidate, scannedLayers(valueToValidate, rules.scanLeaves, rules.limits))
+	}
+	if len(findings) != 0 {
+		invalidation := utils.NewInvalidation(pathToValidate, redactor.Redact(valueToValidate), findings)
+		scan.invalidation = &invalidation
+	}
+	return scan
+}
+
+func defaultScanWorkers() int {
+	return runtime.GOMAXPROCS(0)
 }
@@ -0,0 +1,82 @@
+package secretengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+// TestFindingsNeverCarryPlaintext guards that whatever a notifier or an audit sink may render out of the findings,
+// the flagged values never show up in plaintext.
+func TestFindingsNeverCarryPlaintext(t *testing.T) {
+	secrets := map[string]string{
+		"app.owner":   "jane.doe@example.com",
+		"app.support": "support-desk@internal.example.org",
+	}
+	payload := utils.DeepCopyMap(secrets)
+	payload["app.replicas"] = "3"
+
+	consulKv := &sascomv1.ConsulKV{Spec: sascomv1.ConsulKVSpec{GuardAgainst: []string{"email"}}}
+	scan := getInvalidations(consulKv, payload, utils.NewRedactor([]byte("test-key")))
+	invalidationsOutput, detectorErrors := scan.invalidationsOutput, scan.detectorErrors
+	if len(detectorErrors) != 0 {
+		t.Fatalf("unexpected detector errors: %v", detectorErrors)
+	}
+	if len(invalidationsOutput) != len(secrets) {
+		t.Fatalf("expected %d findings, got %d: %s", len(secrets), len(invalidationsOutput), invalidationsOutput)
+	}
+
+	jsonBytes, err := json.Marshal(invalidationsOutput)
+	if err != nil {
+		t.Fatalf("failed to marshal the findings: %v", err)
+	}
+	renderings := map[string]string{
+		"String":  invalidationsOutput.String(),
+		"%s":      fmt.Sprintf("%s", invalidationsOutput),
+		"%v":      fmt.Sprintf("%v", invalidationsOutput),
+		"%+v":     fmt.Sprintf("%+v", invalidationsOutput),
+		"%#v":     fmt.Sprintf("%#v", invalidationsOutput),
+		"json":    string(jsonBytes),
+		"preview": strings.Join(previews(invalidationsOutput), ","),
+	}
+	for renderingName, rendered := range renderings {
+		for path, secret := range secrets {
+			if strings.Contains(rendered, secret) {
+				t.Errorf("rendering %s leaked the plaintext value of %s: %s", renderingName, path, rendered)
+			}
+		}
+	}
+
+	for _, inv := range invalidationsOutput {
+		if len(inv.Spans()) == 0 {
+			t.Errorf("finding at %s carries no matched span", inv.Path)
+		}
+		if !strings.HasPrefix(inv.Fingerprint, "hmac-sha256:") {
+			t.Errorf("finding at %s carries an unexpected fingerprint %q", inv.Path, inv.Fingerprint)
+		}
+	}
+}
+
+func TestFingerprintsAreKeyed(t *testing.T) {
+	value := "jane.doe@example.com"
+	first, second := utils.NewRedactor([]byte("key-one")), utils.NewRedactor([]byte("key-two"))
+
+	if first.Fingerprint(value) != first.Fingerprint(value) {
+		t.Errorf("fingerprints of the same value under the same key differ")
+	}
+	if first.Fingerprint(value) == second.Fingerprint(value) {
+		t.Errorf("fingerprints of the same value under different keys are equal")
+	}
+}
+
+func previews(invalidationsOutput utils.InvalidationsOutput) []string {
+	output := []string{}
+	for _, inv := range invalidationsOutput {
+		output = append(output, inv.Preview)
+	}
+	return output
+}
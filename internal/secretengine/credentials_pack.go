@@ -0,0 +1,192 @@
+package secretengine
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	credentialsPackName = "cloud-credentials"
+	// bump whenever a rule of the pack is added, removed or changes what it matches
+	credentialsPackVersion = "1.0.1"
+)
+
+var credentialRules = []packRule{
+	{
+		id:         "aws-access-key-id",
+		severity:   utils.HighSeverity,
+		confidence: 0.9,
+		regex:      regexp.MustCompile(`\b(?P<secret>(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16})\b`),
+	},
+	{
+		// a secret access key on its own is just a 40 characters long base64 string, so only report it next to its access key id.
+		// '/' and '+' aren't word characters, so the key is delimited by anything but a base64 character rather than by word boundaries.
+		id:         "aws-secret-access-key",
+		severity:   utils.CriticalSeverity,
+		confidence: 0.9,
+		regex:      regexp.MustCompile(`(?s)(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}.{1,200}?(?:^|[^A-Za-z0-9/+])(?P<secret>[A-Za-z0-9/+]{40})(?:$|[^A-Za-z0-9/+])`),
+		verify: func(match string) bool {
+			secret := strings.TrimRightFunc(match, func(char rune) bool { return !isBase64Char(char) })
+			secret = secret[len(secret)-40:]
+			return strings.ContainsAny(secret, "/+") || hasMixedCase(secret)
+		},
+		requiresVerification: true,
+	},
+	{
+		id:                   "gcp-service-account",
+		severity:             utils.CriticalSeverity,
+		confidence:           0.7,
+		regex:                regexp.MustCompile(`(?s)\{.*"type"\s*:\s*"service_account".*\}`),
+		verify:               isGcpServiceAccount,
+		requiresVerification: true,
+	},
+	{
+		id:                   "azure-storage-key",
+		severity:             utils.HighSeverity,
+		confidence:           0.8,
+		regex:                regexp.MustCompile(`AccountKey=(?P<secret>[A-Za-z0-9+/]{86}==)`),
+		verify:               func(match string) bool { return decodesToLength(strings.TrimPrefix(match, "AccountKey="), 64) },
+		requiresVerification: true,
+	},
+	{
+		id:         "github-token",
+		severity:   utils.HighSeverity,
+		confidence: 0.95,
+		regex:      regexp.MustCompile(`\b(?P<secret>(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{82})\b`),
+	},
+	{
+		id:         "gitlab-token",
+		severity:   utils.HighSeverity,
+		confidence: 0.95,
+		regex:      regexp.MustCompile(`\b(?P<secret>glpat-[A-Za-z0-9_\-]{20})\b`),
+	},
+	{
+		id:         "slack-token",
+		severity:   utils.HighSeverity,
+		confidence: 0.9,
+		regex:      regexp.MustCompile(`\b(?P<secret>xox[abprs]-[A-Za-z0-9-]{10,})\b`),
+	},
+	{
+		id:         "slack-webhook",
+		severity:   utils.MediumSeverity,
+		confidence: 0.95,
+		regex:      regexp.MustCompile(`https://hooks\.slack\.com/services/(?P<secret>T[A-Z0-9]+/B[A-Z0-9]+/[A-Za-z0-9]+)`),
+	},
+	{
+		id:         "stripe-live-key",
+		severity:   utils.CriticalSeverity,
+		confidence: 0.95,
+		regex:      regexp.MustCompile(`\b(?P<secret>(?:sk|rk)_live_[A-Za-z0-9]{16,})\b`),
+	},
+	{
+		id:         "stripe-test-key",
+		severity:   utils.LowSeverity,
+		confidence: 0.95,
+		regex:      regexp.MustCompile(`\b(?P<secret>(?:sk|rk)_test_[A-Za-z0-9]{16,})\b`),
+	},
+	{
+		id:         "pem-private-key",
+		severity:   utils.CriticalSeverity,
+		confidence: 0.8,
+		regex:      regexp.MustCompile(`(?s)-----BEGIN (?:[A-Z]+ )?PRIVATE KEY-----.*?-----END (?:[A-Z]+ )?PRIVATE KEY-----`),
+		verify: func(match string) bool {
+			block, _ := pem.Decode([]byte(match))
+			return block != nil && len(block.Bytes) != 0
+		},
+	},
+	{
+		id:                   "jwt",
+		severity:             utils.MediumSeverity,
+		confidence:           0.5,
+		regex:                regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+		verify:               isJwt,
+		requiresVerification: true,
+	},
+	{
+		id:                   "db-connection-string",
+		severity:             utils.HighSeverity,
+		confidence:           0.6,
+		regex:                regexp.MustCompile(`\b(?:postgres(?:ql)?|mysql|mariadb|mongodb(?:\+srv)?|rediss?|amqps?|mssql|sqlserver)://[^:\s/@]+:(?P<secret>[^@\s]+)@[^\s"']+`),
+		verify:               hasRealPassword,
+		requiresVerification: true,
+	},
+}
+
+func init() {
+	registerDetectorPack(credentialsPackName, credentialsPackVersion, credentialRules)
+}
+
+func isGcpServiceAccount(match string) bool {
+	var serviceAccount struct {
+		Type         string `json:"type"`
+		PrivateKeyID string `json:"private_key_id"`
+		PrivateKey   string `json:"private_key"`
+		ClientEmail  string `json:"client_email"`
+	}
+	if err := json.Unmarshal([]byte(match), &serviceAccount); err != nil {
+		return false
+	}
+	return serviceAccount.Type == "service_account" &&
+		serviceAccount.PrivateKeyID != "" &&
+		strings.Contains(serviceAccount.PrivateKey, "PRIVATE KEY") &&
+		strings.HasSuffix(serviceAccount.ClientEmail, ".gserviceaccount.com")
+}
+
+// isJwt decodes the header and the claims of the token, a JWT header always names its signing algorithm
+func isJwt(match string) bool {
+	segments := strings.Split(match, ".")
+	if len(segments) != 3 {
+		return false
+	}
+	var header map[string]interface{}
+	var claims map[string]interface{}
+	if !decodesToJson(segments[0], &header) || !decodesToJson(segments[1], &claims) {
+		return false
+	}
+	_, hasAlgorithm := header["alg"]
+	return hasAlgorithm
+}
+
+func decodesToJson(segment string, target interface{}) bool {
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(decoded, target) == nil
+}
+
+func decodesToLength(encoded string, length int) bool {
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	return err == nil && len(decoded) == length
+}
+
+// hasRealPassword rules out the connection strings whose password is a templating placeholder rather than an actual password
+func hasRealPassword(match string) bool {
+	parsed, err := url.Parse(match)
+	if err != nil || parsed.User == nil {
+		return false
+	}
+	password, set := parsed.User.Password()
+	if !set || password == "" {
+		return false
+	}
+	for _, placeholder := range []string{"${", "{{", "<", "%s", "$("} {
+		if strings.Contains(password, placeholder) {
+			return false
+		}
+	}
+	return strings.Trim(password, "*xX.") != ""
+}
+
+func hasMixedCase(token string) bool {
+	return strings.ToLower(token) != token && strings.ToUpper(token) != token
+}
+
+func isBase64Char(char rune) bool {
+	return (char >= 'A' && char <= 'Z') || (char >= 'a' && char <= 'z') || (char >= '0' && char <= '9') || char == '/' || char == '+'
+}
@@ -0,0 +1,198 @@
+package secretengine
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	defaultDecodingMaxDepth        = 3
+	defaultDecodingMaxDecodedBytes = 1 << 20
+	// maxDecodedLayers bounds the fan-out of a value which happens to be decodable in more than one way at every depth
+	maxDecodedLayers = 16
+	// minEncodedLength keeps short words, which are often valid base64 or hex by accident, from being decoded
+	minEncodedLength = 16
+	// minPrintableRatio is the share of printable characters a decoded layer needs to be considered text worth scanning
+	minPrintableRatio = 0.9
+)
+
+var (
+	base64Regex         = regexp.MustCompile(`^[A-Za-z0-9+/_-]+={0,2}$`)
+	hexRegex            = regexp.MustCompile(`^(?:[0-9A-Fa-f]{2})+$`)
+	percentEncodedRegex = regexp.MustCompile(`%[0-9A-Fa-f]{2}`)
+	gzipMagic           = []byte{0x1f, 0x8b}
+)
+
+type decoder struct {
+	name   string
+	decode func(encoded []byte, maxDecodedBytes int) ([]byte, bool)
+}
+
+// decoders are tried in this order on every layer, gzip comes first as a compressed layer is never text to begin with
+var decoders = []decoder{
+	{name: "gzip", decode: decodeGzip},
+	{name: "url", decode: decodeUrl},
+	{name: "hex", decode: decodeHex},
+	{name: "base64", decode: decodeBase64},
+}
+
+type decodingLimits struct {
+	enabled         bool
+	maxDepth        int
+	maxDecodedBytes int
+}
+
+func decodingLimitsFor(spec *sascomv1.DetectorsSpec) decodingLimits {
+	limits := decodingLimits{enabled: true, maxDepth: defaultDecodingMaxDepth, maxDecodedBytes: defaultDecodingMaxDecodedBytes}
+	if spec == nil || spec.Decoding == nil {
+		return limits
+	}
+	limits.enabled = !spec.Decoding.Disabled
+	if spec.Decoding.MaxDepth > 0 {
+		limits.maxDepth = spec.Decoding.MaxDepth
+	}
+	if spec.Decoding.MaxDecodedBytes > 0 {
+		limits.maxDecodedBytes = spec.Decoding.MaxDecodedBytes
+	}
+	return limits
+}
+
+// decodedLayer is a decoded form of a value along with the encodings peeled off, outermost first, to reach it
+type decodedLayer struct {
+	chain []string
+	value string
+}
+
+// decodeLayers peels off every encoding layer it can recognise from the value, up to the limits, and returns the decoded layers which read as text
+func decodeLayers(value string, limits decodingLimits) []decodedLayer {
+	if !limits.enabled {
+		return nil
+	}
+	type pendingLayer struct {
+		chain []string
+		data  []byte
+	}
+
+	output := []decodedLayer{}
+	pending := []pendingLayer{{data: []byte(value)}}
+	decodedCount := 0
+	for len(pending) != 0 && decodedCount < maxDecodedLayers {
+		current := pending[0]
+		pending = pending[1:]
+		if len(current.chain) >= limits.maxDepth {
+			continue
+		}
+		for _, d := range decoders {
+			if decodedCount >= maxDecodedLayers {
+				break
+			}
+			decoded, ok := d.decode(current.data, limits.maxDecodedBytes)
+			if !ok || len(decoded) == 0 || bytes.Equal(decoded, current.data) {
+				continue
+			}
+			decodedCount++
+			chain := append(append([]string{}, current.chain...), d.name)
+			pending = append(pending, pendingLayer{chain: chain, data: decoded})
+			if isText(decoded) {
+				output = append(output, decodedLayer{chain: chain, value: string(decoded)})
+			}
+		}
+	}
+	return output
+}
+
+func decodeGzip(encoded []byte, maxDecodedBytes int) ([]byte, bool) {
+	if !bytes.HasPrefix(encoded, gzipMagic) {
+		return nil, false
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(encoded))
+	if err != nil {
+		return nil, false
+	}
+	defer reader.Close()
+	// the reader is capped so that a decompression bomb never gets inflated past the limit
+	decoded, err := io.ReadAll(io.LimitReader(reader, int64(maxDecodedBytes)))
+	if err != nil && len(decoded) == 0 {
+		return nil, false
+	}
+	return decoded, true
+}
+
+func decodeUrl(encoded []byte, maxDecodedBytes int) ([]byte, bool) {
+	if !utf8.Valid(encoded) || !percentEncodedRegex.Match(encoded) {
+		return nil, false
+	}
+	decoded, err := url.QueryUnescape(string(encoded))
+	if err != nil {
+		return nil, false
+	}
+	return truncate([]byte(decoded), maxDecodedBytes), true
+}
+
+func decodeHex(encoded []byte, maxDecodedBytes int) ([]byte, bool) {
+	trimmed := bytes.TrimSpace(encoded)
+	if len(trimmed) < minEncodedLength || !hexRegex.Match(trimmed) {
+		return nil, false
+	}
+	if len(trimmed) > 2*maxDecodedBytes {
+		trimmed = trimmed[:2*maxDecodedBytes]
+	}
+	decoded := make([]byte, hex.DecodedLen(len(trimmed)))
+	if _, err := hex.Decode(decoded, trimmed); err != nil {
+		return nil, false
+	}
+	return decoded, isText(decoded) || bytes.HasPrefix(decoded, gzipMagic)
+}
+
+func decodeBase64(encoded []byte, maxDecodedBytes int) ([]byte, bool) {
+	trimmed := strings.Join(strings.Fields(string(encoded)), "")
+	if len(trimmed) < minEncodedLength || !base64Regex.MatchString(trimmed) {
+		return nil, false
+	}
+	if base64.StdEncoding.EncodedLen(maxDecodedBytes) < len(trimmed) {
+		trimmed = trimmed[:base64.StdEncoding.EncodedLen(maxDecodedBytes)]
+	}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		decoded, err := encoding.DecodeString(trimmed)
+		if err != nil {
+			continue
+		}
+		// random strings decode as base64 all the time, only text or compressed data is worth following
+		if isText(decoded) || bytes.HasPrefix(decoded, gzipMagic) {
+			return truncate(decoded, maxDecodedBytes), true
+		}
+		return nil, false
+	}
+	return nil, false
+}
+
+// isText tells whether the data is valid UTF-8 made mostly of printable characters
+func isText(data []byte) bool {
+	if len(data) == 0 || !utf8.Valid(data) {
+		return false
+	}
+	printable, total := 0, 0
+	for _, char := range string(data) {
+		total++
+		if unicode.IsPrint(char) || unicode.IsSpace(char) {
+			printable++
+		}
+	}
+	return float64(printable)/float64(total) >= minPrintableRatio
+}
+
+func truncate(data []byte, maxBytes int) []byte {
+	if len(data) > maxBytes {
+		return data[:maxBytes]
+	}
+	return data
+}
@@ -0,0 +1,101 @@
+package secretengine
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+func gzipped(t *testing.T, data []byte) []byte {
+	buffer := &bytes.Buffer{}
+	writer := gzip.NewWriter(buffer)
+	if _, err := writer.Write(data); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+// TestDecodeLayers guards that every encoding layer gets peeled off, outermost first, within the limits
+func TestDecodeLayers(t *testing.T) {
+	secret := "owner: jane.doe@example.com"
+	defaults := decodingLimitsFor(nil)
+	for _, tc := range []struct {
+		name      string
+		value     string
+		limits    decodingLimits
+		wantChain []string
+	}{
+		{name: "base64", value: base64.StdEncoding.EncodeToString([]byte(secret)), limits: defaults, wantChain: []string{"base64"}},
+		{name: "hex", value: hex.EncodeToString([]byte(secret)), limits: defaults, wantChain: []string{"hex"}},
+		{name: "url", value: url.QueryEscape(secret), limits: defaults, wantChain: []string{"url"}},
+		{name: "gzipped base64", value: base64.StdEncoding.EncodeToString(gzipped(t, []byte(secret))), limits: defaults, wantChain: []string{"base64", "gzip"}},
+		{name: "double base64", value: base64.StdEncoding.EncodeToString([]byte(base64.StdEncoding.EncodeToString([]byte(secret)))), limits: defaults, wantChain: []string{"base64", "base64"}},
+		{name: "beyond the depth", value: base64.StdEncoding.EncodeToString([]byte(base64.StdEncoding.EncodeToString([]byte(secret)))),
+			limits: decodingLimits{enabled: true, maxDepth: 1, maxDecodedBytes: defaults.maxDecodedBytes}},
+		{name: "disabled", value: base64.StdEncoding.EncodeToString([]byte(secret)), limits: decodingLimitsFor(&sascomv1.DetectorsSpec{Decoding: &sascomv1.DecodingSpec{Disabled: true}})},
+	} {
+		var found *decodedLayer
+		for _, layer := range decodeLayers(tc.value, tc.limits) {
+			if layer.value == secret {
+				layer := layer
+				found = &layer
+			}
+		}
+		switch {
+		case tc.wantChain == nil && found != nil:
+			t.Errorf("%s: unexpectedly decoded the value through %v", tc.name, found.chain)
+		case tc.wantChain != nil && found == nil:
+			t.Errorf("%s: failed to decode the value", tc.name)
+		case tc.wantChain != nil && !reflect.DeepEqual(found.chain, tc.wantChain):
+			t.Errorf("%s: expected the chain %v, got %v", tc.name, tc.wantChain, found.chain)
+		}
+	}
+}
+
+// TestDecodingLimits guards that short words aren't decoded by accident and that a decompression bomb never gets inflated past the limit
+func TestDecodingLimits(t *testing.T) {
+	if layers := decodeLayers("deadbeef", decodingLimitsFor(nil)); len(layers) != 0 {
+		t.Errorf("a short word got decoded: %+v", layers)
+	}
+
+	bomb := base64.StdEncoding.EncodeToString(gzipped(t, []byte(strings.Repeat("a", 1<<16))))
+	layers := decodeLayers(bomb, decodingLimits{enabled: true, maxDepth: 3, maxDecodedBytes: 1024})
+	if len(layers) == 0 {
+		t.Fatalf("failed to decode the compressed value")
+	}
+	for _, layer := range layers {
+		if len(layer.value) > 1024 {
+			t.Errorf("the layer %v got inflated to %d bytes", layer.chain, len(layer.value))
+		}
+	}
+}
+
+// TestFindingsRecordTheirDecodeChain guards that a finding made in a decoded layer tells how that layer was reached
+func TestFindingsRecordTheirDecodeChain(t *testing.T) {
+	consulKv := &sascomv1.ConsulKV{Spec: sascomv1.ConsulKVSpec{GuardAgainst: []string{"email"}}}
+	payload := map[string]string{"app.config": base64.StdEncoding.EncodeToString(gzipped(t, []byte("jane.doe@example.com")))}
+
+	scan := getInvalidations(consulKv, payload, utils.NewRedactor([]byte("test-key")))
+	if len(scan.detectorErrors) != 0 {
+		t.Fatalf("unexpected detector errors: %v", scan.detectorErrors)
+	}
+	if len(scan.invalidationsOutput) != 1 {
+		t.Fatalf("expected a single invalidation, got %s", scan.invalidationsOutput)
+	}
+	for _, finding := range scan.invalidationsOutput[0].Findings {
+		if !reflect.DeepEqual(finding.DecodeChain, []string{"base64", "gzip"}) {
+			t.Errorf("expected the finding to record the chain [base64 gzip], got %v", finding.DecodeChain)
+		}
+	}
+}
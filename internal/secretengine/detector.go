@@ -0,0 +1,173 @@
+package secretengine
+
+import (
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/celguard"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// Detector tells whether a value, living at the provided path, is sensitive.
+// Every finding it returns carries the id of the rule which fired, how confident the detector is about it and the span of the value it covers.
+type Detector interface {
+	Name() string
+	Detect(path string, value string) ([]utils.Finding, error)
+}
+
+// configurableDetector is implemented by the detectors which a ConsulKV may tune through its spec
+type configurableDetector interface {
+	Detector
+	Configure(spec *sascomv1.DetectorsSpec) (Detector, error)
+}
+
+// versionedDetector is implemented by the detectors shipping a versioned set of rules, the version is surfaced in the status of the ConsulKVs using them
+type versionedDetector interface {
+	Detector
+	Version() string
+}
+
+// metadataAwareDetector is implemented by the detectors which also look at the Consul metadata of the key, Detect is used when there is none
+type metadataAwareDetector interface {
+	Detector
+	DetectWithMetadata(path string, value string, metadata utils.ConsulMetadata) ([]utils.Finding, error)
+}
+
+var (
+	detectorRegistryLock = &sync.RWMutex{}
+	detectorRegistry     = map[string]Detector{}
+)
+
+// RegisterDetector makes a detector referenceable by its name from the guards of any ConsulKV
+func RegisterDetector(detector Detector) {
+	detectorRegistryLock.Lock()
+	defer detectorRegistryLock.Unlock()
+	detectorRegistry[detector.Name()] = detector
+}
+
+func lookupDetector(name string) (Detector, bool) {
+	detectorRegistryLock.RLock()
+	defer detectorRegistryLock.RUnlock()
+	detector, found := detectorRegistry[name]
+	return detector, found
+}
+
+// RegisteredDetectors lists the names of every detector a guard may reference
+func RegisteredDetectors() []string {
+	detectorRegistryLock.RLock()
+	defer detectorRegistryLock.RUnlock()
+	names := []string{}
+	for name := range detectorRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveDetectors maps every guard of a ConsulKV to a detector, CEL guards are compiled while guards which neither name a remote detector, a registered detector nor an alias of the runtime library are treated as raw regexes
+func resolveDetectors(guards []string, spec *sascomv1.DetectorsSpec) ([]Detector, []DetectorError) {
+	detectors := []Detector{}
+	detectorErrors := []DetectorError{}
+	for _, guard := range guards {
+		if expression, isCel := celguard.Expression(guard); isCel {
+			detector, err := newCelDetector(guard, expression)
+			if err != nil {
+				detectorErrors = append(detectorErrors, DetectorError{Rule: guard, Err: err})
+				continue
+			}
+			detectors = append(detectors, detector)
+			continue
+		}
+		if detector, found := lookupRemoteDetector(guard, spec); found {
+			detectors = append(detectors, detector)
+			continue
+		}
+		if detector, found := lookupDetector(guard); found {
+			if configurable, ok := detector.(configurableDetector); ok {
+				configured, err := configurable.Configure(spec)
+				if err != nil {
+					detectorErrors = append(detectorErrors, DetectorError{Rule: guard, Err: fmt.Errorf("invalid detector configuration: %w", err)})
+					continue
+				}
+				detector = configured
+			}
+			detectors = append(detectors, detector)
+			continue
+		}
+		if detector, found := lookupLibraryAlias(guard); found {
+			detectors = append(detectors, detector)
+			continue
+		}
+		detector, err := newRegexDetector(guard, guard, rawRegexConfidence)
+		if err != nil {
+			detectorErrors = append(detectorErrors, DetectorError{Rule: guard, Err: err})
+			continue
+		}
+		detectors = append(detectors, detector)
+	}
+	return detectors, detectorErrors
+}
+
+// aliasLikeGuard matches the guards which read as the name of an alias rather than as a regex
+var aliasLikeGuard = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// UnresolvedAliases lists the guards of the ConsulKV which read as the name of an alias but would be treated as raw regexes,
+// as happens to the aliases of a runtime library which isn't loaded
+func UnresolvedAliases(consulKv *sascomv1.ConsulKV) []string {
+	unresolved := []string{}
+	for _, guard := range consulKv.Spec.GuardAgainst {
+		if !aliasLikeGuard.MatchString(guard) {
+			continue
+		}
+		_, isRemote := lookupRemoteDetector(guard, consulKv.Spec.Detectors)
+		_, isRegistered := lookupDetector(guard)
+		_, isLibraryAlias := lookupLibraryAlias(guard)
+		if !isRemote && !isRegistered && !isLibraryAlias {
+			unresolved = append(unresolved, guard)
+		}
+	}
+	return unresolved
+}
+
+// detectorVersions lists the versions of the versioned detectors among the provided ones as 'name@version'
+func detectorVersions(detectors []Detector) []string {
+	versions := []string{}
+	for _, detector := range detectors {
+		if versioned, ok := detector.(versionedDetector); ok {
+			versions = append(versions, fmt.Sprintf("%s@%s", versioned.Name(), versioned.Version()))
+		}
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// runDetectors collects the findings of every detector on a single value, a failing detector doesn't stop the others from running.
+// Batch detectors are left out, they run once over the whole payload instead.
+func runDetectors(detectors []Detector, path string, value string, metadata utils.ConsulMetadata) ([]utils.Finding, []DetectorError) {
+	findings := []utils.Finding{}
+	detectorErrors := []DetectorError{}
+	for _, detector := range detectors {
+		if _, isBatch := detector.(batchDetector); isBatch {
+			continue
+		}
+		var detectorFindings []utils.Finding
+		var err error
+		if metadataAware, ok := detector.(metadataAwareDetector); ok {
+			detectorFindings, err = metadataAware.DetectWithMetadata(path, value, metadata)
+		} else {
+			detectorFindings, err = detector.Detect(path, value)
+		}
+		if err != nil {
+			detectorErrors = append(detectorErrors, DetectorError{
+				Rule: detector.Name(),
+				Path: path,
+				Err:  fmt.Errorf("detector failed to evaluate the value: %w", err),
+			})
+			continue
+		}
+		findings = append(findings, detectorFindings...)
+	}
+	return findings, detectorErrors
+}
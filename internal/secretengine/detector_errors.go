@@ -0,0 +1,169 @@
+package secretengine
+
+import (
+	"errors"
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrSyncHalted is returned by Run when detector errors must leave the configmap untouched
+var ErrSyncHalted = errors.New("sync halted because of detector errors")
+
+var DefaultDetectorErrorPolicies = map[sascomv1.QoSType]sascomv1.DetectorErrorPolicy{
+	sascomv1.Critical: sascomv1.FailClosed,
+	sascomv1.Medium:   sascomv1.FailClosed,
+	sascomv1.Relaxed:  sascomv1.FailOpen,
+}
+
+// DetectorError is an outcome of a scan distinct from a match: the rule could not tell whether the value is sensitive or not
+type DetectorError struct {
+	Rule string
+	// Path is empty when the rule failed as a whole, hence for every scanned path
+	Path string
+	Err  error
+}
+
+func (e DetectorError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("rule '%s': %s", e.Rule, e.Err.Error())
+	}
+	return fmt.Sprintf("rule '%s' at '%s': %s", e.Rule, e.Path, e.Err.Error())
+}
+
+// erroredPaths returns the scanned paths whose evaluation was affected by any of the detector errors
+func erroredPaths(detectorErrors []DetectorError, scannedPaths []string) []string {
+	paths := map[string]bool{}
+	for _, detectorError := range detectorErrors {
+		if detectorError.Path == "" {
+			return scannedPaths
+		}
+		paths[detectorError.Path] = true
+	}
+	output := []string{}
+	for path := range paths {
+		output = append(output, path)
+	}
+	sort.Strings(output)
+	return output
+}
+
+// hasRuleWideErrors tells whether any rule failed as a whole rather than at a given path
+func hasRuleWideErrors(detectorErrors []DetectorError) bool {
+	for _, detectorError := range detectorErrors {
+		if detectorError.Path == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// protectErroredPaths keeps the errored paths out of the configmap, falling back to the value synced last time, which got fully evaluated back then
+func protectErroredPaths(configMapPayload map[string]string, paths []string, previousConfigMapPayload map[string]string) map[string]string {
+	protected := utils.RemoveKeysFromMap(configMapPayload, paths)
+	for _, path := range paths {
+		if previousValue, found := previousConfigMapPayload[path]; found {
+			protected[path] = previousValue
+		}
+	}
+	return protected
+}
+
+func (s Client) detectorErrorPolicy(item *sascomv1.ConsulKV) sascomv1.DetectorErrorPolicy {
+	if item.Spec.DetectorErrorPolicy != "" {
+		return item.Spec.DetectorErrorPolicy
+	}
+	if policy, found := s.detectorErrorPolicies[item.Spec.QoS]; found {
+		return policy
+	}
+	return sascomv1.FailClosed
+}
+
+func setDetectorsHealthyCondition(item *sascomv1.ConsulKV, detectorErrors []DetectorError, policy sascomv1.DetectorErrorPolicy) {
+	if len(detectorErrors) == 0 {
+		meta.SetStatusCondition(&item.Status.Conditions, metav1.Condition{
+			Type:               sascomv1.DetectorsHealthyCondition,
+			Status:             metav1.ConditionTrue,
+			Reason:             "AllDetectorsEvaluated",
+			Message:            "every guard got evaluated successfully",
+			ObservedGeneration: item.Generation,
+		})
+		return
+	}
+	meta.SetStatusCondition(&item.Status.Conditions, metav1.Condition{
+		Type:               sascomv1.DetectorsHealthyCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             "DetectorErrors",
+		Message:            boundedMessage(fmt.Sprintf("policy '%s' applied to %d detector error(s): %s", policy, len(detectorErrors), boundedList(summarizeDetectorErrors(detectorErrors), conditionMessageItems, "; "))),
+		ObservedGeneration: item.Generation,
+	})
+}
+
+// summarizeDetectorErrors describes the errors of every rule at once, in the order the rules first failed.
+// An outage of a remote detector fails every path of a large prefix, which only lists the first few of them.
+func summarizeDetectorErrors(detectorErrors []DetectorError) []string {
+	rules := []string{}
+	ruleWideErrors := map[string]error{}
+	pathErrors := map[string][]DetectorError{}
+	for _, detectorError := range detectorErrors {
+		if _, found := ruleWideErrors[detectorError.Rule]; !found && len(pathErrors[detectorError.Rule]) == 0 {
+			rules = append(rules, detectorError.Rule)
+		}
+		if detectorError.Path == "" {
+			if _, found := ruleWideErrors[detectorError.Rule]; !found {
+				ruleWideErrors[detectorError.Rule] = detectorError.Err
+			}
+			continue
+		}
+		pathErrors[detectorError.Rule] = append(pathErrors[detectorError.Rule], detectorError)
+	}
+
+	summaries := []string{}
+	for _, rule := range rules {
+		if err, found := ruleWideErrors[rule]; found {
+			summaries = append(summaries, DetectorError{Rule: rule, Err: err}.Error())
+		}
+		if errored := pathErrors[rule]; len(errored) != 0 {
+			paths := []string{}
+			for _, detectorError := range errored {
+				paths = append(paths, detectorError.Path)
+			}
+			summaries = append(summaries, fmt.Sprintf("rule '%s' failed at %d path(s) (%s), the first with: %s", rule, len(errored), boundedList(paths, conditionMessagePaths, ", "), errored[0].Err.Error()))
+		}
+	}
+	return summaries
+}
+
+const (
+	// conditionMessageItems bounds how many items a condition message lists
+	conditionMessageItems = 10
+	// conditionMessagePaths bounds how many paths an item of a condition message lists
+	conditionMessagePaths = 5
+	// conditionMessageLength keeps the condition messages well under the 32768 characters the CRD allows
+	conditionMessageLength = 4096
+)
+
+// boundedList joins the first items of the list and tells how many more were left out
+func boundedList(items []string, limit int, separator string) string {
+	if len(items) <= limit {
+		return strings.Join(items, separator)
+	}
+	return fmt.Sprintf("%s …and %d more", strings.Join(items[:limit], separator), len(items)-limit)
+}
+
+// boundedMessage cuts a condition message short, whatever the length of the errors it is made of
+func boundedMessage(message string) string {
+	if len(message) <= conditionMessageLength {
+		return message
+	}
+	cut := conditionMessageLength
+	for cut > 0 && !utf8.RuneStart(message[cut]) {
+		cut--
+	}
+	return message[:cut] + "…"
+}
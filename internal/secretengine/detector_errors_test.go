@@ -0,0 +1,88 @@
+package secretengine
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+// TestProtectErroredPaths guards that the values a detector failed to evaluate fall back to the ones synced last time,
+// and that only the values never synced before are left out of the configmap.
+func TestProtectErroredPaths(t *testing.T) {
+	payload := map[string]string{"app.token": "new", "app.fresh": "value", "app.replicas": "3"}
+	previous := map[string]string{"app.token": "old", "app.replicas": "2"}
+
+	got := protectErroredPaths(payload, []string{"app.token", "app.fresh"}, previous)
+	want := map[string]string{"app.token": "old", "app.replicas": "3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if payload["app.token"] != "new" {
+		t.Errorf("the payload got mutated: %v", payload)
+	}
+}
+
+func TestHasRuleWideErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		errors []DetectorError
+		want   bool
+	}{
+		{"none", nil, false},
+		{"per path", []DetectorError{{Rule: "remote", Path: "app.token", Err: fmt.Errorf("timeout")}}, false},
+		{"rule wide", []DetectorError{{Rule: "remote", Path: "app.token", Err: fmt.Errorf("timeout")}, {Rule: "([", Err: fmt.Errorf("invalid regex")}}, true},
+	} {
+		if got := hasRuleWideErrors(tc.errors); got != tc.want {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+// TestDetectorsHealthyConditionIsBounded guards that a detector failing on every path of a large prefix
+// still fits the condition message, which the CRD caps at 32768 characters.
+func TestDetectorsHealthyConditionIsBounded(t *testing.T) {
+	detectorErrors := []DetectorError{{Rule: "([", Err: fmt.Errorf("invalid regex")}}
+	for idx := 0; idx < 10000; idx++ {
+		detectorErrors = append(detectorErrors, DetectorError{Rule: "remote", Path: fmt.Sprintf("app.key-%05d", idx), Err: fmt.Errorf("plugin unreachable")})
+	}
+	item := &sascomv1.ConsulKV{}
+	setDetectorsHealthyCondition(item, detectorErrors, sascomv1.FailClosed)
+
+	condition := meta.FindStatusCondition(item.Status.Conditions, sascomv1.DetectorsHealthyCondition)
+	if condition == nil || len(condition.Message) > conditionMessageLength+len("…") {
+		t.Fatalf("expected a bounded message, got %+v", condition)
+	}
+	for _, want := range []string{"10001 detector error(s)", "rule '([': invalid regex", "rule 'remote' failed at 10000 path(s) (app.key-00000, ", "…and 9995 more", "plugin unreachable"} {
+		if !strings.Contains(condition.Message, want) {
+			t.Errorf("expected the message to mention %q, got %q", want, condition.Message)
+		}
+	}
+
+	if message := boundedMessage(strings.Repeat("é", conditionMessageLength)); len(message) > conditionMessageLength+len("…") || !utf8.ValidString(message) {
+		t.Errorf("the message got cut in the middle of a character or not at all: %d bytes", len(message))
+	}
+}
+
+// TestCelGuardErrorsAreDetectorErrors guards that a CEL guard failing to evaluate a value, like one turning out no bool,
+// is reported as a detector error at its path rather than taken for a clean value.
+func TestCelGuardErrorsAreDetectorErrors(t *testing.T) {
+	consulKv := &sascomv1.ConsulKV{Spec: sascomv1.ConsulKVSpec{
+		GuardAgainst:     []string{"cel:json.enabled"},
+		StructuredValues: &sascomv1.StructuredValuesSpec{DisableLeafScanning: true},
+	}}
+	scan := getInvalidations(consulKv, map[string]string{"app.flags": `{"enabled": "yes"}`, "app.other": `{"enabled": true}`}, utils.NewRedactor([]byte("test-key")))
+
+	if len(scan.detectorErrors) != 1 || scan.detectorErrors[0].Rule != "cel:json.enabled" || scan.detectorErrors[0].Path != "app.flags" {
+		t.Errorf("expected a detector error at app.flags, got %v", scan.detectorErrors)
+	}
+	if paths := scan.invalidationsOutput.Paths(); !reflect.DeepEqual(paths, []string{"app.other"}) {
+		t.Errorf("expected only app.other to be flagged, got %v", paths)
+	}
+}
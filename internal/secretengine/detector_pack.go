@@ -0,0 +1,99 @@
+package secretengine
+
+import (
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"regexp"
+	"strings"
+)
+
+const defaultVerifiedConfidence = 1.0
+
+// packRule matches a single kind of sensitive value. When the regex has a group named "secret", only that group is reported as the span of the finding.
+type packRule struct {
+	id         string
+	severity   utils.Severity
+	confidence float64
+	regex      *regexp.Regexp
+
+	// verify checks the structure of a match, a verified match is reported with verifiedConfidence, or full confidence when unset
+	verify             func(match string) bool
+	verifiedConfidence float64
+	// requiresVerification drops the matches which fail verification instead of reporting them with their base confidence
+	requiresVerification bool
+	// keyContext restricts the rule to the keys whose last segment it matches, for the patterns too generic to be trusted anywhere else
+	keyContext *regexp.Regexp
+}
+
+// registerDetectorPack makes the whole pack referenceable under its name, and every rule of it under the id of the rule
+func registerDetectorPack(name string, version string, rules []packRule) {
+	RegisterDetector(packDetector{name: name, version: version, rules: rules})
+	// a rule may come in several variants sharing its id, they are all referenced together
+	ids := []string{}
+	variants := map[string][]packRule{}
+	for _, rule := range rules {
+		if _, found := variants[rule.id]; !found {
+			ids = append(ids, rule.id)
+		}
+		variants[rule.id] = append(variants[rule.id], rule)
+	}
+	for _, id := range ids {
+		RegisterDetector(packDetector{name: id, version: version, rules: variants[id]})
+	}
+}
+
+// packDetector runs either a whole versioned pack of rules or a single rule of it, depending on the name it got registered under
+type packDetector struct {
+	name    string
+	version string
+	rules   []packRule
+}
+
+func (d packDetector) Name() string {
+	return d.name
+}
+
+func (d packDetector) Version() string {
+	return d.version
+}
+
+func (d packDetector) Detect(path string, value string) ([]utils.Finding, error) {
+	findings := []utils.Finding{}
+	for _, rule := range d.rules {
+		if rule.keyContext != nil && !rule.keyContext.MatchString(lastKeySegment(path)) {
+			continue
+		}
+		secretGroupIdx := rule.regex.SubexpIndex("secret")
+		for _, loc := range rule.regex.FindAllStringSubmatchIndex(value, -1) {
+			match := value[loc[0]:loc[1]]
+
+			confidence := rule.confidence
+			if rule.verify != nil {
+				if rule.verify(match) {
+					confidence = defaultVerifiedConfidence
+					if rule.verifiedConfidence != 0 {
+						confidence = rule.verifiedConfidence
+					}
+				} else if rule.requiresVerification {
+					continue
+				}
+			}
+
+			span := utils.Span{Start: loc[0], End: loc[1]}
+			if secretGroupIdx != -1 && loc[2*secretGroupIdx] != -1 {
+				span = utils.Span{Start: loc[2*secretGroupIdx], End: loc[2*secretGroupIdx+1]}
+			}
+			findings = append(findings, utils.Finding{
+				RuleID:     rule.id,
+				Confidence: confidence,
+				Severity:   rule.severity,
+				Span:       span,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// lastKeySegment is the last segment of a path, or of the pointer of a field appended to it
+func lastKeySegment(path string) string {
+	return path[strings.LastIndexAny(path, "./")+1:]
+}
@@ -0,0 +1,206 @@
+package secretengine
+
+import (
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	highEntropyDetectorName = "high-entropy"
+
+	defaultEntropyMinLength    = 20
+	defaultEntropyKeywordBoost = 0.5
+)
+
+var (
+	defaultEntropyKeywords = []string{"password", "passwd", "pwd", "token", "secret", "apikey", "api_key", "api-key", "private_key", "credential"}
+
+	// default thresholds follow the ones of detect-secrets, hex tokens can't reach the entropy of base64 ones given their smaller alphabet
+	defaultEntropyThresholds = map[sascomv1.EntropyCharset]float64{
+		sascomv1.Base64Charset:       4.5,
+		sascomv1.HexCharset:          3.0,
+		sascomv1.AlphanumericCharset: 4.0,
+	}
+
+	// ordered from the widest to the narrowest charset, so that a token is reported under the widest class it belongs to
+	entropyCharsetOrder    = []sascomv1.EntropyCharset{sascomv1.Base64Charset, sascomv1.AlphanumericCharset, sascomv1.HexCharset}
+	entropyCharsetPatterns = map[sascomv1.EntropyCharset]string{
+		sascomv1.Base64Charset:       `[A-Za-z0-9+/_\-]+=*`,
+		sascomv1.AlphanumericCharset: `[A-Za-z0-9]+`,
+		sascomv1.HexCharset:          `[0-9a-fA-F]+`,
+	}
+)
+
+func init() {
+	detector, err := newEntropyDetector(nil)
+	if err != nil {
+		panic(err)
+	}
+	RegisterDetector(detector)
+}
+
+type entropyCharsetClass struct {
+	charset   sascomv1.EntropyCharset
+	tokens    *regexp.Regexp
+	threshold float64
+}
+
+// entropyDetector flags random-looking tokens, which are most likely generated secrets no format-based rule knows about
+type entropyDetector struct {
+	classes      []entropyCharsetClass
+	minLength    int
+	keywords     []string
+	keywordBoost float64
+}
+
+func newEntropyDetector(spec *sascomv1.EntropyDetectorSpec) (entropyDetector, error) {
+	if spec == nil {
+		spec = &sascomv1.EntropyDetectorSpec{}
+	}
+	detector := entropyDetector{
+		minLength:    defaultEntropyMinLength,
+		keywords:     defaultEntropyKeywords,
+		keywordBoost: defaultEntropyKeywordBoost,
+	}
+	if spec.MinLength > 0 {
+		detector.minLength = spec.MinLength
+	}
+	if len(spec.Keywords) != 0 {
+		detector.keywords = spec.Keywords
+	}
+	if spec.KeywordBoost != "" {
+		keywordBoost, err := strconv.ParseFloat(spec.KeywordBoost, 64)
+		if err != nil {
+			return entropyDetector{}, fmt.Errorf("invalid keyword boost '%s': %w", spec.KeywordBoost, err)
+		}
+		detector.keywordBoost = keywordBoost
+	}
+
+	thresholds := map[sascomv1.EntropyCharset]float64{}
+	if len(spec.Charsets) == 0 {
+		thresholds = utils.DeepCopyMap(defaultEntropyThresholds)
+	}
+	for _, charsetSpec := range spec.Charsets {
+		threshold, found := defaultEntropyThresholds[charsetSpec.Class]
+		if !found {
+			return entropyDetector{}, fmt.Errorf("unknown charset class '%s'", charsetSpec.Class)
+		}
+		if charsetSpec.Threshold != "" {
+			parsedThreshold, err := strconv.ParseFloat(charsetSpec.Threshold, 64)
+			if err != nil {
+				return entropyDetector{}, fmt.Errorf("invalid threshold '%s' for the charset class '%s': %w", charsetSpec.Threshold, charsetSpec.Class, err)
+			}
+			threshold = parsedThreshold
+		}
+		thresholds[charsetSpec.Class] = threshold
+	}
+
+	for _, charset := range entropyCharsetOrder {
+		threshold, enabled := thresholds[charset]
+		if !enabled {
+			continue
+		}
+		detector.classes = append(detector.classes, entropyCharsetClass{
+			charset:   charset,
+			tokens:    regexp.MustCompile(entropyCharsetPatterns[charset]),
+			threshold: threshold,
+		})
+	}
+	return detector, nil
+}
+
+func (d entropyDetector) Name() string {
+	return highEntropyDetectorName
+}
+
+func (d entropyDetector) Configure(spec *sascomv1.DetectorsSpec) (Detector, error) {
+	if spec == nil || spec.Entropy == nil {
+		return d, nil
+	}
+	return newEntropyDetector(spec.Entropy)
+}
+
+func (d entropyDetector) Detect(path string, value string) ([]utils.Finding, error) {
+	boost := 0.0
+	if d.keyNameHintsAtSecret(path) {
+		boost = d.keywordBoost
+	}
+
+	findings := []utils.Finding{}
+	for _, class := range d.classes {
+		for _, loc := range class.tokens.FindAllStringIndex(value, -1) {
+			span := utils.Span{Start: loc[0], End: loc[1]}
+			if span.End-span.Start < d.minLength || coveredByFindings(span, findings) {
+				continue
+			}
+			entropy := shannonEntropy(value[span.Start:span.End])
+			threshold := class.threshold - boost
+			if entropy < threshold {
+				continue
+			}
+			severity := utils.MediumSeverity
+			if boost > 0 {
+				severity = utils.HighSeverity
+			}
+			findings = append(findings, utils.Finding{
+				RuleID:     fmt.Sprintf("%s/%s", highEntropyDetectorName, class.charset),
+				Confidence: entropyConfidence(entropy, threshold, boost > 0),
+				Severity:   severity,
+				Span:       span,
+			})
+		}
+	}
+	return findings, nil
+}
+
+func (d entropyDetector) keyNameHintsAtSecret(path string) bool {
+	lowerPath := strings.ToLower(path)
+	for _, keyword := range d.keywords {
+		if strings.Contains(lowerPath, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+// entropyConfidence grows with the distance between the entropy of a token and the threshold it crossed
+func entropyConfidence(entropy float64, threshold float64, keywordFound bool) float64 {
+	confidence := 0.6 + (entropy-threshold)*0.2
+	if keywordFound {
+		confidence += 0.2
+	}
+	return math.Min(confidence, 1)
+}
+
+func coveredByFindings(span utils.Span, findings []utils.Finding) bool {
+	for _, finding := range findings {
+		if span.Start >= finding.Span.Start && span.End <= finding.Span.End {
+			return true
+		}
+	}
+	return false
+}
+
+// shannonEntropy is the average amount of bits of information carried by every character of the token
+func shannonEntropy(token string) float64 {
+	if len(token) == 0 {
+		return 0
+	}
+	frequencies := map[rune]int{}
+	total := 0
+	for _, char := range token {
+		frequencies[char]++
+		total++
+	}
+	entropy := 0.0
+	for _, count := range frequencies {
+		probability := float64(count) / float64(total)
+		entropy -= probability * math.Log2(probability)
+	}
+	return entropy
+}
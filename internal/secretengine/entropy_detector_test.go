@@ -0,0 +1,90 @@
+package secretengine
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+func TestShannonEntropy(t *testing.T) {
+	for token, want := range map[string]float64{
+		"":         0,
+		"aaaa":     0,
+		"abab":     1,
+		"abcd":     2,
+		"abcdefgh": 3,
+	} {
+		if got := shannonEntropy(token); math.Abs(got-want) > 1e-9 {
+			t.Errorf("%q: expected an entropy of %v, got %v", token, want, got)
+		}
+	}
+}
+
+// TestEntropyDetector guards that random-looking tokens get flagged under the widest charset they belong to,
+// the threshold being lowered when the key name hints at a secret.
+func TestEntropyDetector(t *testing.T) {
+	detector, err := newEntropyDetector(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, tc := range []struct {
+		path    string
+		value   string
+		want    []string
+		wantSev utils.Severity
+	}{
+		{path: "app.aws", value: "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY", want: []string{"high-entropy/base64"}, wantSev: utils.MediumSeverity},
+		{path: "app.commit", value: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b", want: []string{"high-entropy/hex"}, wantSev: utils.MediumSeverity},
+		{path: "app.motto", value: "correcthorsebatterystaple"},
+		// too short to be considered, however random
+		{path: "app.id", value: "Kx8pQ2mZ7v"},
+		// an entropy of log2(12) only crosses the alphanumeric threshold once lowered by the keyword boost
+		{path: "app.tag", value: "abcdefghijklabcdefghijkl"},
+		{path: "app.db_password", value: "abcdefghijklabcdefghijkl", want: []string{"high-entropy/alphanumeric"}, wantSev: utils.HighSeverity},
+	} {
+		findings, err := detector.Detect(tc.path, tc.value)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.path, err)
+			continue
+		}
+		ruleIDs := []string{}
+		for _, finding := range findings {
+			ruleIDs = append(ruleIDs, finding.RuleID)
+			if finding.Severity != tc.wantSev {
+				t.Errorf("%s: expected a %s severity, got %s", tc.path, tc.wantSev, finding.Severity)
+			}
+		}
+		if len(tc.want) == 0 {
+			tc.want = []string{}
+		}
+		if !reflect.DeepEqual(ruleIDs, tc.want) {
+			t.Errorf("%s: expected the findings %v, got %v", tc.path, tc.want, ruleIDs)
+		}
+	}
+}
+
+func TestEntropyDetectorConfiguration(t *testing.T) {
+	hexOnly, err := newEntropyDetector(&sascomv1.EntropyDetectorSpec{Charsets: []sascomv1.EntropyCharsetSpec{{Class: sascomv1.HexCharset, Threshold: "3.5"}}, MinLength: 8})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hexOnly.classes) != 1 || hexOnly.classes[0].threshold != 3.5 || hexOnly.minLength != 8 {
+		t.Errorf("the configuration didn't apply: %+v", hexOnly)
+	}
+	if findings, _ := hexOnly.Detect("app.aws", "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY"); len(findings) != 0 {
+		t.Errorf("a disabled charset still got flagged: %v", findings)
+	}
+
+	for name, spec := range map[string]*sascomv1.EntropyDetectorSpec{
+		"unknown charset":       {Charsets: []sascomv1.EntropyCharsetSpec{{Class: "octal"}}},
+		"invalid threshold":     {Charsets: []sascomv1.EntropyCharsetSpec{{Class: sascomv1.HexCharset, Threshold: "high"}}},
+		"invalid keyword boost": {KeywordBoost: "a lot"},
+	} {
+		if _, err := newEntropyDetector(spec); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package secretengine
+
+import (
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const exceptionRulePrefix = "exception:"
+
+// exception is a compiled ExceptionSpec, it only applies until it expires unless it stands for a deprecated whitelisted path
+type exception struct {
+	spec      sascomv1.ExceptionSpec
+	regex     *regexp.Regexp
+	permanent bool
+}
+
+// compileExceptions compiles the exceptions of a ConsulKV, an exception failing to compile is reported as a detector error and never applies
+func compileExceptions(specs []sascomv1.ExceptionSpec) ([]exception, []DetectorError) {
+	exceptions := []exception{}
+	detectorErrors := []DetectorError{}
+	for idx, spec := range specs {
+		ruleName := fmt.Sprintf("%s%d", exceptionRulePrefix, idx)
+		if (spec.Glob == "") == (spec.Regex == "") {
+			detectorErrors = append(detectorErrors, DetectorError{Rule: ruleName, Err: fmt.Errorf("exactly one of glob or regex must be set")})
+			continue
+		}
+		pattern := "^(?:" + spec.Regex + ")$"
+		if spec.Glob != "" {
+			pattern = utils.GlobToRegex(spec.Glob)
+		}
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			detectorErrors = append(detectorErrors, DetectorError{Rule: ruleName, Err: fmt.Errorf("invalid exception: %w", err)})
+			continue
+		}
+		exceptions = append(exceptions, exception{spec: spec, regex: regex})
+	}
+	return exceptions, detectorErrors
+}
+
+// whitelistExceptions turns the deprecated whitelisted paths into exceptions which never expire, an entry ending with '/' covers every key under it
+func whitelistExceptions(whitelistedPaths []string) []exception {
+	exceptions := []exception{}
+	for _, whitelistedPath := range whitelistedPaths {
+		key := strings.TrimPrefix(strings.ReplaceAll(whitelistedPath, ".", "/"), "/")
+		if key == "" {
+			continue
+		}
+		pattern := "^" + regexp.QuoteMeta(key) + "$"
+		if strings.HasSuffix(key, "/") {
+			pattern = "^" + regexp.QuoteMeta(key) + ".*$"
+		}
+		exceptions = append(exceptions, exception{
+			spec:      sascomv1.ExceptionSpec{Regex: pattern, Justification: "whitelisted path", Owner: "whitelisted_paths"},
+			regex:     regexp.MustCompile(pattern),
+			permanent: true,
+		})
+	}
+	return exceptions
+}
+
+func (e exception) activeAt(now time.Time) bool {
+	return e.permanent || now.Before(e.spec.ExpiresAt.Time)
+}
+
+// matchesPath matches the dotted configmap path in its slash separated Consul form
+func (e exception) matchesPath(path string) bool {
+	return e.regex.MatchString(strings.ReplaceAll(path, ".", "/"))
+}
+
+// isPathExcepted tells whether a path must not be scanned at all, which is the case when an active exception covers every rule of its current value
+func isPathExcepted(exceptions []exception, path string, fingerprint func() string, now time.Time) bool {
+	for _, exception := range exceptions {
+		if exception.spec.RuleID != "" || !exception.activeAt(now) || !exception.matchesPath(path) {
+			continue
+		}
+		if exception.spec.Fingerprint == "" || exception.spec.Fingerprint == fingerprint() {
+			return true
+		}
+	}
+	return false
+}
+
+// isFindingExcepted tells whether an active exception covers the finding on the value of the invalidation
+func isFindingExcepted(exceptions []exception, inv utils.Invalidation, finding utils.Finding, now time.Time) bool {
+	for _, exception := range exceptions {
+		if !exception.activeAt(now) || !exception.matchesPath(inv.Path) {
+			continue
+		}
+		if exception.spec.RuleID != "" && exception.spec.RuleID != finding.RuleID {
+			continue
+		}
+		if exception.spec.Fingerprint != "" && exception.spec.Fingerprint != inv.RedactedValue.Fingerprint {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// applyExceptions drops the findings covered by an active exception, along with the invalidations left without any finding
+func applyExceptions(exceptions []exception, invalidationsOutput utils.InvalidationsOutput, now time.Time) utils.InvalidationsOutput {
+	if len(exceptions) == 0 {
+		return invalidationsOutput
+	}
+	output := utils.InvalidationsOutput{}
+	for _, inv := range invalidationsOutput {
+		findings := []utils.Finding{}
+		for _, finding := range inv.Findings {
+			if !isFindingExcepted(exceptions, inv, finding, now) {
+				findings = append(findings, finding)
+			}
+		}
+		switch {
+		case len(findings) == len(inv.Findings):
+			output = append(output, inv)
+		case len(findings) != 0:
+			output = append(output, utils.NewInvalidation(inv.Path, inv.RedactedValue, findings))
+		}
+	}
+	return output
+}
+
+// newlyExpiredExceptions returns the exceptions expired by now whose expiry wasn't notified yet, along with the ids of every expired exception
+func newlyExpiredExceptions(exceptions []exception, notified []string, now time.Time) ([]sascomv1.ExceptionSpec, []string) {
+	newlyExpired := []sascomv1.ExceptionSpec{}
+	expiredIDs := []string{}
+	for _, exception := range exceptions {
+		if exception.activeAt(now) {
+			continue
+		}
+		id := exception.spec.ID()
+		expiredIDs = append(expiredIDs, id)
+		if !utils.ValueInSlice(id, notified) {
+			newlyExpired = append(newlyExpired, exception.spec)
+		}
+	}
+	return newlyExpired, expiredIDs
+}
@@ -0,0 +1,147 @@
+package secretengine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+func mustCompileExceptions(t *testing.T, specs ...sascomv1.ExceptionSpec) []exception {
+	exceptions, detectorErrors := compileExceptions(specs)
+	if len(detectorErrors) != 0 {
+		t.Fatalf("unexpected detector errors: %v", detectorErrors)
+	}
+	return exceptions
+}
+
+func TestExceptionMatchesPath(t *testing.T) {
+	until := metav1.NewTime(time.Now().Add(time.Hour))
+	for _, tc := range []struct {
+		spec    sascomv1.ExceptionSpec
+		path    string
+		matches bool
+	}{
+		{sascomv1.ExceptionSpec{Glob: "app/*/token"}, "app.payments.token", true},
+		{sascomv1.ExceptionSpec{Glob: "app/*/token"}, "app.payments.eu.token", false},
+		{sascomv1.ExceptionSpec{Glob: "app/**/token"}, "app.payments.eu.token", true},
+		{sascomv1.ExceptionSpec{Glob: "app/tok?n"}, "app.token", true},
+		{sascomv1.ExceptionSpec{Glob: "app/tok?n"}, "app.tokn", false},
+		{sascomv1.ExceptionSpec{Regex: "app/(token|secret)"}, "app.secret", true},
+		// a regex must match the whole key
+		{sascomv1.ExceptionSpec{Regex: "app/token"}, "app.token.old", false},
+		{sascomv1.ExceptionSpec{Regex: "token"}, "app.token", false},
+	} {
+		tc.spec.ExpiresAt = until
+		exceptions := mustCompileExceptions(t, tc.spec)
+		if got := exceptions[0].matchesPath(tc.path); got != tc.matches {
+			t.Errorf("%s on %s: expected %v, got %v", tc.spec.ID(), tc.path, tc.matches, got)
+		}
+	}
+}
+
+func TestCompileExceptionsRefusesAmbiguousOnes(t *testing.T) {
+	_, detectorErrors := compileExceptions([]sascomv1.ExceptionSpec{{}, {Glob: "a/*", Regex: "a/.*"}, {Regex: "(["}})
+	if len(detectorErrors) != 3 {
+		t.Errorf("expected 3 detector errors, got %v", detectorErrors)
+	}
+}
+
+// TestExceptionScoping guards that an exception only silences the findings of its rule and fingerprint, and only until it expires
+func TestExceptionScoping(t *testing.T) {
+	now := time.Now()
+	inv := utils.NewInvalidation("app.token", utils.RedactedValue{Fingerprint: "hmac-sha256:current"}, []utils.Finding{
+		{RuleID: "github-token", Confidence: 1, Severity: utils.HighSeverity},
+		{RuleID: "high-entropy", Confidence: 0.5, Severity: utils.MediumSeverity},
+	})
+	for _, tc := range []struct {
+		name string
+		spec sascomv1.ExceptionSpec
+		want []string
+	}{
+		{"whole key", sascomv1.ExceptionSpec{Glob: "app/token", ExpiresAt: metav1.NewTime(now.Add(time.Hour))}, nil},
+		{"rule", sascomv1.ExceptionSpec{Glob: "app/token", RuleID: "high-entropy", ExpiresAt: metav1.NewTime(now.Add(time.Hour))}, []string{"github-token"}},
+		{"fingerprint", sascomv1.ExceptionSpec{Glob: "app/token", Fingerprint: "hmac-sha256:current", ExpiresAt: metav1.NewTime(now.Add(time.Hour))}, nil},
+		{"other fingerprint", sascomv1.ExceptionSpec{Glob: "app/token", Fingerprint: "hmac-sha256:previous", ExpiresAt: metav1.NewTime(now.Add(time.Hour))}, []string{"github-token", "high-entropy"}},
+		{"expired", sascomv1.ExceptionSpec{Glob: "app/token", ExpiresAt: metav1.NewTime(now.Add(-time.Minute))}, []string{"github-token", "high-entropy"}},
+		{"other key", sascomv1.ExceptionSpec{Glob: "app/password", ExpiresAt: metav1.NewTime(now.Add(time.Hour))}, []string{"github-token", "high-entropy"}},
+	} {
+		output := applyExceptions(mustCompileExceptions(t, tc.spec), utils.InvalidationsOutput{inv}, now)
+		var got []string
+		for _, remaining := range output {
+			for _, finding := range remaining.Findings {
+				got = append(got, finding.RuleID)
+			}
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected the findings %v to remain, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestIsPathExcepted(t *testing.T) {
+	now := time.Now()
+	fingerprint := func() string { return "hmac-sha256:current" }
+	for _, tc := range []struct {
+		name     string
+		spec     sascomv1.ExceptionSpec
+		excepted bool
+	}{
+		{"whole key", sascomv1.ExceptionSpec{Glob: "app/**", ExpiresAt: metav1.NewTime(now.Add(time.Hour))}, true},
+		{"same fingerprint", sascomv1.ExceptionSpec{Glob: "app/**", Fingerprint: "hmac-sha256:current", ExpiresAt: metav1.NewTime(now.Add(time.Hour))}, true},
+		{"new value", sascomv1.ExceptionSpec{Glob: "app/**", Fingerprint: "hmac-sha256:previous", ExpiresAt: metav1.NewTime(now.Add(time.Hour))}, false},
+		// a rule scoped exception leaves the other rules to scan the key
+		{"rule", sascomv1.ExceptionSpec{Glob: "app/**", RuleID: "email", ExpiresAt: metav1.NewTime(now.Add(time.Hour))}, false},
+		{"expired", sascomv1.ExceptionSpec{Glob: "app/**", ExpiresAt: metav1.NewTime(now.Add(-time.Hour))}, false},
+	} {
+		if got := isPathExcepted(mustCompileExceptions(t, tc.spec), "app.token", fingerprint, now); got != tc.excepted {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.excepted, got)
+		}
+	}
+}
+
+func TestNewlyExpiredExceptions(t *testing.T) {
+	now := time.Now()
+	expired := sascomv1.ExceptionSpec{Glob: "app/old", ExpiresAt: metav1.NewTime(now.Add(-time.Hour))}
+	notified := sascomv1.ExceptionSpec{Glob: "app/older", ExpiresAt: metav1.NewTime(now.Add(-2 * time.Hour))}
+	active := sascomv1.ExceptionSpec{Glob: "app/new", ExpiresAt: metav1.NewTime(now.Add(time.Hour))}
+	exceptions := append(mustCompileExceptions(t, expired, notified, active), whitelistExceptions([]string{"app/legacy"})...)
+
+	newlyExpired, expiredIDs := newlyExpiredExceptions(exceptions, []string{notified.ID()}, now)
+	if len(newlyExpired) != 1 || newlyExpired[0].ID() != expired.ID() {
+		t.Errorf("expected only %s to be notified, got %v", expired.ID(), newlyExpired)
+	}
+	if want := []string{expired.ID(), notified.ID()}; !reflect.DeepEqual(expiredIDs, want) {
+		t.Errorf("expected the expired ids %v, got %v", want, expiredIDs)
+	}
+}
+
+// TestWhitelistedPathsNeverExpire guards that the deprecated whitelisted paths keep shielding their keys after the upgrade to exceptions
+func TestWhitelistedPathsNeverExpire(t *testing.T) {
+	exceptions := whitelistExceptions([]string{"app/legacy/", "app/token", "", "/app/v1.2"})
+	farFuture := time.Now().Add(100 * 365 * 24 * time.Hour)
+	for path, excepted := range map[string]bool{
+		"app.legacy.password": true,
+		"app.legacy.a.b":      true,
+		"app.legacyish":       false,
+		"app.token":           true,
+		"app.token.old":       false,
+		"app.tokens":          false,
+		"app.v1.2":            true,
+		"app.v1x2":            false,
+	} {
+		if got := isPathExcepted(exceptions, path, func() string { return "" }, farFuture); got != excepted {
+			t.Errorf("%s: expected %v, got %v", path, excepted, got)
+		}
+	}
+
+	consulKv := &sascomv1.ConsulKV{Spec: sascomv1.ConsulKVSpec{GuardAgainst: []string{"email"}, WhitelistedPaths: []string{"app/legacy/"}}}
+	scan := getInvalidations(consulKv, map[string]string{"app.legacy.owner": "jane.doe@example.com", "app.owner": "jane.doe@example.com"}, utils.NewRedactor([]byte("test-key")))
+	if paths := scan.invalidationsOutput.Paths(); !reflect.DeepEqual(paths, []string{"app.owner"}) {
+		t.Errorf("expected only app.owner to be flagged, got %v", paths)
+	}
+}
@@ -0,0 +1,69 @@
+package secretengine
+
+import (
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"regexp"
+	"strings"
+)
+
+const (
+	keyRulePrefix = "key:"
+	// a key name hints at a secret without proving anything about the value it holds
+	keyRuleConfidence = 0.5
+)
+
+type keyRule struct {
+	name     string
+	regex    *regexp.Regexp
+	severity utils.Severity
+}
+
+// compileKeyRules compiles the key rules of a ConsulKV, a rule failing to compile is reported as a detector error rather than silently ignored
+func compileKeyRules(specs []sascomv1.KeyRuleSpec) ([]keyRule, []DetectorError) {
+	rules := []keyRule{}
+	detectorErrors := []DetectorError{}
+	for _, spec := range specs {
+		ruleName := keyRulePrefix + spec.Name
+		if (spec.Glob == "") == (spec.Regex == "") {
+			detectorErrors = append(detectorErrors, DetectorError{Rule: ruleName, Err: fmt.Errorf("exactly one of glob or regex must be set")})
+			continue
+		}
+		pattern := spec.Regex
+		if spec.Glob != "" {
+			pattern = utils.GlobToRegex(spec.Glob)
+		}
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			detectorErrors = append(detectorErrors, DetectorError{Rule: ruleName, Err: fmt.Errorf("invalid key rule: %w", err)})
+			continue
+		}
+		severity := utils.Severity(spec.Severity)
+		if severity == "" {
+			severity = utils.MediumSeverity
+		}
+		rules = append(rules, keyRule{name: ruleName, regex: regex, severity: severity})
+	}
+	return rules, detectorErrors
+}
+
+// matchKeyRules returns a finding for every key rule matching the path, the dotted configmap path being matched in its slash separated Consul form
+func matchKeyRules(rules []keyRule, path string) []utils.Finding {
+	findings := []utils.Finding{}
+	slashedPath := strings.ReplaceAll(path, ".", "/")
+	for _, rule := range rules {
+		loc := rule.regex.FindStringIndex(slashedPath)
+		if loc == nil {
+			continue
+		}
+		findings = append(findings, utils.Finding{
+			RuleID:     rule.name,
+			Confidence: keyRuleConfidence,
+			Severity:   rule.severity,
+			OnKey:      true,
+			Span:       utils.Span{Start: loc[0], End: loc[1]},
+		})
+	}
+	return findings
+}
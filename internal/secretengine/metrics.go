@@ -0,0 +1,47 @@
+package secretengine
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	scanCacheHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "consulkv_scan_cache_hits_total",
+		Help: "Values whose previous scan result got reused as neither their ModifyIndex nor the applicable rules changed.",
+	}, []string{"consulkv"})
+	scanCacheMisses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "consulkv_scan_cache_misses_total",
+		Help: "Values which had to be scanned again.",
+	}, []string{"consulkv"})
+	scanCacheEntries = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "consulkv_scan_cache_entries",
+		Help: "Scan results currently cached.",
+	}, []string{"consulkv"})
+	certificateNotAfter = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "consulkv_certificate_not_after_timestamp_seconds",
+		Help: "Expiry time of the certificates found in values, as a unix timestamp.",
+	}, []string{"consulkv", "path", "subject"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(scanCacheHits, scanCacheMisses, scanCacheEntries, certificateNotAfter)
+}
+
+// recordCertificateExpiries replaces the certificate expiries of a ConsulKV with the ones of its latest scan
+func recordCertificateExpiries(consulKvKey string, certificates []sascomv1.CertificateStatus) {
+	certificateNotAfter.DeletePartialMatch(prometheus.Labels{"consulkv": consulKvKey})
+	for _, certificate := range certificates {
+		certificateNotAfter.WithLabelValues(consulKvKey, certificate.Path, certificate.Subject).Set(float64(certificate.NotAfter.Unix()))
+	}
+}
+
+// forgetConsulKVMetrics drops every series of a deleted ConsulKV
+func forgetConsulKVMetrics(consulKvKey string) {
+	labels := prometheus.Labels{"consulkv": consulKvKey}
+	scanCacheHits.DeletePartialMatch(labels)
+	scanCacheMisses.DeletePartialMatch(labels)
+	scanCacheEntries.DeletePartialMatch(labels)
+	certificateNotAfter.DeletePartialMatch(labels)
+}
@@ -0,0 +1,195 @@
+package secretengine
+
+import (
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	piiPackName = "pii"
+	// bump whenever a rule of the pack is added, removed or changes what it matches
+	piiPackVersion = "1.1.0"
+)
+
+// every PII rule requires its checksum to hold, the pattern alone yields way too many false positives to be trusted with self-heal
+var piiRules = []packRule{
+	{
+		id:                   "credit-card",
+		severity:             utils.HighSeverity,
+		confidence:           0.3,
+		regex:                regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		verify:               isPaymentCardNumber,
+		verifiedConfidence:   0.9,
+		requiresVerification: true,
+	},
+	{
+		id:                   "iban",
+		severity:             utils.MediumSeverity,
+		confidence:           0.3,
+		regex:                regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`),
+		verify:               isIban,
+		verifiedConfidence:   0.95,
+		requiresVerification: true,
+	},
+	{
+		id:                   "us-ssn",
+		severity:             utils.HighSeverity,
+		confidence:           0.3,
+		regex:                regexp.MustCompile(`\b(?:\d{3}-\d{2}-\d{4}|\d{3} \d{2} \d{4})\b`),
+		verify:               isUsSsn,
+		verifiedConfidence:   0.7,
+		requiresVerification: true,
+	},
+	{
+		id:                   "ca-sin",
+		severity:             utils.HighSeverity,
+		confidence:           0.3,
+		regex:                regexp.MustCompile(`\b(?:\d{3}-\d{3}-\d{3}|\d{3} \d{3} \d{3})\b`),
+		verify:               isCanadianSin,
+		verifiedConfidence:   0.6,
+		requiresVerification: true,
+	},
+	// about one in ten bare 9 digit numbers passes the Luhn checksum, so they only count when labelled as a SIN
+	{
+		id:                   "ca-sin",
+		severity:             utils.HighSeverity,
+		confidence:           0.3,
+		regex:                regexp.MustCompile(`(?i)\b(?:sin|social[ _-]?insurance(?:[ _-]?(?:number|num|no|nr))?)\s*[:=#]?\s*(?P<secret>\d{9})\b`),
+		verify:               isCanadianSin,
+		verifiedConfidence:   0.6,
+		requiresVerification: true,
+	},
+	{
+		id:                   "ca-sin",
+		severity:             utils.HighSeverity,
+		confidence:           0.3,
+		regex:                regexp.MustCompile(`\b\d{9}\b`),
+		verify:               isCanadianSin,
+		verifiedConfidence:   0.6,
+		requiresVerification: true,
+		keyContext:           regexp.MustCompile(`(?i)^(?:ca[_-]?)?(?:sin|social[_-]?insurance(?:[_-]?(?:number|num|no|nr))?)$`),
+	},
+}
+
+// ibanLengths holds the length of the IBANs of the countries they are most commonly seen from
+var ibanLengths = map[string]int{
+	"AD": 24, "AE": 23, "AT": 20, "BE": 16, "BG": 22, "BH": 22, "BR": 29, "CH": 21, "CY": 28, "CZ": 24,
+	"DE": 22, "DK": 18, "EE": 20, "ES": 24, "FI": 18, "FR": 27, "GB": 22, "GI": 23, "GR": 27, "HR": 21,
+	"HU": 28, "IE": 22, "IL": 23, "IS": 26, "IT": 27, "KW": 30, "KZ": 20, "LI": 21, "LT": 20, "LU": 20,
+	"LV": 21, "MC": 27, "MT": 31, "NL": 18, "NO": 15, "PK": 24, "PL": 28, "PT": 25, "QA": 29, "RO": 24,
+	"RS": 22, "SA": 24, "SE": 24, "SI": 19, "SK": 24, "SM": 27, "TR": 26, "UA": 29,
+}
+
+func init() {
+	registerDetectorPack(piiPackName, piiPackVersion, piiRules)
+}
+
+func isPaymentCardNumber(match string) bool {
+	digits := onlyDigits(match)
+	if len(digits) < 13 || len(digits) > 19 || !luhnValid(digits) {
+		return false
+	}
+	return hasKnownCardPrefix(digits)
+}
+
+// hasKnownCardPrefix checks the issuer identification number against the ranges of the major card networks
+func hasKnownCardPrefix(digits string) bool {
+	prefix := func(length int) int {
+		value, _ := strconv.Atoi(digits[:length])
+		return value
+	}
+	switch {
+	case digits[0] == '4': // Visa
+		return true
+	case prefix(2) >= 51 && prefix(2) <= 55, prefix(4) >= 2221 && prefix(4) <= 2720: // Mastercard
+		return len(digits) == 16
+	case prefix(2) == 34, prefix(2) == 37: // American Express
+		return len(digits) == 15
+	case prefix(4) == 6011, prefix(2) == 65, prefix(3) >= 644 && prefix(3) <= 649: // Discover
+		return true
+	case prefix(4) >= 3528 && prefix(4) <= 3589: // JCB
+		return true
+	case prefix(2) == 36, prefix(2) == 38, prefix(3) >= 300 && prefix(3) <= 305: // Diners Club
+		return true
+	}
+	return false
+}
+
+// isIban checks the length expected for the country of the IBAN and its ISO 13616 mod-97 checksum
+func isIban(match string) bool {
+	iban := strings.ReplaceAll(match, " ", "")
+	if expectedLength, found := ibanLengths[iban[:2]]; !found || len(iban) != expectedLength {
+		return false
+	}
+	rearranged := iban[4:] + iban[:4]
+	numeric := strings.Builder{}
+	for _, char := range rearranged {
+		switch {
+		case char >= '0' && char <= '9':
+			numeric.WriteRune(char)
+		case char >= 'A' && char <= 'Z':
+			numeric.WriteString(strconv.Itoa(int(char-'A') + 10))
+		default:
+			return false
+		}
+	}
+	value, ok := new(big.Int).SetString(numeric.String(), 10)
+	if !ok {
+		return false
+	}
+	return new(big.Int).Mod(value, big.NewInt(97)).Int64() == 1
+}
+
+// isUsSsn applies the rules of the Social Security Administration: the area is never 000, 666 or 9xx, the group never 00 and the serial never 0000
+func isUsSsn(match string) bool {
+	digits := onlyDigits(match)
+	area, group, serial := digits[:3], digits[3:5], digits[5:]
+	if area == "000" || area == "666" || area[0] == '9' || group == "00" || serial == "0000" {
+		return false
+	}
+	// numbers which were famously published as examples and never belonged to anyone
+	switch digits {
+	case "078051120", "219099999", "123456789":
+		return false
+	}
+	return true
+}
+
+// isCanadianSin checks the Luhn checksum of the number, a SIN never starts with 0 nor with 8
+func isCanadianSin(match string) bool {
+	digits := onlyDigits(match)
+	if len(digits) != 9 || digits[0] == '0' || digits[0] == '8' {
+		return false
+	}
+	return luhnValid(digits)
+}
+
+func luhnValid(digits string) bool {
+	sum := 0
+	double := false
+	for idx := len(digits) - 1; idx >= 0; idx-- {
+		digit := int(digits[idx] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func onlyDigits(value string) string {
+	digits := strings.Builder{}
+	for _, char := range value {
+		if char >= '0' && char <= '9' {
+			digits.WriteRune(char)
+		}
+	}
+	return digits.String()
+}
@@ -0,0 +1,110 @@
+package secretengine
+
+import (
+	"testing"
+)
+
+func TestLuhnValid(t *testing.T) {
+	for digits, valid := range map[string]bool{
+		"4111111111111111": true,
+		"4111111111111112": false,
+		"79927398713":      true,
+		"79927398710":      false,
+		"130692544":        true,
+		"0":                true,
+	} {
+		if got := luhnValid(digits); got != valid {
+			t.Errorf("%s: expected %v, got %v", digits, valid, got)
+		}
+	}
+}
+
+func TestIsIban(t *testing.T) {
+	for match, valid := range map[string]bool{
+		"GB82WEST12345698765432":      true,
+		"GB82 WEST 1234 5698 7654 32": true,
+		"DE89370400440532013000":      true,
+		"DE89370400440532013001":      false,
+		"GB82WEST1234569876543":       false, // too short for a British IBAN
+		"XX82WEST12345698765432":      false, // unknown country
+		"FR1420041010050500013M02606": true,
+		"FR1420041010050500013M02607": false,
+		"NL91 ABNA 0417 1643 00":      true,
+		"NL91 ABNA 0417 1643 0@":      false,
+	} {
+		if got := isIban(match); got != valid {
+			t.Errorf("%s: expected %v, got %v", match, valid, got)
+		}
+	}
+}
+
+func TestIsUsSsn(t *testing.T) {
+	for match, valid := range map[string]bool{
+		"536-22-1234": true,
+		"536 22 1234": true,
+		"000-22-1234": false,
+		"666-22-1234": false,
+		"936-22-1234": false,
+		"536-00-1234": false,
+		"536-22-0000": false,
+		"078-05-1120": false, // the Woolworth wallet card
+		"123-45-6789": false,
+	} {
+		if got := isUsSsn(match); got != valid {
+			t.Errorf("%s: expected %v, got %v", match, valid, got)
+		}
+	}
+}
+
+func TestIsCanadianSin(t *testing.T) {
+	for match, valid := range map[string]bool{
+		"130 692 544": true,
+		"130-692-544": true,
+		"130692544":   true,
+		"130692545":   false,
+		"046454286":   false, // Luhn valid, yet no SIN starts with 0
+		"830692547":   false, // nor with 8
+		"13069254":    false,
+	} {
+		if got := isCanadianSin(match); got != valid {
+			t.Errorf("%s: expected %v, got %v", match, valid, got)
+		}
+	}
+}
+
+// TestBareCanadianSinNeedsContext guards that a bare 9 digit number, a tenth of which pass the Luhn checksum, is only reported as a SIN
+// when its key or a label in the value says so.
+func TestBareCanadianSinNeedsContext(t *testing.T) {
+	detector, found := lookupDetector("ca-sin")
+	if !found {
+		t.Fatalf("the ca-sin rule isn't registered")
+	}
+	for _, tc := range []struct {
+		path  string
+		value string
+		found bool
+	}{
+		{"app.sin", "130692544", true},
+		{"app.employee/social_insurance_number", "130692544", true},
+		{"app.socialInsuranceNumber", "130692544", true},
+		{"app.order_id", "130692544", false},
+		{"app.cousin", "130692544", false},
+		{"app.note", "SIN: 130692544", true},
+		{"app.note", "social insurance number 130692544", true},
+		{"app.note", "order 130692544", false},
+		{"app.note", "130 692 544", true},
+		{"app.sin", "130692545", false},
+	} {
+		findings, err := detector.Detect(tc.path, tc.value)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if found := len(findings) != 0; found != tc.found {
+			t.Errorf("%s=%q: expected a finding = %v, got %v", tc.path, tc.value, tc.found, findings)
+			continue
+		}
+		if tc.found && tc.value[findings[0].Span.Start:findings[0].Span.End] != "130692544" && tc.value[findings[0].Span.Start:findings[0].Span.End] != "130 692 544" {
+			t.Errorf("%s=%q: unexpected span %+v", tc.path, tc.value, findings[0].Span)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package secretengine
+
+import (
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/policyreport"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sensitiveDataCategory stands for the findings without a category in the policy reports
+const sensitiveDataCategory = "sensitive-data"
+
+// multiRuleDetector is implemented by the detectors reporting their findings under other rule ids than their name
+type multiRuleDetector interface {
+	RuleIDs() []string
+}
+
+func (d packDetector) RuleIDs() []string {
+	ruleIDs := []string{}
+	for _, rule := range d.rules {
+		ruleIDs = append(ruleIDs, rule.id)
+	}
+	return ruleIDs
+}
+
+func (d certificateDetector) RuleIDs() []string {
+	return []string{"certificate-expired", "certificate-expiring", "certificate-self-signed", "certificate-weak-key"}
+}
+
+func (d breachedCredentialsDetector) RuleIDs() []string {
+	return []string{breachedCredentialRuleID}
+}
+
+// evaluatedRule is a rule a scan evaluated along with the guard it belongs to, a rule covering the findings reported either under its id or under '<id>/...'
+type evaluatedRule struct {
+	guard string
+	rule  string
+}
+
+// evaluatedRules lists the rules a scan evaluated
+func evaluatedRules(rules ruleSet, policiesEvaluated bool) []evaluatedRule {
+	evaluated := []evaluatedRule{}
+	for _, detector := range rules.detectors {
+		if multiRule, ok := detector.(multiRuleDetector); ok {
+			for _, ruleID := range multiRule.RuleIDs() {
+				evaluated = append(evaluated, evaluatedRule{guard: detector.Name(), rule: ruleID})
+			}
+			continue
+		}
+		evaluated = append(evaluated, evaluatedRule{guard: detector.Name(), rule: detector.Name()})
+	}
+	for _, rule := range rules.keyRules {
+		evaluated = append(evaluated, evaluatedRule{guard: rule.name, rule: rule.name})
+	}
+	if policiesEvaluated {
+		guard := strings.TrimSuffix(regoRulePrefix, "/")
+		evaluated = append(evaluated, evaluatedRule{guard: guard, rule: guard})
+	}
+	if len(rules.validations) != 0 {
+		guard := strings.TrimSuffix(validationRulePrefix, "/")
+		evaluated = append(evaluated, evaluatedRule{guard: guard, rule: guard})
+	}
+	// a pack may be referenced along with some of its own rules
+	deduplicated := []evaluatedRule{}
+	seen := map[string]bool{}
+	for _, rule := range evaluated {
+		if !seen[rule.rule] {
+			seen[rule.rule] = true
+			deduplicated = append(deduplicated, rule)
+		}
+	}
+	return deduplicated
+}
+
+// guardOf is the guard of the evaluated rule covering the rule id, the rule id itself when none does
+func guardOf(evaluated []evaluatedRule, ruleID string) string {
+	for _, rule := range evaluated {
+		if ruleCovers(rule.rule, ruleID) {
+			return rule.guard
+		}
+	}
+	return ruleID
+}
+
+func ruleCovers(rule string, ruleID string) bool {
+	return ruleID == rule || strings.HasPrefix(ruleID, rule+"/")
+}
+
+// policyReportResults turns the outcome of a scan into the results of the PolicyReport of the ConsulKV, each under the guard its rule belongs to.
+// A finding fails when its severity is high or critical and warns otherwise, an evaluated rule without any finding nor error passes.
+// Values only show up through their fingerprint and masked preview.
+func policyReportResults(item *sascomv1.ConsulKV, evaluated []evaluatedRule, invalidationsOutput utils.InvalidationsOutput, absentKeys []absentKeyFinding, detectorErrors []DetectorError) []policyreport.Result {
+	now := metav1.Timestamp{Seconds: time.Now().Unix()}
+	resource := corev1.ObjectReference{
+		APIVersion: sascomv1.GroupVersion.String(),
+		Kind:       "ConsulKV",
+		Namespace:  item.Namespace,
+		Name:       item.Name,
+		UID:        item.UID,
+	}
+	newResult := func(rule string, status policyreport.ResultStatus) policyreport.Result {
+		return policyreport.Result{
+			Source:    policyreport.Source,
+			Policy:    guardOf(evaluated, rule),
+			Rule:      rule,
+			Timestamp: now,
+			Result:    status,
+			Scored:    true,
+			Resources: []corev1.ObjectReference{resource},
+		}
+	}
+
+	results := []policyreport.Result{}
+	reportedRules := []string{}
+	for _, inv := range invalidationsOutput {
+		reported := map[string]bool{}
+		for _, finding := range inv.Findings {
+			if reported[finding.RuleID] {
+				continue
+			}
+			reported[finding.RuleID] = true
+			reportedRules = append(reportedRules, finding.RuleID)
+
+			category := string(finding.Category)
+			if category == "" {
+				category = sensitiveDataCategory
+			}
+			result := newResult(finding.RuleID, findingStatus(finding))
+			result.Category = category
+			result.Severity = string(finding.Severity)
+			result.Message = fmt.Sprintf("the rule %s flagged the key %s", finding.RuleID, inv.Path)
+			result.Properties = map[string]string{
+				"path":        inv.Path,
+				"fingerprint": inv.Fingerprint,
+				"preview":     inv.Preview,
+				"confidence":  fmt.Sprintf("%.2f", finding.Confidence),
+			}
+			results = append(results, result)
+		}
+	}
+	for _, absentKey := range absentKeys {
+		reportedRules = append(reportedRules, absentKey.finding.RuleID)
+		result := newResult(absentKey.finding.RuleID, findingStatus(absentKey.finding))
+		result.Category = string(absentKey.finding.Category)
+		result.Severity = string(absentKey.finding.Severity)
+		result.Message = fmt.Sprintf("the rule %s reported the key %s which is missing from the payload", absentKey.finding.RuleID, absentKey.path)
+		if absentKey.message != "" {
+			result.Message += ": " + absentKey.message
+		}
+		result.Properties = map[string]string{
+			"path":       absentKey.path,
+			"confidence": fmt.Sprintf("%.2f", absentKey.finding.Confidence),
+		}
+		results = append(results, result)
+	}
+	for _, detectorError := range detectorErrors {
+		reportedRules = append(reportedRules, detectorError.Rule)
+		result := newResult(detectorError.Rule, policyreport.Error)
+		result.Message = detectorError.Err.Error()
+		if detectorError.Path != "" {
+			result.Properties = map[string]string{"path": detectorError.Path}
+		}
+		results = append(results, result)
+	}
+	for _, rule := range evaluated {
+		passed := true
+		for _, reportedRule := range reportedRules {
+			if ruleCovers(rule.rule, reportedRule) {
+				passed = false
+				break
+			}
+		}
+		if passed {
+			results = append(results, newResult(rule.rule, policyreport.Pass))
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Rule != results[j].Rule {
+			return results[i].Rule < results[j].Rule
+		}
+		return results[i].Properties["path"] < results[j].Properties["path"]
+	})
+	return results
+}
+
+// findingStatus fails the findings whose severity is high or critical and warns about the others
+func findingStatus(finding utils.Finding) policyreport.ResultStatus {
+	if finding.Severity.Rank() >= utils.HighSeverity.Rank() {
+		return policyreport.Fail
+	}
+	return policyreport.Warn
+}
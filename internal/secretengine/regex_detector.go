@@ -0,0 +1,77 @@
+package secretengine
+
+import (
+	"fmt"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"regexp"
+)
+
+// rawRegexConfidence is the confidence of a regex written straight into a guard, the author of the ConsulKV vouches for it
+const rawRegexConfidence = 1.0
+
+var (
+	predefinedRegexAliases = map[string]string{
+		"email":           "^[\\w-\\.]+@([\\w-]+\\.)+[\\w-]{2,4}$",
+		"contact":         "^\\s*(?:\\+?(\\d{1,3}))?([-. (]*(\\d{3})[-. )]*)?((\\d{3})[-. ]*(\\d{2,4})(?:[-.x ]*(\\d+))?)\\s*$",
+		"bitcoin-address": "([13][a-km-zA-HJ-NP-Z0-9]{26,33})",
+		"badwords":        "\\b(?:(?:ass+(?:\\s+)?|i+(?:\\s+)?|butt+(?:\\s+)?|mo(?:(?:m|t|d)h?(?:e|a)?r?)(?:\\s+)?)?f(?:(?:\\s+)?u+)?(?:(?:\\s+)?c+)?(?:(?:\\s+)?k+)?(?:(?:e|a)(?:r+)?|i(?:n(?:g)?)?)?(?:s+)?(?:\\s+)?(?:hole|head|(?:yo?)?u?)?)+\\b",
+		"html-tags":       "</?\\w+((\\s+\\w+(\\s*=\\s*(?:\".*?\"|'.*?'|[^'\">\\s]+))?)+\\s*|\\s*)/?>",
+		"sql-injection":   "\"((SELECT|DELETE|UPDATE|INSERT INTO) (\\*|[A-Z0-9_]+) (FROM) ([A-Z0-9_]+))( (WHERE) ([A-Z0-9_]+) (=|<|>|>=|<=|==|!=) (\\?|\\$[A-Z]{1}[A-Z_]+)( (AND) ([A-Z0-9_]+) (=|<|>|>=|<=|==|!=) (\\?))?)?\"",
+	}
+
+	// the looser an alias, the more false positives it yields, hence the less confident its findings are
+	predefinedRegexAliasConfidences = map[string]float64{
+		"email":           0.9,
+		"contact":         0.5,
+		"bitcoin-address": 0.6,
+		"badwords":        0.7,
+		"html-tags":       0.8,
+		"sql-injection":   0.8,
+	}
+)
+
+func init() {
+	for alias, regex := range predefinedRegexAliases {
+		RegisterDetector(mustRegexDetector(alias, regex, predefinedRegexAliasConfidences[alias]))
+	}
+}
+
+type regexDetector struct {
+	name       string
+	regex      *regexp.Regexp
+	confidence float64
+	severity   utils.Severity
+}
+
+func newRegexDetector(name string, regex string, confidence float64) (regexDetector, error) {
+	r, err := regexp.Compile(regex)
+	if err != nil {
+		return regexDetector{}, fmt.Errorf("validation regex '%s' failed to get compiled: %w", regex, err)
+	}
+	return regexDetector{name: name, regex: r, confidence: confidence, severity: utils.MediumSeverity}, nil
+}
+
+func mustRegexDetector(name string, regex string, confidence float64) regexDetector {
+	detector, err := newRegexDetector(name, regex, confidence)
+	if err != nil {
+		panic(err)
+	}
+	return detector
+}
+
+func (d regexDetector) Name() string {
+	return d.name
+}
+
+func (d regexDetector) Detect(_ string, value string) ([]utils.Finding, error) {
+	findings := []utils.Finding{}
+	for _, loc := range d.regex.FindAllStringIndex(value, -1) {
+		findings = append(findings, utils.Finding{
+			RuleID:     d.name,
+			Confidence: d.confidence,
+			Severity:   d.severity,
+			Span:       utils.Span{Start: loc[0], End: loc[1]},
+		})
+	}
+	return findings, nil
+}
@@ -0,0 +1,208 @@
+package secretengine
+
+import (
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/regopolicy"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const regoRulePrefix = "rego/"
+
+// regoPolicyCache keeps the compiled policies of every ConsulKV until their source changes
+type regoPolicyCache struct {
+	lock    *sync.Mutex
+	entries map[string]cachedRegoPolicies
+}
+
+type cachedRegoPolicies struct {
+	version   string
+	evaluator regopolicy.Evaluator
+	err       error
+}
+
+func newRegoPolicyCache() *regoPolicyCache {
+	return &regoPolicyCache{
+		lock:    &sync.Mutex{},
+		entries: map[string]cachedRegoPolicies{},
+	}
+}
+
+// get returns the compiled policies, a compile error is remembered too so that broken policies aren't compiled again on every scan
+func (c *regoPolicyCache) get(consulKvKey string, modules map[string]string) (regopolicy.Evaluator, error) {
+	version := regopolicy.Version(modules)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if cached, found := c.entries[consulKvKey]; found && cached.version == version {
+		return cached.evaluator, cached.err
+	}
+	evaluator, err := regopolicy.Compile(modules)
+	c.entries[consulKvKey] = cachedRegoPolicies{version: version, evaluator: evaluator, err: err}
+	return evaluator, err
+}
+
+// absentKeyFinding is a finding of the Rego policies about a key missing from the payload, like a required key nobody wrote yet
+type absentKeyFinding struct {
+	// path is the dotted configmap path the key would have
+	path    string
+	finding utils.Finding
+	message string
+}
+
+// evict forgets the compiled policies of a deleted ConsulKV
+func (c *regoPolicyCache) evict(consulKvKey string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.entries, consulKvKey)
+}
+
+// evaluateRegoPolicies runs the policies of a ConsulKV over its whole payload and returns their findings keyed by path,
+// the findings about keys missing from the payload being returned apart
+func evaluateRegoPolicies(policies *regoPolicyCache, item *sascomv1.ConsulKV, modules map[string]string, configMapPayload map[string]string, pathToMetadata map[string]utils.ConsulMetadata) (map[string][]utils.Finding, []absentKeyFinding, []DetectorError) {
+	consulKvKey := fmt.Sprintf("%s/%s", item.Namespace, item.Name)
+	evaluator, err := policies.get(consulKvKey, modules)
+	if err != nil {
+		return nil, nil, []DetectorError{{Rule: "rego", Err: err}}
+	}
+
+	input := regopolicy.Input{
+		Payload:  map[string]string{},
+		Metadata: map[string]map[string]interface{}{},
+		ConsulKV: map[string]interface{}{
+			"name":        item.Name,
+			"namespace":   item.Namespace,
+			"labels":      item.Labels,
+			"annotations": item.Annotations,
+			"qos":         item.Spec.QoS,
+			"generation":  item.Generation,
+		},
+	}
+	slashedToPath := map[string]string{}
+	for path, value := range configMapPayload {
+		slashedPath := strings.ReplaceAll(path, ".", "/")
+		slashedToPath[slashedPath] = path
+		input.Payload[slashedPath] = value
+		input.Metadata[slashedPath] = metadataFields(pathToMetadata[path])
+	}
+
+	policyFindings, err := evaluator.Evaluate(input)
+	if err != nil {
+		return nil, nil, []DetectorError{{Rule: "rego", Err: err}}
+	}
+	findings := map[string][]utils.Finding{}
+	absentKeys := []absentKeyFinding{}
+	for _, policyFinding := range policyFindings {
+		slashedPath := strings.Trim(policyFinding.Key, "/")
+		path, found := slashedToPath[slashedPath]
+		if !found {
+			absentKeys = append(absentKeys, absentKeyFinding{
+				path:    strings.ReplaceAll(slashedPath, "/", "."),
+				finding: toPolicyFinding(policyFinding, ""),
+				message: policyFinding.Message,
+			})
+			continue
+		}
+		findings[path] = append(findings[path], toPolicyFinding(policyFinding, configMapPayload[path]))
+	}
+	sort.SliceStable(absentKeys, func(i, j int) bool {
+		if absentKeys[i].path != absentKeys[j].path {
+			return absentKeys[i].path < absentKeys[j].path
+		}
+		return absentKeys[i].finding.RuleID < absentKeys[j].finding.RuleID
+	})
+	return findings, absentKeys, nil
+}
+
+// exceptAbsentKeys drops the findings about absent keys covered by an active exception, fingerprint-scoped exceptions never cover an absent key
+func exceptAbsentKeys(exceptions []exception, absentKeys []absentKeyFinding, now time.Time) []absentKeyFinding {
+	output := []absentKeyFinding{}
+	for _, absentKey := range absentKeys {
+		if !isFindingExcepted(exceptions, utils.Invalidation{Path: absentKey.path}, absentKey.finding, now) {
+			output = append(output, absentKey)
+		}
+	}
+	return output
+}
+
+// setPolicyKeysPresentCondition reports the keys the Rego policies expected but the payload misses, the condition is dropped when no policy is evaluated
+func setPolicyKeysPresentCondition(item *sascomv1.ConsulKV, policiesEvaluated bool, absentKeys []absentKeyFinding) {
+	if !policiesEvaluated {
+		meta.RemoveStatusCondition(&item.Status.Conditions, sascomv1.PolicyKeysPresentCondition)
+		return
+	}
+	if len(absentKeys) == 0 {
+		meta.SetStatusCondition(&item.Status.Conditions, metav1.Condition{
+			Type:               sascomv1.PolicyKeysPresentCondition,
+			Status:             metav1.ConditionTrue,
+			Reason:             "AllKeysPresent",
+			Message:            "every key reported by the Rego policies is part of the payload",
+			ObservedGeneration: item.Generation,
+		})
+		return
+	}
+	messages := []string{}
+	for _, absentKey := range absentKeys {
+		message := fmt.Sprintf("%s reported the missing key %s", absentKey.finding.RuleID, absentKey.path)
+		if absentKey.message != "" {
+			message += ": " + absentKey.message
+		}
+		messages = append(messages, message)
+	}
+	meta.SetStatusCondition(&item.Status.Conditions, metav1.Condition{
+		Type:               sascomv1.PolicyKeysPresentCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             "MissingKeys",
+		Message:            boundedMessage(fmt.Sprintf("%d finding(s) about missing keys: %s", len(absentKeys), boundedList(messages, conditionMessageItems, "; "))),
+		ObservedGeneration: item.Generation,
+	})
+}
+
+func toPolicyFinding(policyFinding regopolicy.Finding, value string) utils.Finding {
+	confidence := 1.0
+	if policyFinding.Confidence != nil && *policyFinding.Confidence >= 0 && *policyFinding.Confidence <= 1 {
+		confidence = *policyFinding.Confidence
+	}
+	severity := utils.Severity(policyFinding.Severity)
+	if severity.Rank() == 0 {
+		severity = utils.MediumSeverity
+	}
+	category := utils.PolicyCategory
+	if policyFinding.Advisory {
+		category = utils.PolicyAdvisoryCategory
+	}
+	return utils.Finding{
+		RuleID:     regoRulePrefix + policyFinding.RuleID,
+		Confidence: confidence,
+		Severity:   severity,
+		Category:   category,
+		Span:       utils.Span{Start: 0, End: len(value)},
+	}
+}
+
+// mergeFindings adds the provided findings to the invalidations of their path, creating the missing ones, the output remains sorted by path
+func mergeFindings(invalidationsOutput utils.InvalidationsOutput, pathToFindings map[string][]utils.Finding, configMapPayload map[string]string, redactor utils.Redactor) utils.InvalidationsOutput {
+	if len(pathToFindings) == 0 {
+		return invalidationsOutput
+	}
+	output := utils.InvalidationsOutput{}
+	for _, inv := range invalidationsOutput {
+		if findings, found := pathToFindings[inv.Path]; found {
+			inv = utils.NewInvalidation(inv.Path, inv.RedactedValue, append(append([]utils.Finding{}, inv.Findings...), findings...))
+			delete(pathToFindings, inv.Path)
+		}
+		output = append(output, inv)
+	}
+	for path, findings := range pathToFindings {
+		output = append(output, utils.NewInvalidation(path, redactor.Redact(configMapPayload[path]), findings))
+	}
+	sort.SliceStable(output, func(i, j int) bool {
+		return output[i].Path < output[j].Path
+	})
+	return output
+}
@@ -0,0 +1,96 @@
+package secretengine
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/policyreport"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+const testRegoPolicy = `package consulkv
+
+findings[f] {
+	not input.payload["app/tls/cert"]
+	f := {"key": "app/tls/cert", "rule_id": "tls-cert-required", "severity": "high", "message": "TLS needs a certificate"}
+}
+
+findings[f] {
+	input.payload["app/replicas"] == "1"
+	f := {"key": "/app/replicas", "rule_id": "replicas", "severity": "low"}
+}
+
+findings[f] {
+	input.payload["app/region"] == "eu-west-1"
+	f := {"key": "app/region", "rule_id": "region-deprecated", "advisory": true}
+}
+`
+
+// TestRegoFindingsAboutAbsentKeys guards that a policy requiring a key missing from the payload is reported against the ConsulKV
+// rather than discarded.
+func TestRegoFindingsAboutAbsentKeys(t *testing.T) {
+	item := &sascomv1.ConsulKV{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Generation: 2}}
+	payload := map[string]string{"app.replicas": "1", "app.region": "eu-west-1"}
+
+	findings, absentKeys, detectorErrors := evaluateRegoPolicies(newRegoPolicyCache(), item, map[string]string{"policies/app.rego": testRegoPolicy}, payload, nil)
+	if len(detectorErrors) != 0 {
+		t.Fatalf("unexpected detector errors: %v", detectorErrors)
+	}
+	if len(findings) != 2 || len(findings["app.replicas"]) != 1 || findings["app.replicas"][0].RuleID != "rego/replicas" || len(findings["app.region"]) != 1 {
+		t.Fatalf("unexpected findings: %v", findings)
+	}
+	if findings["app.replicas"][0].Category != utils.PolicyCategory {
+		t.Errorf("the policy finding carries the category %q", findings["app.replicas"][0].Category)
+	}
+	if findings["app.region"][0].Category != utils.PolicyAdvisoryCategory {
+		t.Errorf("the finding the policy marked as advisory carries the category %q", findings["app.region"][0].Category)
+	}
+	if len(absentKeys) != 1 || absentKeys[0].path != "app.tls.cert" || absentKeys[0].finding.RuleID != "rego/tls-cert-required" || absentKeys[0].message != "TLS needs a certificate" {
+		t.Fatalf("unexpected findings about absent keys: %+v", absentKeys)
+	}
+
+	setPolicyKeysPresentCondition(item, true, absentKeys)
+	condition := meta.FindStatusCondition(item.Status.Conditions, sascomv1.PolicyKeysPresentCondition)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.ObservedGeneration != 2 {
+		t.Errorf("unexpected condition: %+v", condition)
+	}
+
+	results := policyReportResults(item, []evaluatedRule{{guard: "rego", rule: "rego"}}, nil, absentKeys, nil)
+	if len(results) != 1 || results[0].Policy != "rego" || results[0].Rule != "rego/tls-cert-required" || results[0].Result != policyreport.Fail || results[0].Properties["path"] != "app.tls.cert" {
+		t.Errorf("unexpected policy report results: %+v", results)
+	}
+
+	exceptions, _ := compileExceptions([]sascomv1.ExceptionSpec{{Glob: "app/tls/*", RuleID: "rego/tls-cert-required", ExpiresAt: metav1.NewTime(time.Now().Add(time.Hour))}})
+	if remaining := exceptAbsentKeys(exceptions, absentKeys, time.Now()); len(remaining) != 0 {
+		t.Errorf("the exception didn't cover the absent key: %+v", remaining)
+	}
+
+	setPolicyKeysPresentCondition(item, false, nil)
+	if meta.FindStatusCondition(item.Status.Conditions, sascomv1.PolicyKeysPresentCondition) != nil {
+		t.Errorf("the condition outlived the policies")
+	}
+}
+
+// TestPolicyKeysPresentConditionIsBounded guards that a policy requiring many missing keys still fits the condition message
+func TestPolicyKeysPresentConditionIsBounded(t *testing.T) {
+	absentKeys := []absentKeyFinding{}
+	for idx := 0; idx < 5000; idx++ {
+		absentKeys = append(absentKeys, absentKeyFinding{path: fmt.Sprintf("app.key-%04d", idx), finding: utils.Finding{RuleID: "rego/required"}, message: "every key is required"})
+	}
+	item := &sascomv1.ConsulKV{}
+	setPolicyKeysPresentCondition(item, true, absentKeys)
+
+	condition := meta.FindStatusCondition(item.Status.Conditions, sascomv1.PolicyKeysPresentCondition)
+	if condition == nil || len(condition.Message) > conditionMessageLength+len("…") {
+		t.Fatalf("expected a bounded message, got %+v", condition)
+	}
+	if !strings.HasPrefix(condition.Message, "5000 finding(s) about missing keys") || !strings.HasSuffix(condition.Message, "…and 4990 more") {
+		t.Errorf("expected the message to count every finding and list the first ones, got %q", condition.Message)
+	}
+}
@@ -0,0 +1,255 @@
+package secretengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/detectorplugin"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRemoteTimeout        = 5 * time.Second
+	defaultRemoteBatchSize      = 100
+	defaultRemoteMaxConcurrency = 4
+)
+
+// remoteDetector sends the values to scan, or only their fingerprints, to an external plugin in batches
+type remoteDetector struct {
+	name           string
+	url            string
+	sendValues     bool
+	timeout        time.Duration
+	batchSize      int
+	maxConcurrency int
+	ignoreFailures bool
+}
+
+// batchDetector is implemented by the detectors scanning every value of a payload at once, they are left out of the per-value runs
+type batchDetector interface {
+	Detector
+	DetectBatch(values []remoteValue, redactor utils.Redactor) remoteBatch
+}
+
+// remoteValue is a value sent over to a plugin, only whole values are sent, never their leaves or decoded layers
+type remoteValue struct {
+	path  string
+	value string
+}
+
+// remoteBatch is what a batch detector found out about the values it was sent
+type remoteBatch struct {
+	findings       map[string][]utils.Finding
+	detectorErrors []DetectorError
+	// ignoredPaths are the paths whose detection failed without it being an error, as per the failure policy
+	ignoredPaths []string
+}
+
+// remoteOutcome is what the batch detectors found out about a single path
+type remoteOutcome struct {
+	findings       []utils.Finding
+	detectorErrors []DetectorError
+	// incomplete tells a detection failure got ignored, the path isn't known to be clean
+	incomplete bool
+}
+
+func lookupRemoteDetector(name string, spec *sascomv1.DetectorsSpec) (remoteDetector, bool) {
+	if spec == nil {
+		return remoteDetector{}, false
+	}
+	for _, remote := range spec.Remote {
+		if remote.Name != name {
+			continue
+		}
+		detector := remoteDetector{
+			name:           remote.Name,
+			url:            remote.URL,
+			sendValues:     remote.SendValues,
+			timeout:        defaultRemoteTimeout,
+			batchSize:      defaultRemoteBatchSize,
+			maxConcurrency: defaultRemoteMaxConcurrency,
+			ignoreFailures: remote.FailurePolicy == sascomv1.RemoteDetectorIgnore,
+		}
+		if remote.Timeout != nil && remote.Timeout.Duration > 0 {
+			detector.timeout = remote.Timeout.Duration
+		}
+		if remote.BatchSize > 0 {
+			detector.batchSize = remote.BatchSize
+		}
+		if remote.MaxConcurrency > 0 {
+			detector.maxConcurrency = remote.MaxConcurrency
+		}
+		return detector, true
+	}
+	return remoteDetector{}, false
+}
+
+func (d remoteDetector) Name() string {
+	return d.name
+}
+
+// Detect sends the value in a batch of its own, without a fingerprint as there is no redactor at hand
+func (d remoteDetector) Detect(path string, value string) ([]utils.Finding, error) {
+	findings, err := d.sendBatch([]remoteValue{{path: path, value: value}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return findings[path], nil
+}
+
+// DetectBatch splits the values into batches sent concurrently, up to the concurrency limit, a failing batch fails the paths it carried only
+func (d remoteDetector) DetectBatch(values []remoteValue, redactor utils.Redactor) remoteBatch {
+	outcome := remoteBatch{findings: map[string][]utils.Finding{}, detectorErrors: []DetectorError{}, ignoredPaths: []string{}}
+	lock := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+	semaphore := make(chan struct{}, d.maxConcurrency)
+	for start := 0; start < len(values); start += d.batchSize {
+		end := start + d.batchSize
+		if end > len(values) {
+			end = len(values)
+		}
+		batch := values[start:end]
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			batchFindings, err := d.sendBatch(batch, &redactor)
+
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				if d.ignoreFailures {
+					fmt.Printf("remote detector '%s' failed, ignoring it as per its failure policy: %v\n", d.name, err)
+					for _, item := range batch {
+						outcome.ignoredPaths = append(outcome.ignoredPaths, item.path)
+					}
+					return
+				}
+				for _, item := range batch {
+					outcome.detectorErrors = append(outcome.detectorErrors, DetectorError{Rule: d.name, Path: item.path, Err: err})
+				}
+				return
+			}
+			for path, pathFindings := range batchFindings {
+				outcome.findings[path] = append(outcome.findings[path], pathFindings...)
+			}
+		}()
+	}
+	wg.Wait()
+	return outcome
+}
+
+func (d remoteDetector) sendBatch(batch []remoteValue, redactor *utils.Redactor) (map[string][]utils.Finding, error) {
+	request := detectorplugin.Request{SchemaVersion: detectorplugin.SchemaVersion, Detector: d.name, Items: []detectorplugin.Item{}}
+	for idx, item := range batch {
+		pluginItem := detectorplugin.Item{ID: strconv.Itoa(idx), Key: strings.ReplaceAll(item.path, ".", "/")}
+		if redactor != nil {
+			pluginItem.Fingerprint = redactor.Fingerprint(item.value)
+		}
+		if d.sendValues {
+			pluginItem.Value = utils.ToPtr(item.value)
+		}
+		request.Items = append(request.Items, pluginItem)
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the plugin request: %w", err)
+	}
+
+	responseBody, statusCode, err := utils.CallAPI(utils.APIRequest{
+		URL:         d.url,
+		Method:      utils.POST,
+		ContentType: utils.JSON,
+		Body:        bytes.NewReader(body),
+		Timeout:     d.timeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call the plugin: %w", err)
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("plugin answered with status code %d: %s", statusCode, strings.TrimSpace(string(responseBody)))
+	}
+	response := detectorplugin.Response{}
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode the plugin response: %w", err)
+	}
+	if response.SchemaVersion != detectorplugin.SchemaVersion {
+		return nil, fmt.Errorf("plugin answered with schema version '%s', expected '%s'", response.SchemaVersion, detectorplugin.SchemaVersion)
+	}
+
+	findings := map[string][]utils.Finding{}
+	for _, result := range response.Results {
+		idx, err := strconv.Atoi(result.ID)
+		if err != nil || idx < 0 || idx >= len(batch) {
+			return nil, fmt.Errorf("plugin answered about an unknown item '%s'", result.ID)
+		}
+		item := batch[idx]
+		for _, pluginFinding := range result.Findings {
+			findings[item.path] = append(findings[item.path], d.toFinding(pluginFinding, item.value))
+		}
+	}
+	return findings, nil
+}
+
+// toFinding namespaces the rule id of a plugin finding under the name of the plugin and sanitizes whatever the plugin is not trusted with
+func (d remoteDetector) toFinding(pluginFinding detectorplugin.Finding, value string) utils.Finding {
+	confidence := pluginFinding.Confidence
+	if confidence < 0 {
+		confidence = 0
+	} else if confidence > 1 {
+		confidence = 1
+	}
+	severity := utils.Severity(pluginFinding.Severity)
+	if severity.Rank() == 0 {
+		severity = utils.MediumSeverity
+	}
+	span := utils.Span{Start: 0, End: len(value)}
+	if pluginFinding.Span != nil && pluginFinding.Span.Start >= 0 && pluginFinding.Span.Start < pluginFinding.Span.End && pluginFinding.Span.End <= len(value) {
+		span = utils.Span{Start: pluginFinding.Span.Start, End: pluginFinding.Span.End}
+	}
+	return utils.Finding{
+		RuleID:     d.name + "/" + pluginFinding.RuleID,
+		Confidence: confidence,
+		Severity:   severity,
+		Span:       span,
+	}
+}
+
+// runBatchDetectors runs every batch detector over the provided paths of the payload
+func runBatchDetectors(detectors []Detector, paths []string, payload map[string]string, redactor utils.Redactor) map[string]remoteOutcome {
+	outcomes := map[string]remoteOutcome{}
+	values := []remoteValue{}
+	for _, path := range paths {
+		values = append(values, remoteValue{path: path, value: payload[path]})
+	}
+	for _, detector := range detectors {
+		batch, ok := detector.(batchDetector)
+		if !ok || len(values) == 0 {
+			continue
+		}
+		result := batch.DetectBatch(values, redactor)
+		for path, pathFindings := range result.findings {
+			outcome := outcomes[path]
+			outcome.findings = append(outcome.findings, pathFindings...)
+			outcomes[path] = outcome
+		}
+		for _, detectorError := range result.detectorErrors {
+			outcome := outcomes[detectorError.Path]
+			outcome.detectorErrors = append(outcome.detectorErrors, detectorError)
+			outcomes[detectorError.Path] = outcome
+		}
+		for _, path := range result.ignoredPaths {
+			outcome := outcomes[path]
+			outcome.incomplete = true
+			outcomes[path] = outcome
+		}
+	}
+	return outcomes
+}
@@ -0,0 +1,114 @@
+package secretengine
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/detectorplugin"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/detectorplugin/detectorplugintest"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+func remoteConsulKv(remote sascomv1.RemoteDetectorSpec) *sascomv1.ConsulKV {
+	return &sascomv1.ConsulKV{Spec: sascomv1.ConsulKVSpec{
+		GuardAgainst: []string{remote.Name},
+		Detectors:    &sascomv1.DetectorsSpec{Remote: []sascomv1.RemoteDetectorSpec{remote}},
+	}}
+}
+
+// TestRemoteDetectorBatchesValues guards that values are sent in batches bounded by the batch size, and that the values themselves only leave the operator when allowed to
+func TestRemoteDetectorBatchesValues(t *testing.T) {
+	stub := detectorplugintest.NewStub(func(_ string, item detectorplugin.Item) []detectorplugin.Finding {
+		if item.Value != nil && strings.Contains(*item.Value, "leak") {
+			return []detectorplugin.Finding{{RuleID: "leak", Confidence: 7, Severity: "bogus", Span: &detectorplugin.Span{Start: 0, End: 4}}}
+		}
+		return nil
+	})
+	defer stub.Close()
+
+	payload := map[string]string{}
+	for idx := 0; idx < 5; idx++ {
+		payload[fmt.Sprintf("app.key%d", idx)] = "fine"
+	}
+	payload["app.key2"] = "leak-here"
+
+	for _, sendValues := range []bool{false, true} {
+		consulKv := remoteConsulKv(sascomv1.RemoteDetectorSpec{Name: "plugin", URL: stub.URL(), SendValues: sendValues, BatchSize: 2})
+		scan := getInvalidations(consulKv, payload, utils.NewRedactor([]byte("test-key")))
+		if len(scan.detectorErrors) != 0 {
+			t.Fatalf("unexpected detector errors: %v", scan.detectorErrors)
+		}
+		if !sendValues {
+			if len(scan.invalidationsOutput) != 0 {
+				t.Errorf("expected no findings without values, got %s", scan.invalidationsOutput)
+			}
+			continue
+		}
+		if len(scan.invalidationsOutput) != 1 {
+			t.Fatalf("expected a single finding, got %s", scan.invalidationsOutput)
+		}
+		finding := scan.invalidationsOutput[0].Findings[0]
+		if scan.invalidationsOutput[0].Path != "app.key2" || finding.RuleID != "plugin/leak" {
+			t.Errorf("unexpected finding %+v at %s", finding, scan.invalidationsOutput[0].Path)
+		}
+		if finding.Confidence != 1 || finding.Severity != utils.MediumSeverity || finding.Span != (utils.Span{Start: 0, End: 4}) {
+			t.Errorf("plugin finding got not sanitized: %+v", finding)
+		}
+	}
+
+	requests := stub.Requests()
+	if len(requests) != 6 {
+		t.Fatalf("expected 3 batches per scan, got %d requests", len(requests))
+	}
+	for idx, request := range requests {
+		sendsValues := idx >= 3
+		for _, item := range request.Items {
+			if (item.Value != nil) != sendsValues {
+				t.Errorf("request %d: value sent = %v, expected %v", idx, item.Value != nil, sendsValues)
+			}
+			if !strings.HasPrefix(item.Fingerprint, "hmac-sha256:") || !strings.HasPrefix(item.Key, "app/") {
+				t.Errorf("request %d: unexpected item %+v", idx, item)
+			}
+		}
+	}
+}
+
+// TestRemoteDetectorFailurePolicy guards that a failing plugin fails the paths it was sent, unless told to be ignored
+func TestRemoteDetectorFailurePolicy(t *testing.T) {
+	stub := detectorplugintest.NewFailingStub(http.StatusServiceUnavailable)
+	defer stub.Close()
+	payload := map[string]string{"app.a": "1", "app.b": "2"}
+
+	scan := getInvalidations(remoteConsulKv(sascomv1.RemoteDetectorSpec{Name: "plugin", URL: stub.URL(), FailurePolicy: sascomv1.RemoteDetectorFail}), payload, utils.NewRedactor(nil))
+	if paths := erroredPaths(scan.detectorErrors, scan.scannedPaths); len(paths) != 2 {
+		t.Errorf("expected both paths to be errored, got %v", paths)
+	}
+
+	rules := compileRuleSet(remoteConsulKv(sascomv1.RemoteDetectorSpec{Name: "plugin", URL: stub.URL(), FailurePolicy: sascomv1.RemoteDetectorIgnore}))
+	cache := newScanCache().forConsulKV("default/ignored", rules.version, map[string]utils.ConsulMetadata{"app.a": {ModifyIndex: 1}, "app.b": {ModifyIndex: 1}})
+	scan = scanPayload(rules, payload, cache.pathToMetadata, utils.NewRedactor(nil), defaultScanWorkers(), cache)
+	if len(scan.detectorErrors) != 0 || len(scan.invalidationsOutput) != 0 {
+		t.Errorf("expected the failing plugin to be ignored, got %v and %s", scan.detectorErrors, scan.invalidationsOutput)
+	}
+	// the paths weren't scanned by the plugin, they must be sent again rather than remembered as clean
+	if len(cache.results) != 0 {
+		t.Errorf("the scan of paths the plugin failed on got cached: %v", cache.results)
+	}
+}
+
+// TestRemoteDetectorSchemaVersion guards that a response in another schema version is an error rather than an empty result
+func TestRemoteDetectorSchemaVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"schema_version":"v2","results":[]}`))
+	}))
+	defer server.Close()
+
+	detector, _ := lookupRemoteDetector("plugin", &sascomv1.DetectorsSpec{Remote: []sascomv1.RemoteDetectorSpec{{Name: "plugin", URL: server.URL}}})
+	if _, err := detector.Detect("app.a", "1"); err == nil || !strings.Contains(err.Error(), "schema version") {
+		t.Errorf("expected the response to be refused for its schema version, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package secretengine
+
+import (
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sync"
+)
+
+// ruleSet is everything a scan needs out of the spec of a ConsulKV, compiled once
+type ruleSet struct {
+	// version changes whenever the rules applicable to the ConsulKV may have changed
+	version       string
+	detectors     []Detector
+	keyRules      []keyRule
+	compileErrors []DetectorError
+	limits        decodingLimits
+	scanLeaves    bool
+	exceptions    []exception
+	validations   []valueValidation
+	// certificates is set when the certificates detector is among the detectors, for the certificates of every value to be inventoried
+	certificates *certificateDetector
+}
+
+func compileRuleSet(consulKv *sascomv1.ConsulKV) ruleSet {
+	detectors, compileErrors := resolveDetectors(consulKv.Spec.GuardAgainst, consulKv.Spec.Detectors)
+	keyRules, keyRuleErrors := compileKeyRules(consulKv.Spec.KeyRules)
+	validations, validationErrors := compileValueValidations(consulKv.Spec.Paths)
+	exceptions, exceptionErrors := compileExceptions(consulKv.Spec.Exceptions)
+	exceptions = append(exceptions, whitelistExceptions(consulKv.Spec.WhitelistedPaths)...)
+	var certificates *certificateDetector
+	for _, detector := range detectors {
+		if certificateDetector, ok := detector.(certificateDetector); ok {
+			certificates = &certificateDetector
+		}
+	}
+	return ruleSet{
+		version:       fmt.Sprintf("%s/%d@%s", consulKv.UID, consulKv.Generation, runtimeRulesVersion()),
+		detectors:     detectors,
+		keyRules:      keyRules,
+		compileErrors: append(append(append(compileErrors, keyRuleErrors...), validationErrors...), exceptionErrors...),
+		limits:        decodingLimitsFor(consulKv.Spec.Detectors),
+		scanLeaves:    consulKv.Spec.StructuredValues.ScansLeaves(),
+		exceptions:    exceptions,
+		validations:   validations,
+		certificates:  certificates,
+	}
+}
+
+// isEmpty tells whether the ConsulKV guards against nothing at all
+func (r ruleSet) isEmpty() bool {
+	return len(r.detectors) == 0 && len(r.keyRules) == 0 && len(r.compileErrors) == 0
+}
+
+// runtimeRulesVersion changes whenever any of the rules loaded at runtime, the alias library or the breached credentials corpus, changes
+func runtimeRulesVersion() string {
+	return AliasLibraryVersion() + "+" + BreachedCorpusVersion()
+}
+
+// ruleSetCache keeps the compiled rule set of every ConsulKV until either its spec or the rules loaded at runtime change.
+// A ConsulKV deleted then created again under the same name starts over with a generation of 1, hence the UID being part of the cache key.
+type ruleSetCache struct {
+	lock    *sync.Mutex
+	entries map[string]cachedRuleSet
+}
+
+type cachedRuleSet struct {
+	uid            types.UID
+	generation     int64
+	runtimeVersion string
+	ruleSet        ruleSet
+}
+
+func newRuleSetCache() *ruleSetCache {
+	return &ruleSetCache{
+		lock:    &sync.Mutex{},
+		entries: map[string]cachedRuleSet{},
+	}
+}
+
+func (c *ruleSetCache) get(consulKv *sascomv1.ConsulKV) ruleSet {
+	consulKvKey := client.ObjectKeyFromObject(consulKv).String()
+	runtimeVersion := runtimeRulesVersion()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if cached, found := c.entries[consulKvKey]; found && cached.uid == consulKv.UID && cached.generation == consulKv.Generation && cached.runtimeVersion == runtimeVersion {
+		return cached.ruleSet
+	}
+	compiled := compileRuleSet(consulKv)
+	c.entries[consulKvKey] = cachedRuleSet{
+		uid:            consulKv.UID,
+		generation:     consulKv.Generation,
+		runtimeVersion: runtimeVersion,
+		ruleSet:        compiled,
+	}
+	return compiled
+}
+
+// evict forgets the rule set of a deleted ConsulKV
+func (c *ruleSetCache) evict(consulKvKey string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.entries, consulKvKey)
+}
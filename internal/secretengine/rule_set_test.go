@@ -0,0 +1,47 @@
+package secretengine
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+)
+
+func detectorNames(rules ruleSet) []string {
+	names := []string{}
+	for _, detector := range rules.detectors {
+		names = append(names, detector.Name())
+	}
+	return names
+}
+
+// TestRuleSetCacheTellsRecreatedConsulKVsApart guards that a ConsulKV deleted then created again under the same name,
+// whose generation starts over, doesn't get the rules of its predecessor.
+func TestRuleSetCacheTellsRecreatedConsulKVsApart(t *testing.T) {
+	cache := newRuleSetCache()
+	original := &sascomv1.ConsulKV{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", UID: "first", Generation: 1},
+		Spec:       sascomv1.ConsulKVSpec{GuardAgainst: []string{"email"}},
+	}
+	recreated := original.DeepCopy()
+	recreated.UID = "second"
+	recreated.Spec.GuardAgainst = []string{"jwt"}
+
+	if got := detectorNames(cache.get(original)); !reflect.DeepEqual(got, []string{"email"}) {
+		t.Fatalf("unexpected detectors %v", got)
+	}
+	if got := detectorNames(cache.get(recreated)); !reflect.DeepEqual(got, []string{"jwt"}) {
+		t.Errorf("the recreated ConsulKV got the detectors %v", got)
+	}
+	// the scan cache tells rule sets apart by their version only
+	if cache.get(original).version == cache.get(recreated).version {
+		t.Errorf("both ConsulKVs share the rules version %s", cache.get(recreated).version)
+	}
+
+	cache.evict("default/app")
+	if len(cache.entries) != 0 {
+		t.Errorf("the rule set outlived its ConsulKV: %v", cache.entries)
+	}
+}
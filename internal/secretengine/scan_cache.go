@@ -0,0 +1,95 @@
+package secretengine
+
+import (
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"sync"
+	"time"
+)
+
+// scanCache remembers the scan result of every path of every ConsulKV along with the ModifyIndex of the value it was computed on
+type scanCache struct {
+	lock    *sync.Mutex
+	entries map[string]*consulKvScanCache
+}
+
+// consulKvScanCache is the scan cache of a single ConsulKV, it only holds results computed with the rules of the given version
+type consulKvScanCache struct {
+	consulKvKey    string
+	rulesVersion   string
+	results        map[string]cachedPathScan
+	pathToMetadata map[string]utils.ConsulMetadata
+}
+
+type cachedPathScan struct {
+	modifyIndex uint64
+	result      pathScan
+}
+
+func newScanCache() *scanCache {
+	return &scanCache{
+		lock:    &sync.Mutex{},
+		entries: map[string]*consulKvScanCache{},
+	}
+}
+
+// evict forgets the cache of a deleted ConsulKV along with its metrics
+func (c *scanCache) evict(consulKvKey string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.entries, consulKvKey)
+	forgetConsulKVMetrics(consulKvKey)
+}
+
+// forConsulKV returns the cache of a ConsulKV as of the current ModifyIndex of its keys, a cache built with other rules is thrown away as a whole
+func (c *scanCache) forConsulKV(consulKvKey string, rulesVersion string, pathToMetadata map[string]utils.ConsulMetadata) *consulKvScanCache {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	cache, found := c.entries[consulKvKey]
+	if !found || cache.rulesVersion != rulesVersion {
+		cache = &consulKvScanCache{
+			consulKvKey:  consulKvKey,
+			rulesVersion: rulesVersion,
+			results:      map[string]cachedPathScan{},
+		}
+		c.entries[consulKvKey] = cache
+	}
+	cache.pathToMetadata = pathToMetadata
+	return cache
+}
+
+// lookup returns the cached result of a path as long as its value wasn't modified since, nor did the result go stale
+func (c *consulKvScanCache) lookup(path string) (pathScan, bool) {
+	modifyIndex := c.pathToMetadata[path].ModifyIndex
+	cached, found := c.results[path]
+	stale := found && !cached.result.rescanAfter.IsZero() && time.Now().After(cached.result.rescanAfter)
+	if !found || modifyIndex == 0 || cached.modifyIndex != modifyIndex || stale {
+		scanCacheMisses.WithLabelValues(c.consulKvKey).Inc()
+		return pathScan{}, false
+	}
+	scanCacheHits.WithLabelValues(c.consulKvKey).Inc()
+	return cached.result, true
+}
+
+// store caches the result of a path, results carrying detector errors or an ignored detector failure are never cached so that they get retried
+func (c *consulKvScanCache) store(path string, result pathScan) {
+	modifyIndex := c.pathToMetadata[path].ModifyIndex
+	if modifyIndex == 0 || len(result.detectorErrors) != 0 || result.incomplete {
+		delete(c.results, path)
+		return
+	}
+	c.results[path] = cachedPathScan{modifyIndex: modifyIndex, result: result}
+}
+
+// prune forgets the paths which are no longer part of the ConsulKV
+func (c *consulKvScanCache) prune(scannedPaths []string) {
+	keep := make(map[string]struct{}, len(scannedPaths))
+	for _, path := range scannedPaths {
+		keep[path] = struct{}{}
+	}
+	for path := range c.results {
+		if _, found := keep[path]; !found {
+			delete(c.results, path)
+		}
+	}
+	scanCacheEntries.WithLabelValues(c.consulKvKey).Set(float64(len(c.results)))
+}
@@ -0,0 +1,95 @@
+package secretengine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+func TestScanCacheReusesUnmodifiedValues(t *testing.T) {
+	cache := newScanCache().forConsulKV("default/cached", "uid/1@", map[string]utils.ConsulMetadata{"app.token": {ModifyIndex: 7}})
+	cache.store("app.token", pathScan{})
+	if _, found := cache.lookup("app.token"); !found {
+		t.Errorf("the result of an unmodified value wasn't reused")
+	}
+
+	cache.pathToMetadata = map[string]utils.ConsulMetadata{"app.token": {ModifyIndex: 8}}
+	if _, found := cache.lookup("app.token"); found {
+		t.Errorf("the result of a modified value was reused")
+	}
+
+	cache.pathToMetadata = map[string]utils.ConsulMetadata{"app.token": {ModifyIndex: 7}}
+	cache.store("app.token", pathScan{rescanAfter: time.Now().Add(-time.Second)})
+	if _, found := cache.lookup("app.token"); found {
+		t.Errorf("a stale result was reused")
+	}
+
+	cache.store("app.token", pathScan{detectorErrors: []DetectorError{{Rule: "remote", Path: "app.token"}}})
+	if _, found := cache.results["app.token"]; found {
+		t.Errorf("a result carrying detector errors got cached")
+	}
+}
+
+// TestScanCacheEviction guards that a deleted ConsulKV leaves neither cached results nor metric series behind,
+// and that a recreated one never reuses the results of its predecessor.
+func TestScanCacheEviction(t *testing.T) {
+	consulKvKey := "default/evicted"
+	metadata := map[string]utils.ConsulMetadata{"app.token": {ModifyIndex: 3}}
+	original := &sascomv1.ConsulKV{ObjectMeta: metav1.ObjectMeta{Name: "evicted", Namespace: "default", UID: "first", Generation: 1}}
+	recreated := original.DeepCopy()
+	recreated.UID = "second"
+
+	scans := newScanCache()
+	cache := scans.forConsulKV(consulKvKey, compileRuleSet(original).version, metadata)
+	cache.store("app.token", pathScan{})
+	cache.lookup("app.token")
+	cache.prune([]string{"app.token"})
+	recordCertificateExpiries(consulKvKey, []sascomv1.CertificateStatus{{Path: "app.cert", Subject: "CN=app", NotAfter: metav1.Now()}})
+
+	if _, found := scans.forConsulKV(consulKvKey, compileRuleSet(recreated).version, metadata).lookup("app.token"); found {
+		t.Errorf("the recreated ConsulKV reused the results of its predecessor")
+	}
+
+	scans.evict(consulKvKey)
+	if len(scans.entries) != 0 {
+		t.Errorf("the cache outlived its ConsulKV")
+	}
+	for name, collector := range map[string]prometheus.Collector{
+		"hits":         scanCacheHits,
+		"misses":       scanCacheMisses,
+		"entries":      scanCacheEntries,
+		"certificates": certificateNotAfter,
+	} {
+		if count := seriesOf(t, collector, consulKvKey); count != 0 {
+			t.Errorf("%d %s series outlived the ConsulKV", count, name)
+		}
+	}
+}
+
+// seriesOf counts the series of the collector labelled with the ConsulKV
+func seriesOf(t *testing.T, collector prometheus.Collector, consulKvKey string) int {
+	metrics := make(chan prometheus.Metric)
+	go func() {
+		collector.Collect(metrics)
+		close(metrics)
+	}()
+	count := 0
+	for metric := range metrics {
+		var written dto.Metric
+		if err := metric.Write(&written); err != nil {
+			t.Fatalf("failed to read a metric: %v", err)
+		}
+		for _, label := range written.GetLabel() {
+			if label.GetName() == "consulkv" && label.GetValue() == consulKvKey {
+				count++
+			}
+		}
+	}
+	return count
+}
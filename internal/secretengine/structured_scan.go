@@ -0,0 +1,76 @@
+package secretengine
+
+import (
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+// scanValue runs the detectors over a value and, when it is a structured document, over each of its leaves on their own
+func scanValue(detectors []Detector, path string, value string, metadata utils.ConsulMetadata, scanLeaves bool, limits decodingLimits) ([]utils.Finding, []DetectorError) {
+	findings, detectorErrors := scanLayers(detectors, path, value, metadata, limits)
+	if !scanLeaves {
+		return findings, detectorErrors
+	}
+	_, leaves, ok := utils.ParseStructuredValue(value)
+	if !ok {
+		return findings, detectorErrors
+	}
+
+	leafFindings := []utils.Finding{}
+	rulesHitOnLeaves := map[string]bool{}
+	for _, leaf := range leaves {
+		// the pointer is appended to the path so that detectors looking at the key name, like the entropy one, see the field name too
+		findingsOnLeaf, leafDetectorErrors := scanLayers(detectors, path+leaf.Pointer, leaf.Value, metadata, limits)
+		for _, finding := range findingsOnLeaf {
+			finding.Pointer = leaf.Pointer
+			leafFindings = append(leafFindings, finding)
+			rulesHitOnLeaves[finding.RuleID] = true
+		}
+		for _, detectorError := range leafDetectorErrors {
+			detectorError.Path = path
+			detectorErrors = append(detectorErrors, detectorError)
+		}
+	}
+
+	// a whole-document finding of a rule which also fired on a leaf is the same leak seen twice, the leaf one is more precise
+	// whereas rules only matching across fields, like a service account key, remain reported against the whole value
+	for _, finding := range findings {
+		if !rulesHitOnLeaves[finding.RuleID] {
+			leafFindings = append(leafFindings, finding)
+		}
+	}
+	return leafFindings, detectorErrors
+}
+
+// scanLayers runs the detectors over a value as stored and over every layer decoded out of it
+func scanLayers(detectors []Detector, path string, value string, metadata utils.ConsulMetadata, limits decodingLimits) ([]utils.Finding, []DetectorError) {
+	findings, detectorErrors := runDetectors(detectors, path, value, metadata)
+	for _, layer := range decodeLayers(value, limits) {
+		layerFindings, layerDetectorErrors := runDetectors(detectors, path, layer.value, metadata)
+		for _, finding := range layerFindings {
+			finding.DecodeChain = layer.chain
+			findings = append(findings, finding)
+		}
+		detectorErrors = append(detectorErrors, layerDetectorErrors...)
+	}
+	return findings, detectorErrors
+}
+
+// scannedLayers lists every text scanValue runs the detectors over: the value as stored, the leaves of a structured value and every layer decoded out of them
+func scannedLayers(value string, scanLeaves bool, limits decodingLimits) []string {
+	values := []string{value}
+	if scanLeaves {
+		if _, leaves, ok := utils.ParseStructuredValue(value); ok {
+			for _, leaf := range leaves {
+				values = append(values, leaf.Value)
+			}
+		}
+	}
+	layers := []string{}
+	for _, v := range values {
+		layers = append(layers, v)
+		for _, layer := range decodeLayers(v, limits) {
+			layers = append(layers, layer.value)
+		}
+	}
+	return layers
+}
@@ -0,0 +1,152 @@
+package secretengine
+
+import (
+	"fmt"
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/valuerules"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"strings"
+)
+
+const validationRulePrefix = "validation/"
+
+// valueValidation is the compiled validation of a path of a ConsulKV
+type valueValidation struct {
+	// prefix is the path, slash-separated, the keys under which get validated
+	prefix string
+	policy sascomv1.ValueValidationPolicy
+	rules  []valuerules.Rule
+}
+
+// validationOutcome lists the values failing their validation, along with the policy each of them falls under
+type validationOutcome struct {
+	failures     utils.InvalidationsOutput
+	pathToPolicy map[string]sascomv1.ValueValidationPolicy
+	messages     []string
+}
+
+func compileValueValidations(paths []sascomv1.PathSpec) ([]valueValidation, []DetectorError) {
+	validations := []valueValidation{}
+	compileErrors := []DetectorError{}
+	for _, pathSpec := range paths {
+		if pathSpec.Validation == nil {
+			continue
+		}
+		validation := valueValidation{
+			prefix: strings.Trim(pathSpec.Path, "/"),
+			policy: pathSpec.Validation.Policy,
+		}
+		if validation.policy == "" {
+			validation.policy = sascomv1.RejectInvalidValue
+		}
+		for idx, ruleSpec := range pathSpec.Validation.Rules {
+			rule, err := valuerules.Compile(valuerules.Spec{
+				Key:        ruleSpec.Key,
+				Type:       string(ruleSpec.Type),
+				Enum:       ruleSpec.Enum,
+				MaxSize:    ruleSpec.MaxSize,
+				JSONSchema: ruleSpec.JSONSchema,
+			})
+			if err != nil {
+				compileErrors = append(compileErrors, DetectorError{Rule: fmt.Sprintf("%s%s[%d]", validationRulePrefix, pathSpec.Path, idx), Err: err})
+				continue
+			}
+			validation.rules = append(validation.rules, rule)
+		}
+		validations = append(validations, validation)
+	}
+	// the most specific path wins when paths are nested
+	sort.SliceStable(validations, func(i, j int) bool {
+		return len(validations[i].prefix) > len(validations[j].prefix)
+	})
+	return validations, compileErrors
+}
+
+// validateValues checks every value of the payload against the validation of the path it lives under, if any
+func validateValues(validations []valueValidation, configMapPayload map[string]string, redactor utils.Redactor) validationOutcome {
+	outcome := validationOutcome{failures: utils.InvalidationsOutput{}, pathToPolicy: map[string]sascomv1.ValueValidationPolicy{}}
+	if len(validations) == 0 {
+		return outcome
+	}
+	paths := []string{}
+	for path := range configMapPayload {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		value := configMapPayload[path]
+		validation, relativeKey, found := validationFor(validations, strings.ReplaceAll(path, ".", "/"))
+		if !found {
+			continue
+		}
+		findings := []utils.Finding{}
+		for _, rule := range validation.rules {
+			if !rule.MatchesKey(relativeKey) {
+				continue
+			}
+			for _, violation := range rule.Check(value) {
+				findings = append(findings, utils.Finding{
+					RuleID:     validationRulePrefix + violation.RuleID,
+					Confidence: 1,
+					Severity:   utils.MediumSeverity,
+					Category:   utils.ValidationCategory,
+					Span:       utils.Span{Start: 0, End: len(value)},
+				})
+				outcome.messages = append(outcome.messages, fmt.Sprintf("'%s': %s", path, violation.Message))
+			}
+		}
+		if len(findings) == 0 {
+			continue
+		}
+		outcome.failures = append(outcome.failures, utils.NewInvalidation(path, redactor.Redact(value), findings))
+		outcome.pathToPolicy[path] = validation.policy
+	}
+	return outcome
+}
+
+// validationFor returns the validation of the most specific path the key lives under, along with the key relative to that path.
+// A path only covers the keys under it segment-wise, app/db covers app/db/host but not app/dbx.
+func validationFor(validations []valueValidation, slashedKey string) (valueValidation, string, bool) {
+	for _, validation := range validations {
+		if validation.prefix == "" || slashedKey == validation.prefix || strings.HasPrefix(slashedKey, validation.prefix+"/") {
+			return validation, strings.TrimLeft(strings.TrimPrefix(slashedKey, validation.prefix), "/"), true
+		}
+	}
+	return valueValidation{}, "", false
+}
+
+// pendingValidationFailures leaves out the failures whose value an earlier adaptation already took out of the configmap, be it removed, rewritten
+// or held back at its previous value because it couldn't be fully evaluated, so that their policy never reinstates what was taken out
+func pendingValidationFailures(failures utils.InvalidationsOutput, configMapPayloadUntilNow map[string]string, sanitizedConfigMapPayload map[string]string) utils.InvalidationsOutput {
+	pending := utils.InvalidationsOutput{}
+	for _, inv := range failures {
+		if value, found := sanitizedConfigMapPayload[inv.Path]; found && value == configMapPayloadUntilNow[inv.Path] {
+			pending = append(pending, inv)
+		}
+	}
+	return pending
+}
+
+func setValuesValidCondition(item *sascomv1.ConsulKV, outcome validationOutcome) {
+	if len(outcome.failures) == 0 {
+		meta.SetStatusCondition(&item.Status.Conditions, metav1.Condition{
+			Type:               sascomv1.ValuesValidCondition,
+			Status:             metav1.ConditionTrue,
+			Reason:             "AllValuesValid",
+			Message:            "every value passed the validation of its path",
+			ObservedGeneration: item.Generation,
+		})
+		return
+	}
+	meta.SetStatusCondition(&item.Status.Conditions, metav1.Condition{
+		Type:               sascomv1.ValuesValidCondition,
+		Status:             metav1.ConditionFalse,
+		Reason:             "InvalidValues",
+		Message:            boundedMessage(fmt.Sprintf("%d value(s) failed their validation: %s", len(outcome.failures), boundedList(outcome.messages, conditionMessageItems, "; "))),
+		ObservedGeneration: item.Generation,
+	})
+}
@@ -0,0 +1,75 @@
+package secretengine
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	sascomv1 "github.com/yashvardhan-kukreja/consulkv-commander/api/v1"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+)
+
+// TestValidationForStopsAtSegments guards that a path only validates the keys living under it, not its siblings sharing a prefix
+func TestValidationForStopsAtSegments(t *testing.T) {
+	validations, compileErrors := compileValueValidations([]sascomv1.PathSpec{
+		{Path: "app/", Validation: &sascomv1.ValueValidationSpec{Policy: sascomv1.ReportInvalidValue}},
+		{Path: "app/db/", Validation: &sascomv1.ValueValidationSpec{Policy: sascomv1.DropInvalidValue}},
+	})
+	if len(compileErrors) != 0 {
+		t.Fatalf("unexpected compile errors: %v", compileErrors)
+	}
+	for _, tc := range []struct {
+		key         string
+		found       bool
+		policy      sascomv1.ValueValidationPolicy
+		relativeKey string
+	}{
+		{key: "app/db/host", found: true, policy: sascomv1.DropInvalidValue, relativeKey: "host"},
+		{key: "app/db", found: true, policy: sascomv1.DropInvalidValue, relativeKey: ""},
+		{key: "app/dbx/host", found: true, policy: sascomv1.ReportInvalidValue, relativeKey: "dbx/host"},
+		{key: "application/host", found: false},
+	} {
+		validation, relativeKey, found := validationFor(validations, tc.key)
+		if found != tc.found || validation.policy != tc.policy || relativeKey != tc.relativeKey {
+			t.Errorf("%s: expected (%v, %s, %q), got (%v, %s, %q)", tc.key, tc.found, tc.policy, tc.relativeKey, found, validation.policy, relativeKey)
+		}
+	}
+}
+
+// TestPendingValidationFailures guards that the policy of a failing value never brings back what an earlier adaptation took out of the configmap
+func TestPendingValidationFailures(t *testing.T) {
+	failures := utils.InvalidationsOutput{
+		utils.NewInvalidation("app.kept", utils.RedactedValue{}, nil),
+		utils.NewInvalidation("app.removed", utils.RedactedValue{}, nil),
+		utils.NewInvalidation("app.errored", utils.RedactedValue{}, nil),
+	}
+	payload := map[string]string{"app.kept": "a", "app.removed": "b", "app.errored": "new"}
+	sanitized := map[string]string{"app.kept": "a", "app.errored": "previous"}
+
+	if paths := pendingValidationFailures(failures, payload, sanitized).Paths(); !reflect.DeepEqual(paths, []string{"app.kept"}) {
+		t.Errorf("expected only app.kept to be pending, got %v", paths)
+	}
+}
+
+// TestValuesValidConditionIsBounded guards that a large prefix of invalid values still fits the condition message
+func TestValuesValidConditionIsBounded(t *testing.T) {
+	outcome := validationOutcome{}
+	for idx := 0; idx < 5000; idx++ {
+		path := fmt.Sprintf("app.key-%04d", idx)
+		outcome.failures = append(outcome.failures, utils.Invalidation{Path: path})
+		outcome.messages = append(outcome.messages, fmt.Sprintf("'%s': expected an integer", path))
+	}
+	item := &sascomv1.ConsulKV{}
+	setValuesValidCondition(item, outcome)
+
+	condition := meta.FindStatusCondition(item.Status.Conditions, sascomv1.ValuesValidCondition)
+	if condition == nil || len(condition.Message) > conditionMessageLength+len("…") {
+		t.Fatalf("expected a bounded message, got %+v", condition)
+	}
+	if !strings.HasPrefix(condition.Message, "5000 value(s) failed") || !strings.HasSuffix(condition.Message, "…and 4990 more") {
+		t.Errorf("expected the message to count every failure and list the first ones, got %q", condition.Message)
+	}
+}
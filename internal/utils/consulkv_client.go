@@ -20,6 +20,31 @@ func NewConsulKV(url string) ConsulKVClient {
 type ConsulKVResponseElement struct {
 	Key   string `json:"Key,omitempty"`
 	Value string `json:"Value,omitempty"`
+	// ModifyIndex is bumped by Consul on every write to the key
+	ModifyIndex uint64 `json:"ModifyIndex,omitempty"`
+	CreateIndex uint64 `json:"CreateIndex,omitempty"`
+	LockIndex   uint64 `json:"LockIndex,omitempty"`
+	Flags       uint64 `json:"Flags,omitempty"`
+	Session     string `json:"Session,omitempty"`
+}
+
+// ConsulMetadata is what Consul knows about a key besides its value
+type ConsulMetadata struct {
+	CreateIndex uint64
+	ModifyIndex uint64
+	LockIndex   uint64
+	Flags       uint64
+	Session     string
+}
+
+func (e ConsulKVResponseElement) Metadata() ConsulMetadata {
+	return ConsulMetadata{
+		CreateIndex: e.CreateIndex,
+		ModifyIndex: e.ModifyIndex,
+		LockIndex:   e.LockIndex,
+		Flags:       e.Flags,
+		Session:     e.Session,
+	}
 }
 
 type ConsulKVResponse []ConsulKVResponseElement
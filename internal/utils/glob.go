@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// GlobToRegex translates a glob into an anchored regex, '*' and '?' stay within a path segment whereas '**' spans across segments
+func GlobToRegex(glob string) string {
+	regex := strings.Builder{}
+	regex.WriteString("^")
+	for idx := 0; idx < len(glob); idx++ {
+		switch char := glob[idx]; char {
+		case '*':
+			if idx+1 < len(glob) && glob[idx+1] == '*' {
+				regex.WriteString(".*")
+				idx++
+				continue
+			}
+			regex.WriteString("[^/]*")
+		case '?':
+			regex.WriteString("[^/]")
+		default:
+			regex.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	regex.WriteString("$")
+	return regex.String()
+}
@@ -50,12 +50,17 @@ func ToPtr[K any](value K) *K {
 	return &value
 }
 
+// RemoveKeysFromMap returns a copy of the map without the provided keys, in O(len(src) + len(keys))
 func RemoveKeysFromMap[K comparable, V any](src map[K]V, keys []K) map[K]V {
-	deepCopy := DeepCopyMap(src)
-	for k := range deepCopy {
-		if ValueInSlice(k, keys) {
-			delete(deepCopy, k)
+	toRemove := make(map[K]struct{}, len(keys))
+	for _, k := range keys {
+		toRemove[k] = struct{}{}
+	}
+	output := make(map[K]V, len(src))
+	for k, v := range src {
+		if _, found := toRemove[k]; !found {
+			output[k] = v
 		}
 	}
-	return deepCopy
+	return output
 }
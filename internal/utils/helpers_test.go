@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+)
+
+// removeKeysFromMapBySlice is the former implementation of RemoveKeysFromMap, kept around to benchmark against
+func removeKeysFromMapBySlice[K comparable, V any](src map[K]V, keys []K) map[K]V {
+	deepCopy := DeepCopyMap(src)
+	for k := range deepCopy {
+		if ValueInSlice(k, keys) {
+			delete(deepCopy, k)
+		}
+	}
+	return deepCopy
+}
+
+func BenchmarkRemoveKeysFromMap(b *testing.B) {
+	src := map[string]string{}
+	keys := []string{}
+	for idx := 0; idx < 50000; idx++ {
+		path := fmt.Sprintf("services.setting-%d", idx)
+		src[path] = "value"
+		if idx%10 == 0 {
+			keys = append(keys, path)
+		}
+	}
+
+	b.Run("slice", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			removeKeysFromMapBySlice(src, keys)
+		}
+	})
+	b.Run("set", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			RemoveKeysFromMap(src, keys)
+		}
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"time"
 )
 
 type APIMethod string
@@ -30,13 +31,15 @@ type APIRequest struct {
 	Method      APIMethod
 	ContentType APIContentType
 	Body        io.Reader
+	// Timeout bounds the whole call, no timeout applies when zero
+	Timeout time.Duration
 }
 
 func CallAPI(request APIRequest) ([]byte, int, error) {
 	var response *http.Response
 	switch request.Method {
 	case GET:
-		resp, err := http.Get(request.URL)
+		resp, err := (&http.Client{Timeout: request.Timeout}).Get(request.URL)
 		if err != nil {
 			return []byte{}, http.StatusInternalServerError, fmt.Errorf("error occurred while calling %s: %w", request.URL, err)
 		}
@@ -47,7 +50,7 @@ func CallAPI(request APIRequest) ([]byte, int, error) {
 			return []byte{}, http.StatusInternalServerError, fmt.Errorf("error occurred while calling %s: %w", request.URL, err)
 		}
 		req.Header.Set("Content-Type", string(request.ContentType))
-		resp, err := http.DefaultClient.Do(req)
+		resp, err := (&http.Client{Timeout: request.Timeout}).Do(req)
 		if err != nil {
 			return []byte{}, http.StatusInternalServerError, fmt.Errorf("error occurred while calling %s: %w", request.URL, err)
 		}
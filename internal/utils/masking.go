@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Masker replaces a matched span of a value with something safe to keep in place of it
+type Masker func(match string) string
+
+// PlaceholderMasker replaces every matched span with the same placeholder
+func PlaceholderMasker(placeholder string) Masker {
+	return func(string) string {
+		return placeholder
+	}
+}
+
+// FormatPreservingMasker replaces every letter and digit of a matched span with '*' and keeps separators in place, so that the masked span looks like the original one.
+// The last few characters only stay readable when the span is long enough for them not to give most of it away.
+func FormatPreservingMasker(keepLast int) Masker {
+	return func(match string) string {
+		runes := []rune(match)
+		alphanumerics := 0
+		for _, char := range runes {
+			if unicode.IsLetter(char) || unicode.IsDigit(char) {
+				alphanumerics++
+			}
+		}
+		kept := keepLast
+		if alphanumerics < 3*keepLast {
+			kept = 0
+		}
+		masked := strings.Builder{}
+		for idx, char := range runes {
+			if (unicode.IsLetter(char) || unicode.IsDigit(char)) && idx < len(runes)-kept {
+				masked.WriteRune('*')
+				continue
+			}
+			masked.WriteRune(char)
+		}
+		return masked.String()
+	}
+}
+
+// MaskFindings masks the spans of the value the findings were made on, findings made on the key or on a decoded layer of the value get the whole value masked as their span can't be located in it.
+// Findings made on a field of a structured value only get that field masked.
+func MaskFindings(value string, findings []Finding, mask Masker) (string, error) {
+	spans := []Span{}
+	fieldFindings := map[string][]Finding{}
+	for _, finding := range findings {
+		switch {
+		case finding.Pointer != "":
+			fieldFindings[finding.Pointer] = append(fieldFindings[finding.Pointer], Finding{Span: finding.Span, DecodeChain: finding.DecodeChain})
+		case finding.OnKey || len(finding.DecodeChain) != 0:
+			spans = append(spans, Span{Start: 0, End: len(value)})
+		default:
+			spans = append(spans, finding.Span)
+		}
+	}
+
+	if len(fieldFindings) != 0 {
+		rewrites := map[string]func(string) string{}
+		var fieldErr error
+		for pointer, findingsOnField := range fieldFindings {
+			pointer, findingsOnField := pointer, findingsOnField
+			rewrites[pointer] = func(leaf string) string {
+				masked, err := MaskFindings(leaf, findingsOnField, mask)
+				if err != nil && fieldErr == nil {
+					fieldErr = fmt.Errorf("failed to mask the field %s: %w", pointer, err)
+				}
+				return masked
+			}
+		}
+		rewritten, err := RewriteStructuredFields(value, rewrites)
+		if err != nil {
+			return "", err
+		}
+		if fieldErr != nil {
+			return "", fieldErr
+		}
+		if len(spans) == 0 {
+			return rewritten, nil
+		}
+		// some finding covers the whole value anyway
+		value = rewritten
+		spans = []Span{{Start: 0, End: len(value)}}
+	}
+	return maskSpans(value, spans, mask), nil
+}
+
+// maskSpans masks the provided spans of the value, overlapping spans are merged beforehand.
+// A span which can't be located in the value gets the whole of it masked rather than none of it.
+func maskSpans(value string, spans []Span, mask Masker) string {
+	merged := []Span{}
+	for _, span := range sortedSpans(spans, len(value)) {
+		if len(merged) != 0 && span.Start <= merged[len(merged)-1].End {
+			if span.End > merged[len(merged)-1].End {
+				merged[len(merged)-1].End = span.End
+			}
+			continue
+		}
+		merged = append(merged, span)
+	}
+
+	if len(merged) == 0 && value != "" {
+		return mask(value)
+	}
+	output := strings.Builder{}
+	cursor := 0
+	for _, span := range merged {
+		output.WriteString(value[cursor:span.Start])
+		output.WriteString(mask(value[span.Start:span.End]))
+		cursor = span.End
+	}
+	output.WriteString(value[cursor:])
+	return output.String()
+}
+
+func sortedSpans(spans []Span, valueLength int) []Span {
+	output := []Span{}
+	for _, span := range spans {
+		if span.Start < 0 || span.End > valueLength || span.Start >= span.End {
+			span = Span{Start: 0, End: valueLength}
+		}
+		output = append(output, span)
+	}
+	sort.Slice(output, func(i, j int) bool { return output[i].Start < output[j].Start })
+	return output
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestFormatPreservingMasker(t *testing.T) {
+	for _, tc := range []struct {
+		match    string
+		keepLast int
+		masked   string
+	}{
+		{"4111-1111-1111-1234", 4, "****-****-****-1234"},
+		// too short for the last characters not to give most of it away
+		{"ab12", 4, "****"},
+		{"secret", 0, "******"},
+		{"jane.doe@example.com", 4, "****.***@*******.com"},
+	} {
+		if masked := FormatPreservingMasker(tc.keepLast)(tc.match); masked != tc.masked {
+			t.Errorf("%s: expected %s, got %s", tc.match, tc.masked, masked)
+		}
+	}
+}
+
+func TestMaskFindings(t *testing.T) {
+	placeholder := PlaceholderMasker("***")
+	for _, tc := range []struct {
+		name     string
+		value    string
+		findings []Finding
+		masked   string
+	}{
+		{name: "span", value: "token=abcd rest", findings: []Finding{{Span: Span{Start: 6, End: 10}}}, masked: "token=*** rest"},
+		{name: "overlapping spans", value: "abcdefgh", findings: []Finding{{Span: Span{Start: 1, End: 4}}, {Span: Span{Start: 3, End: 6}}}, masked: "a***gh"},
+		{name: "out of bounds span", value: "abcd", findings: []Finding{{Span: Span{Start: 2, End: 40}}}, masked: "***"},
+		{name: "on key", value: "abcd", findings: []Finding{{OnKey: true, Span: Span{Start: 0, End: 1}}}, masked: "***"},
+		{name: "decoded layer", value: "YWJjZA==", findings: []Finding{{DecodeChain: []string{"base64"}, Span: Span{Start: 0, End: 2}}}, masked: "***"},
+		{name: "field", value: `{"password":"hunter2","user":"jane"}`, findings: []Finding{{Pointer: "/password", Span: Span{Start: 0, End: 7}}}, masked: `{"password":"***","user":"jane"}`},
+		{name: "numeric field", value: `{"card":4111111111111111,"user":"jane"}`, findings: []Finding{{Pointer: "/card", Span: Span{Start: 0, End: 16}}}, masked: `{"card":0,"user":"jane"}`},
+		{name: "field and whole value", value: `{"password":"hunter2"}`, findings: []Finding{{Pointer: "/password", Span: Span{Start: 0, End: 7}}, {OnKey: true}}, masked: "***"},
+	} {
+		masked, err := MaskFindings(tc.value, tc.findings, placeholder)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if masked != tc.masked {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.masked, masked)
+		}
+	}
+}
+
+// TestRewrittenNumber guards that a numeric leaf stays a number once rewritten, so that the document keeps its schema
+func TestRewrittenNumber(t *testing.T) {
+	for rewritten, number := range map[string]string{
+		"42":               "42",
+		"-1.5e3":           "-1.5e3",
+		"":                 "0",
+		"****1234":         "0",
+		"0000000000001234": "0",
+	} {
+		if got := rewrittenNumber(rewritten); got.String() != number {
+			t.Errorf("%q: expected %s, got %s", rewritten, number, got)
+		}
+	}
+}
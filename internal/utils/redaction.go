@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// values shorter than this never reveal any of their characters in a preview
+	minPreviewableLength = 16
+	previewSuffixLength  = 4
+)
+
+// Redactor turns sensitive values into something safe to hand over to notifiers and audit sinks
+type Redactor struct {
+	key []byte
+}
+
+// NewRedactor builds a Redactor fingerprinting values with the provided key.
+// Without a key, a random one is generated, so fingerprints would only be stable for the lifetime of the process.
+func NewRedactor(key []byte) Redactor {
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		_, _ = rand.Read(key)
+	}
+	return Redactor{key: key}
+}
+
+// Fingerprint is a keyed HMAC of the value: equal values share a fingerprint, yet the value can't be brute-forced back without the key
+func (r Redactor) Fingerprint(value string) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(value))
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// MaskPreview hides a value entirely, only long values keep their last few characters to help a human recognise them
+func MaskPreview(value string) string {
+	if len(value) < minPreviewableLength {
+		return strings.Repeat("*", 8)
+	}
+	return strings.Repeat("*", 8) + value[len(value)-previewSuffixLength:]
+}
+
+// Redact builds the redacted view of the value
+func (r Redactor) Redact(value string) RedactedValue {
+	return RedactedValue{
+		Fingerprint: r.Fingerprint(value),
+		Preview:     MaskPreview(value),
+	}
+}
@@ -0,0 +1,269 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonValueSpan locates a value of a JSON document, kind being the first byte of it
+type jsonValueSpan struct {
+	start int
+	end   int
+	kind  byte
+}
+
+// rewriteJSONFields splices the rewritten fields into the document, every byte outside of them is left as is.
+// A field missing from the document fails the rewrite rather than leaving the value it was found in untouched.
+func rewriteJSONFields(value string, rewrites map[string]func(string) string) (string, error) {
+	scanner := &jsonSpanScanner{data: value, spans: map[string]jsonValueSpan{}}
+	if err := scanner.value(""); err != nil {
+		return "", fmt.Errorf("failed to locate the fields of the document: %w", err)
+	}
+
+	spans := []jsonValueSpan{}
+	replacements := map[int]string{}
+	for pointer, rewrite := range rewrites {
+		span, found := scanner.spans[pointer]
+		if !found {
+			return "", fmt.Errorf("the field %s is not in the document", pointer)
+		}
+		replacement, err := rewrittenJSONValue(value[span.start:span.end], span.kind, rewrite)
+		if err != nil {
+			return "", err
+		}
+		spans = append(spans, span)
+		replacements[span.start] = replacement
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	output := strings.Builder{}
+	cursor := 0
+	for _, span := range spans {
+		// a field nested in a rewritten one is gone along with it
+		if span.start < cursor {
+			continue
+		}
+		output.WriteString(value[cursor:span.start])
+		output.WriteString(replacements[span.start])
+		cursor = span.end
+	}
+	output.WriteString(value[cursor:])
+	return output.String(), nil
+}
+
+// rewrittenJSONValue encodes the rewrite of a raw JSON value, a number stays a number while anything else becomes a string
+func rewrittenJSONValue(raw string, kind byte, rewrite func(string) string) (string, error) {
+	leaf := ""
+	switch {
+	case kind == '"':
+		if err := json.Unmarshal([]byte(raw), &leaf); err != nil {
+			return "", fmt.Errorf("failed to decode the field %s: %w", raw, err)
+		}
+	case kind == '-' || (kind >= '0' && kind <= '9'):
+		return rewrittenNumber(rewrite(raw)).String(), nil
+	case kind == 't' || kind == 'f':
+		leaf = raw
+	}
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(rewrite(leaf)); err != nil {
+		return "", fmt.Errorf("failed to encode the rewritten field: %w", err)
+	}
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
+
+// jsonSpanScanner records the span of every value of a JSON document, keyed by its pointer, the document being known to be valid already
+type jsonSpanScanner struct {
+	data  string
+	pos   int
+	spans map[string]jsonValueSpan
+}
+
+func (s *jsonSpanScanner) value(pointer string) error {
+	s.skipSpaces()
+	if s.pos >= len(s.data) {
+		return fmt.Errorf("unexpected end of the document")
+	}
+	start, kind := s.pos, s.data[s.pos]
+	switch kind {
+	case '{':
+		s.pos++
+		for s.skipSpaces(); s.peek() != '}'; s.skipSpaces() {
+			keyStart := s.pos
+			if err := s.str(); err != nil {
+				return err
+			}
+			key := ""
+			if err := json.Unmarshal([]byte(s.data[keyStart:s.pos]), &key); err != nil {
+				return fmt.Errorf("failed to decode the key %s: %w", s.data[keyStart:s.pos], err)
+			}
+			s.skipSpaces()
+			if err := s.expect(':'); err != nil {
+				return err
+			}
+			if err := s.value(pointer + "/" + escapePointerToken(key)); err != nil {
+				return err
+			}
+			s.skipSpaces()
+			if s.peek() == ',' {
+				s.pos++
+			}
+		}
+		s.pos++
+	case '[':
+		s.pos++
+		for idx := 0; ; idx++ {
+			if s.skipSpaces(); s.peek() == ']' {
+				break
+			}
+			if err := s.value(pointer + "/" + strconv.Itoa(idx)); err != nil {
+				return err
+			}
+			s.skipSpaces()
+			if s.peek() == ',' {
+				s.pos++
+			}
+		}
+		s.pos++
+	case '"':
+		if err := s.str(); err != nil {
+			return err
+		}
+	default:
+		for s.pos < len(s.data) && !strings.ContainsRune(",}] \t\r\n", rune(s.data[s.pos])) {
+			s.pos++
+		}
+	}
+	s.spans[pointer] = jsonValueSpan{start: start, end: s.pos, kind: kind}
+	return nil
+}
+
+// str moves past the string starting at the current position
+func (s *jsonSpanScanner) str() error {
+	if err := s.expect('"'); err != nil {
+		return err
+	}
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '\\':
+			s.pos += 2
+		case '"':
+			s.pos++
+			return nil
+		default:
+			s.pos++
+		}
+	}
+	return fmt.Errorf("unterminated string")
+}
+
+func (s *jsonSpanScanner) expect(char byte) error {
+	if s.peek() != char {
+		return fmt.Errorf("expected '%c' at offset %d", char, s.pos)
+	}
+	s.pos++
+	return nil
+}
+
+func (s *jsonSpanScanner) peek() byte {
+	if s.pos >= len(s.data) {
+		return 0
+	}
+	return s.data[s.pos]
+}
+
+func (s *jsonSpanScanner) skipSpaces() {
+	for s.pos < len(s.data) && strings.ContainsRune(" \t\r\n", rune(s.data[s.pos])) {
+		s.pos++
+	}
+}
+
+// rewriteYAMLFields rewrites the fields of the node tree of the document, so that its comments, key order and styles survive the rewrite.
+// A field missing from the document fails the rewrite rather than leaving the value it was found in untouched.
+func rewriteYAMLFields(value string, rewrites map[string]func(string) string) (string, error) {
+	root, ok := decodeYAMLDocument(value)
+	if !ok {
+		return "", fmt.Errorf("failed to decode the document")
+	}
+	rewritten := map[string]bool{}
+	rewriteYAMLNode(root.Content[0], "", rewrites, rewritten)
+	for pointer := range rewrites {
+		if !rewritten[pointer] && !nestedInRewrittenField(pointer, rewritten) {
+			return "", fmt.Errorf("the field %s is not in the document", pointer)
+		}
+	}
+
+	buffer := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(yamlIndent(value))
+	if err := encoder.Encode(root); err != nil {
+		return "", fmt.Errorf("failed to encode the rewritten document: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("failed to encode the rewritten document: %w", err)
+	}
+	output := buffer.String()
+	if !strings.HasSuffix(value, "\n") {
+		output = strings.TrimSuffix(output, "\n")
+	}
+	return output, nil
+}
+
+func rewriteYAMLNode(node *yaml.Node, pointer string, rewrites map[string]func(string) string, rewritten map[string]bool) {
+	if rewrite, found := rewrites[pointer]; found {
+		rewritten[pointer] = true
+		if node.Kind == yaml.ScalarNode && (node.ShortTag() == "!!int" || node.ShortTag() == "!!float") {
+			node.Value, node.Style = rewrittenNumber(rewrite(node.Value)).String(), 0
+			return
+		}
+		leaf := ""
+		if node.Kind == yaml.ScalarNode && node.ShortTag() != "!!null" {
+			leaf = node.Value
+		}
+		style := node.Style &^ (yaml.FlowStyle | yaml.TaggedStyle)
+		*node = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: rewrite(leaf), Style: style,
+			HeadComment: node.HeadComment, LineComment: node.LineComment, FootComment: node.FootComment}
+		return
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			rewriteYAMLNode(node.Content[idx+1], pointer+"/"+escapePointerToken(node.Content[idx].Value), rewrites, rewritten)
+		}
+	case yaml.SequenceNode:
+		for idx, child := range node.Content {
+			rewriteYAMLNode(child, pointer+"/"+strconv.Itoa(idx), rewrites, rewritten)
+		}
+	}
+}
+
+// nestedInRewrittenField tells whether the field is gone along with a rewritten field it was nested in
+func nestedInRewrittenField(pointer string, rewritten map[string]bool) bool {
+	for parent := range rewritten {
+		if strings.HasPrefix(pointer, parent+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// yamlIndent is the indentation the document is written with, the narrowest one found or 2 spaces
+func yamlIndent(value string) int {
+	indent := 0
+	for _, line := range strings.Split(value, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if width := len(line) - len(trimmed); width != 0 && trimmed != "" && !strings.HasPrefix(trimmed, "#") && (indent == 0 || width < indent) {
+			indent = width
+		}
+	}
+	if indent < 2 {
+		return 2
+	}
+	return indent
+}
@@ -0,0 +1,210 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type StructuredFormat string
+
+const (
+	JSONFormat       StructuredFormat = "json"
+	YAMLFormat       StructuredFormat = "yaml"
+	PropertiesFormat StructuredFormat = "properties"
+)
+
+var propertiesLineRegex = regexp.MustCompile(`^\s*([^=:#!\s][^=:\s]*)\s*[=:]\s*(.*)$`)
+
+// StructuredLeaf is a scalar living inside a structured value, addressed by its JSON pointer
+type StructuredLeaf struct {
+	Pointer string
+	Value   string
+}
+
+// ParseStructuredValue tells whether the value is a JSON, YAML or properties document and, if so, returns every scalar leaf of it
+func ParseStructuredValue(value string) (StructuredFormat, []StructuredLeaf, bool) {
+	if format, leaves, ok := decodeDocument(value); ok {
+		return format, leaves, true
+	}
+	if properties, ok := parseProperties(value); ok {
+		leaves := []StructuredLeaf{}
+		for _, property := range properties {
+			leaves = append(leaves, StructuredLeaf{Pointer: "/" + escapePointerToken(property.key), Value: property.value})
+		}
+		return PropertiesFormat, leaves, true
+	}
+	return "", nil, false
+}
+
+// BlankStructuredFields rewrites a structured value with every field addressed by the provided pointers blanked out, leaving the rest of the document as is
+func BlankStructuredFields(value string, pointers []string) (string, error) {
+	rewrites := map[string]func(string) string{}
+	for _, pointer := range pointers {
+		rewrites[pointer] = func(string) string { return "" }
+	}
+	return RewriteStructuredFields(value, rewrites)
+}
+
+// RewriteStructuredFields rewrites the fields of a structured value addressed by the pointers of the provided rewrites, leaving the rest of the document as is,
+// down to its formatting, key order and comments. A pointer addressing no field of the document fails the rewrite.
+func RewriteStructuredFields(value string, rewrites map[string]func(leaf string) string) (string, error) {
+	if format, _, ok := decodeDocument(value); ok {
+		if format == JSONFormat {
+			return rewriteJSONFields(value, rewrites)
+		}
+		return rewriteYAMLFields(value, rewrites)
+	}
+
+	if _, ok := parseProperties(value); ok {
+		output := []string{}
+		rewritten := map[string]bool{}
+		scanner := bufio.NewScanner(strings.NewReader(value))
+		for scanner.Scan() {
+			line := scanner.Text()
+			if groups := propertiesLineRegex.FindStringSubmatchIndex(line); groups != nil {
+				pointer := "/" + escapePointerToken(line[groups[2]:groups[3]])
+				if rewrite, found := rewrites[pointer]; found {
+					line = line[:groups[4]] + rewrite(line[groups[4]:groups[5]])
+					rewritten[pointer] = true
+				}
+			}
+			output = append(output, line)
+		}
+		for pointer := range rewrites {
+			if !rewritten[pointer] {
+				return "", fmt.Errorf("the field %s is not in the document", pointer)
+			}
+		}
+		if strings.HasSuffix(value, "\n") {
+			output = append(output, "")
+		}
+		return strings.Join(output, "\n"), nil
+	}
+	return "", fmt.Errorf("the value is not a structured document")
+}
+
+// decodeDocument decodes JSON or YAML documents into their scalar leaves, only objects and arrays count as documents since any plain string is valid YAML too.
+// YAML documents are decoded into the very node tree their fields get rewritten on, so that a leaf is found at the same pointer by both.
+func decodeDocument(value string) (StructuredFormat, []StructuredLeaf, bool) {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return "", nil, false
+	}
+	if (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid([]byte(trimmed)) {
+		decoder := json.NewDecoder(strings.NewReader(trimmed))
+		// numbers are kept verbatim, a float64 would mangle long digit sequences like card numbers
+		decoder.UseNumber()
+		var document interface{}
+		if err := decoder.Decode(&document); err != nil {
+			return "", nil, false
+		}
+		leaves := []StructuredLeaf{}
+		collectLeaves(document, "", &leaves)
+		return JSONFormat, leaves, true
+	}
+
+	root, ok := decodeYAMLDocument(value)
+	if !ok {
+		return "", nil, false
+	}
+	leaves := []StructuredLeaf{}
+	collectYAMLLeaves(root.Content[0], "", &leaves)
+	return YAMLFormat, leaves, true
+}
+
+// decodeYAMLDocument decodes a YAML document into its node tree, only a mapping or a sequence counting as a document
+func decodeYAMLDocument(value string) (*yaml.Node, bool) {
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(value), root); err != nil || len(root.Content) == 0 {
+		return nil, false
+	}
+	if kind := root.Content[0].Kind; kind != yaml.MappingNode && kind != yaml.SequenceNode {
+		return nil, false
+	}
+	return root, true
+}
+
+// collectYAMLLeaves collects the scalars of the node tree verbatim, an alias is left out as the value it refers to is collected at its anchor
+func collectYAMLLeaves(node *yaml.Node, pointer string, leaves *[]StructuredLeaf) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			collectYAMLLeaves(node.Content[idx+1], pointer+"/"+escapePointerToken(node.Content[idx].Value), leaves)
+		}
+	case yaml.SequenceNode:
+		for idx, child := range node.Content {
+			collectYAMLLeaves(child, pointer+"/"+strconv.Itoa(idx), leaves)
+		}
+	case yaml.ScalarNode:
+		if node.ShortTag() != "!!null" {
+			*leaves = append(*leaves, StructuredLeaf{Pointer: pointer, Value: node.Value})
+		}
+	}
+}
+
+func collectLeaves(node interface{}, pointer string, leaves *[]StructuredLeaf) {
+	switch typedNode := node.(type) {
+	case map[string]interface{}:
+		keys := []string{}
+		for key := range typedNode {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			collectLeaves(typedNode[key], pointer+"/"+escapePointerToken(key), leaves)
+		}
+	case []interface{}:
+		for idx, child := range typedNode {
+			collectLeaves(child, pointer+"/"+strconv.Itoa(idx), leaves)
+		}
+	case nil:
+		return
+	default:
+		*leaves = append(*leaves, StructuredLeaf{Pointer: pointer, Value: fmt.Sprintf("%v", typedNode)})
+	}
+}
+
+// rewrittenNumber keeps a rewritten numeric leaf a number so that the document keeps its schema, a rewrite which isn't one, like a masked or blanked number, becomes 0
+func rewrittenNumber(rewritten string) json.Number {
+	if rewritten != "" && json.Valid([]byte(rewritten)) {
+		var number json.Number
+		if err := json.Unmarshal([]byte(rewritten), &number); err == nil {
+			return number
+		}
+	}
+	return json.Number("0")
+}
+
+type property struct {
+	key   string
+	value string
+}
+
+// parseProperties accepts a value as a properties document only when every line of it is either a property, a comment or blank
+func parseProperties(value string) ([]property, bool) {
+	properties := []property{}
+	scanner := bufio.NewScanner(strings.NewReader(value))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		groups := propertiesLineRegex.FindStringSubmatch(line)
+		if groups == nil {
+			return nil, false
+		}
+		properties = append(properties, property{key: groups[1], value: groups[2]})
+	}
+	return properties, len(properties) != 0
+}
+
+// escapePointerToken escapes a single reference token as per RFC 6901
+func escapePointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
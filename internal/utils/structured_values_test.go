@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestRewriteStructuredFieldsPreservesDocuments guards that rewriting a field leaves every other byte of the document alone,
+// or at least its key order, comments and indentation when it is YAML.
+func TestRewriteStructuredFieldsPreservesDocuments(t *testing.T) {
+	mask := func(string) string { return "***" }
+	for _, tc := range []struct {
+		name      string
+		value     string
+		pointers  []string
+		rewritten string
+	}{
+		{
+			name:      "compact json",
+			value:     `{"user":"jane","password":"hunter2","port":5432,"tls":true}`,
+			pointers:  []string{"/password", "/port", "/tls"},
+			rewritten: `{"user":"jane","password":"***","port":0,"tls":"***"}`,
+		},
+		{
+			name:      "indented json",
+			value:     "{\n    \"zeta\": \"z\",\n    \"alpha\": {\"token\": \"a\\\"b\", \"list\": [1, \"two\"]}\n}\n",
+			pointers:  []string{"/alpha/token", "/alpha/list/1"},
+			rewritten: "{\n    \"zeta\": \"z\",\n    \"alpha\": {\"token\": \"***\", \"list\": [1, \"***\"]}\n}\n",
+		},
+		{
+			name:      "nested json pointers",
+			value:     `{"a":{"b":"c"},"d":"e"}`,
+			pointers:  []string{"/a", "/a/b"},
+			rewritten: `{"a":"***","d":"e"}`,
+		},
+		{
+			name:      "escaped json keys",
+			value:     `{"a/b":"c","m~n":"o"}`,
+			pointers:  []string{"/a~1b", "/m~0n"},
+			rewritten: `{"a/b":"***","m~n":"***"}`,
+		},
+		{
+			name:      "yaml",
+			value:     "# database settings\nzeta: z\nalpha:\n    password: hunter2 # rotated yearly\n    port: 5432\n    hosts:\n        - a\n        - b\n",
+			pointers:  []string{"/alpha/password", "/alpha/port", "/alpha/hosts/1"},
+			rewritten: "# database settings\nzeta: z\nalpha:\n    password: '***' # rotated yearly\n    port: 0\n    hosts:\n        - a\n        - '***'\n",
+		},
+		{
+			name:      "quoted yaml",
+			value:     "token: \"abc\"\nother: 'x'",
+			pointers:  []string{"/token"},
+			rewritten: "token: \"***\"\nother: 'x'",
+		},
+		{
+			name:      "properties",
+			value:     "# settings\nuser = jane\npassword : hunter2\n",
+			pointers:  []string{"/password"},
+			rewritten: "# settings\nuser = jane\npassword : ***\n",
+		},
+	} {
+		rewrites := map[string]func(string) string{}
+		for _, pointer := range tc.pointers {
+			rewrites[pointer] = mask
+		}
+		rewritten, err := RewriteStructuredFields(tc.value, rewrites)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if rewritten != tc.rewritten {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tc.name, tc.rewritten, rewritten)
+		}
+	}
+}
+
+// TestRewriteStructuredFieldsRoundtrip guards that a rewrite addressing nothing gives the document back untouched
+func TestRewriteStructuredFieldsRoundtrip(t *testing.T) {
+	for _, value := range []string{
+		"{\n  \"b\": 1,\n  \"a\": [true, null, \"x\"]\n}",
+		"# comment\nb: 1\na:\n  - true\n  - x # trailing\n",
+		"b=1\n! comment\na : x",
+	} {
+		rewritten, err := RewriteStructuredFields(value, map[string]func(string) string{})
+		if err != nil {
+			t.Errorf("unexpected error on %q: %v", value, err)
+			continue
+		}
+		if rewritten != value {
+			t.Errorf("expected %q back, got %q", value, rewritten)
+		}
+	}
+}
+
+// TestRewriteStructuredFieldsMissingField guards that a pointer addressing no field fails the rewrite,
+// so that the value gets remediated as a whole rather than synced with its finding left in place.
+func TestRewriteStructuredFieldsMissingField(t *testing.T) {
+	for _, value := range []string{
+		`{"password":"hunter2","user":"jane"}`,
+		"password: hunter2\nuser: jane\n",
+		"password=hunter2\nuser=jane",
+	} {
+		rewrites := map[string]func(string) string{"/password": func(string) string { return "" }, "/secret": func(string) string { return "" }}
+		if rewritten, err := RewriteStructuredFields(value, rewrites); err == nil {
+			t.Errorf("expected the rewrite of %q to fail, got %q", value, rewritten)
+		}
+	}
+}
+
+// TestYAMLLeavesResolveInTheirRewrite guards that YAML is read the same way by detection and remediation,
+// a key like 'on' staying a key rather than turning into a boolean no rewrite could ever address.
+func TestYAMLLeavesResolveInTheirRewrite(t *testing.T) {
+	value := "on: push\nsecret: yes\nport: 0x1F\nempty: ~\nhosts:\n  - a\n"
+	format, leaves, ok := ParseStructuredValue(value)
+	if !ok || format != YAMLFormat {
+		t.Fatalf("expected a YAML document, got %s", format)
+	}
+	want := []StructuredLeaf{{Pointer: "/on", Value: "push"}, {Pointer: "/secret", Value: "yes"}, {Pointer: "/port", Value: "0x1F"}, {Pointer: "/hosts/0", Value: "a"}}
+	if !reflect.DeepEqual(leaves, want) {
+		t.Fatalf("expected the leaves %v, got %v", want, leaves)
+	}
+
+	pointers := []string{}
+	for _, leaf := range leaves {
+		pointers = append(pointers, leaf.Pointer)
+	}
+	blanked, err := BlankStructuredFields(value, pointers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "on: \"\"\nsecret: \"\"\nport: 0\nempty: ~\nhosts:\n  - \"\"\n"; blanked != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, blanked)
+	}
+}
@@ -25,11 +25,154 @@ func (i InvalidationsOutput) Paths() []string {
 	return output
 }
 
+// Invalidation never carries the flagged value itself, only its redacted view
 type Invalidation struct {
-	Path         string `json:"path,omitempty"`
-	Value        string `json:"value"`
-	AnyError     string `json:"any_error,omitempty"`
-	FailingRegex string `json:"failing_regex,omitempty"`
+	Path string `json:"path,omitempty"`
+	RedactedValue
+
+	// RuleID and Confidence are the ones of the most confident finding, Severity is the highest among all findings
+	RuleID     string    `json:"rule_id,omitempty"`
+	Confidence float64   `json:"confidence,omitempty"`
+	Severity   Severity  `json:"severity,omitempty"`
+	Match      MatchKind `json:"match,omitempty"`
+	// Category is the one shared by every finding, empty when they don't share one
+	Category FindingCategory `json:"category,omitempty"`
+	Findings []Finding       `json:"findings,omitempty"`
+}
+
+// FindingCategory tells what a finding is about, findings without a category are about sensitive data
+type FindingCategory string
+
+const (
+	// ValidationCategory is the category of the findings about values failing the validation of their path
+	ValidationCategory FindingCategory = "validation"
+	// PolicyCategory is the category of the findings of the Rego policies, which span the whole payload
+	PolicyCategory FindingCategory = "policy"
+	// PolicyAdvisoryCategory is the category of the findings of the Rego policies which their policy marked as advisory
+	PolicyAdvisoryCategory FindingCategory = "policy-advisory"
+	// CertificateCategory is the category of the findings about the health of certificates, which leak nothing
+	CertificateCategory FindingCategory = "certificate"
+)
+
+// MatchKind tells whether a key got flagged because of its name, its value or both
+type MatchKind string
+
+const (
+	KeyOnlyMatch     MatchKind = "key-only"
+	ValueOnlyMatch   MatchKind = "value-only"
+	KeyAndValueMatch MatchKind = "both"
+)
+
+func NewInvalidation(path string, redactedValue RedactedValue, findings []Finding) Invalidation {
+	invalidation := Invalidation{
+		Path:          path,
+		RedactedValue: redactedValue,
+		Findings:      findings,
+	}
+	onKey, onValue, mixedCategories := false, false, false
+	for idx, finding := range findings {
+		if idx == 0 {
+			invalidation.Category = finding.Category
+		} else if finding.Category != invalidation.Category {
+			mixedCategories = true
+		}
+		if finding.OnKey {
+			onKey = true
+		} else {
+			onValue = true
+		}
+		if invalidation.RuleID == "" || finding.Confidence > invalidation.Confidence {
+			invalidation.RuleID = finding.RuleID
+			invalidation.Confidence = finding.Confidence
+		}
+		if finding.Severity.Rank() > invalidation.Severity.Rank() {
+			invalidation.Severity = finding.Severity
+		}
+	}
+	if mixedCategories {
+		invalidation.Category = ""
+	}
+	switch {
+	case onKey && onValue:
+		invalidation.Match = KeyAndValueMatch
+	case onKey:
+		invalidation.Match = KeyOnlyMatch
+	case onValue:
+		invalidation.Match = ValueOnlyMatch
+	}
+	return invalidation
+}
+
+func (i Invalidation) Spans() []Span {
+	spans := []Span{}
+	for _, finding := range i.Findings {
+		spans = append(spans, finding.Span)
+	}
+	return spans
+}
+
+// FieldPointers returns the pointers of the offending fields, only when every finding is scoped to a field of a structured value
+func (i Invalidation) FieldPointers() ([]string, bool) {
+	pointers := []string{}
+	for _, finding := range i.Findings {
+		if finding.Pointer == "" {
+			return nil, false
+		}
+		pointers = append(pointers, finding.Pointer)
+	}
+	return pointers, len(pointers) != 0
+}
+
+type RedactedValue struct {
+	Fingerprint string `json:"fingerprint"`
+	Preview     string `json:"preview"`
+}
+
+// Finding is a single hit of a detector rule on a value
+type Finding struct {
+	RuleID string `json:"rule_id"`
+	// Confidence ranges from 0, a wild guess, to 1, a certainty
+	Confidence float64  `json:"confidence"`
+	Severity   Severity `json:"severity"`
+	// OnKey tells the finding was made on the key itself rather than on its value, the span is then relative to the key
+	OnKey bool `json:"on_key,omitempty"`
+	// Pointer is the JSON pointer of the offending field when the value is a structured document, the span is then relative to that field
+	Pointer string `json:"pointer,omitempty"`
+	// Category is empty for findings about sensitive data
+	Category FindingCategory `json:"category,omitempty"`
+	// DecodeChain lists the encodings peeled off, outermost first, to reach the layer the finding was made in, the span is then relative to that layer
+	DecodeChain []string `json:"decode_chain,omitempty"`
+	Span        Span     `json:"span"`
+}
+
+type Severity string
+
+const (
+	LowSeverity      Severity = "low"
+	MediumSeverity   Severity = "medium"
+	HighSeverity     Severity = "high"
+	CriticalSeverity Severity = "critical"
+)
+
+func (s Severity) Rank() int {
+	switch s {
+	case LowSeverity:
+		return 1
+	case MediumSeverity:
+		return 2
+	case HighSeverity:
+		return 3
+	case CriticalSeverity:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// Span holds the byte offsets [Start, End) of the part of a value which matched a rule
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
 }
 
 func (i Invalidation) String() string {
@@ -0,0 +1,138 @@
+// Package valuerules checks Consul values against the type, size and JSON Schema constraints of their path.
+// It doesn't depend on the API types so that the admission webhook can compile the rules upfront.
+package valuerules
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/yashvardhan-kukreja/consulkv-commander/internal/utils"
+	"k8s.io/kube-openapi/pkg/validation/errors"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/kube-openapi/pkg/validation/strfmt"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+	"net/url"
+	"regexp"
+	"sigs.k8s.io/yaml"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Spec mirrors a value rule of the API
+type Spec struct {
+	Key        string
+	Type       string
+	Enum       []string
+	MaxSize    int
+	JSONSchema string
+}
+
+// Rule is a compiled Spec
+type Rule struct {
+	spec   Spec
+	key    *regexp.Regexp
+	schema *validate.SchemaValidator
+}
+
+// Violation is a constraint of a rule the value failed, the message never quotes the value
+type Violation struct {
+	RuleID  string
+	Message string
+}
+
+func Compile(ruleSpec Spec) (Rule, error) {
+	rule := Rule{spec: ruleSpec}
+	if ruleSpec.Key != "" {
+		key, err := regexp.Compile(utils.GlobToRegex(ruleSpec.Key))
+		if err != nil {
+			return Rule{}, fmt.Errorf("invalid key glob '%s': %w", ruleSpec.Key, err)
+		}
+		rule.key = key
+	}
+	switch ruleSpec.Type {
+	case "", "string", "int", "bool", "duration", "url", "json":
+	case "enum":
+		if len(ruleSpec.Enum) == 0 {
+			return Rule{}, fmt.Errorf("an enum rule requires the values it allows")
+		}
+	default:
+		return Rule{}, fmt.Errorf("unknown type '%s'", ruleSpec.Type)
+	}
+	if ruleSpec.JSONSchema != "" {
+		schema := &spec.Schema{}
+		if err := json.Unmarshal([]byte(ruleSpec.JSONSchema), schema); err != nil {
+			return Rule{}, fmt.Errorf("invalid JSON schema: %w", err)
+		}
+		rule.schema = validate.NewSchemaValidator(schema, nil, "", strfmt.Default)
+	}
+	return rule, nil
+}
+
+// MatchesKey tells whether the rule applies to the key, relative to the path of the rule and slash-separated
+func (r Rule) MatchesKey(relativeKey string) bool {
+	return r.key == nil || r.key.MatchString(relativeKey)
+}
+
+// Check returns every constraint of the rule the value fails
+func (r Rule) Check(value string) []Violation {
+	violations := []Violation{}
+	if r.spec.MaxSize > 0 && len(value) > r.spec.MaxSize {
+		violations = append(violations, Violation{RuleID: "max-size", Message: fmt.Sprintf("%d bytes exceed the maximum size of %d bytes", len(value), r.spec.MaxSize)})
+	}
+	if err := checkType(r.spec, strings.TrimSpace(value)); err != nil {
+		violations = append(violations, Violation{RuleID: "type-" + r.spec.Type, Message: err.Error()})
+	}
+	if r.schema != nil {
+		document := interface{}(nil)
+		if err := yaml.Unmarshal([]byte(value), &document); err != nil {
+			violations = append(violations, Violation{RuleID: "json-schema", Message: "the value is neither JSON nor YAML"})
+		} else if result := r.schema.Validate(document); !result.IsValid() {
+			violations = append(violations, Violation{RuleID: "json-schema", Message: schemaViolationMessage(result.Errors)})
+		}
+	}
+	return violations
+}
+
+func checkType(spec Spec, value string) error {
+	switch spec.Type {
+	case "int":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("not an integer")
+		}
+	case "bool":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("not a boolean")
+		}
+	case "duration":
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("not a duration")
+		}
+	case "url":
+		if parsed, err := url.Parse(value); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("not an absolute URL")
+		}
+	case "enum":
+		if !utils.ValueInSlice(value, spec.Enum) {
+			return fmt.Errorf("not one of %s", strings.Join(spec.Enum, ", "))
+		}
+	case "json":
+		if !json.Valid([]byte(value)) {
+			return fmt.Errorf("not valid JSON")
+		}
+	}
+	return nil
+}
+
+// schemaViolationMessage only names the offending fields, the messages of the schema validator quote the offending values
+func schemaViolationMessage(schemaErrors []error) string {
+	fields := []string{}
+	for _, err := range schemaErrors {
+		if validationError, ok := err.(*errors.Validation); ok && validationError.Name != "" && !utils.ValueInSlice(validationError.Name, fields) {
+			fields = append(fields, validationError.Name)
+		}
+	}
+	if len(fields) == 0 {
+		return "the document doesn't conform to the JSON schema"
+	}
+	return fmt.Sprintf("the fields %s don't conform to the JSON schema", strings.Join(fields, ", "))
+}
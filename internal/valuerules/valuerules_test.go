@@ -0,0 +1,95 @@
+package valuerules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	for _, tc := range []struct {
+		spec    Spec
+		invalid bool
+	}{
+		{spec: Spec{Type: "int"}},
+		{spec: Spec{Key: "db/*", Type: "url"}},
+		{spec: Spec{Type: "enum"}, invalid: true},
+		{spec: Spec{Type: "float"}, invalid: true},
+		{spec: Spec{JSONSchema: `{"type": "object"}`}},
+		{spec: Spec{JSONSchema: `{"type": `}, invalid: true},
+	} {
+		if _, err := Compile(tc.spec); (err != nil) != tc.invalid {
+			t.Errorf("%+v: expected invalid = %v, got %v", tc.spec, tc.invalid, err)
+		}
+	}
+}
+
+func TestMatchesKey(t *testing.T) {
+	rule, err := Compile(Spec{Key: "db/*"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for key, matches := range map[string]bool{"db/host": true, "db/replica/host": false, "cache/host": false} {
+		if got := rule.MatchesKey(key); got != matches {
+			t.Errorf("%s: expected %v, got %v", key, matches, got)
+		}
+	}
+	if rule, _ := Compile(Spec{}); !rule.MatchesKey("anything/at/all") {
+		t.Errorf("a rule without a key glob must apply to every key")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		spec       Spec
+		value      string
+		violations []string
+	}{
+		{name: "int", spec: Spec{Type: "int"}, value: " 42\n"},
+		{name: "not int", spec: Spec{Type: "int"}, value: "4.2", violations: []string{"type-int"}},
+		{name: "bool", spec: Spec{Type: "bool"}, value: "true"},
+		{name: "not bool", spec: Spec{Type: "bool"}, value: "yes", violations: []string{"type-bool"}},
+		{name: "duration", spec: Spec{Type: "duration"}, value: "1m30s"},
+		{name: "not duration", spec: Spec{Type: "duration"}, value: "90", violations: []string{"type-duration"}},
+		{name: "url", spec: Spec{Type: "url"}, value: "https://example.com/path"},
+		{name: "relative url", spec: Spec{Type: "url"}, value: "/path", violations: []string{"type-url"}},
+		{name: "enum", spec: Spec{Type: "enum", Enum: []string{"debug", "info"}}, value: "info"},
+		{name: "not enum", spec: Spec{Type: "enum", Enum: []string{"debug", "info"}}, value: "trace", violations: []string{"type-enum"}},
+		{name: "json", spec: Spec{Type: "json"}, value: `{"a": 1}`},
+		{name: "not json", spec: Spec{Type: "json"}, value: `{"a": }`, violations: []string{"type-json"}},
+		{name: "too big", spec: Spec{Type: "int", MaxSize: 2}, value: "123", violations: []string{"max-size"}},
+		{name: "schema", spec: Spec{JSONSchema: `{"type": "object", "required": ["port"]}`}, value: "port: 80"},
+		{name: "schema violation", spec: Spec{JSONSchema: `{"type": "object", "required": ["port"]}`}, value: `{"host": "db"}`, violations: []string{"json-schema"}},
+		{name: "not a document", spec: Spec{JSONSchema: `{"type": "object"}`}, value: "a: [", violations: []string{"json-schema"}},
+	} {
+		rule, err := Compile(tc.spec)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		ruleIDs := []string{}
+		for _, violation := range rule.Check(tc.value) {
+			ruleIDs = append(ruleIDs, violation.RuleID)
+		}
+		if strings.Join(ruleIDs, ",") != strings.Join(tc.violations, ",") {
+			t.Errorf("%s: expected the violations %v, got %v", tc.name, tc.violations, ruleIDs)
+		}
+	}
+}
+
+// TestViolationsNeverQuoteTheValue guards that the messages, which end up in the status and the pages, never leak the value
+func TestViolationsNeverQuoteTheValue(t *testing.T) {
+	rule, err := Compile(Spec{Type: "enum", Enum: []string{"a"}, MaxSize: 4, JSONSchema: `{"type": "object", "properties": {"password": {"type": "integer"}}}`})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	value := `{"password": "hunter2"}`
+	violations := rule.Check(value)
+	if len(violations) != 3 {
+		t.Fatalf("expected 3 violations, got %+v", violations)
+	}
+	for _, violation := range violations {
+		if strings.Contains(violation.Message, "hunter2") {
+			t.Errorf("the violation %s quotes the value: %s", violation.RuleID, violation.Message)
+		}
+	}
+}